@@ -101,6 +101,10 @@ db.createCollection('push_intents', {
           items: {
             bsonType: 'string'
           }
+        },
+        priority: {
+          bsonType: 'number',
+          description: 'Higher priorities are processed first'
         }
       }
     }
@@ -109,6 +113,11 @@ db.createCollection('push_intents', {
 
 db.createCollection('history');
 db.createCollection('conflicts');
+db.createCollection('audit');
+db.createCollection('dead_letter_intents');
+db.createCollection('pending_marks');
+db.createCollection('batch_summaries');
+db.createCollection('repo_locks');
 
 // Create indexes
 db.documents.createIndex({ repo: 1, branch: 1, path: 1 }, { unique: true });
@@ -117,6 +126,7 @@ db.documents.createIndex({ author: 1 });
 db.documents.createIndex({ '_v.value': 1 });
 
 db.push_intents.createIndex({ processed: 1, timestamp: 1 });
+db.push_intents.createIndex({ processed: 1, priority: -1, timestamp: 1 });
 db.push_intents.createIndex({ repo: 1, branch: 1 });
 db.push_intents.createIndex({ timestamp: -1 });
 db.push_intents.createIndex({ author: 1 });
@@ -128,4 +138,14 @@ db.conflicts.createIndex({ repo: 1, branch: 1, path: 1 });
 db.conflicts.createIndex({ resolved: 1 });
 db.conflicts.createIndex({ created_at: -1 });
 
+db.audit.createIndex({ repo: 1, branch: 1, timestamp: -1 });
+
+db.dead_letter_intents.createIndex({ quarantined_at: -1 });
+
+db.pending_marks.createIndex({ created_at: 1 });
+
+db.batch_summaries.createIndex({ completed_at: -1 });
+
+db.repo_locks.createIndex({ expires_at: 1 }, { expireAfterSeconds: 0 });
+
 print('Virtual DOM database initialized successfully');
\ No newline at end of file
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Pauser pauses and resumes intent processing
+type Pauser interface {
+	Pause()
+	Resume()
+	Paused() bool
+}
+
+// PauseResponse reports the processing state after a pause or resume
+type PauseResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// NewPauseHandler returns a handler that pauses processing on POST
+func NewPauseHandler(p Pauser, logger *logrus.Logger) http.Handler {
+	return pauseHandler(logger, "Processing paused via admin API", p.Pause, p.Paused)
+}
+
+// NewResumeHandler returns a handler that resumes processing on POST
+func NewResumeHandler(p Pauser, logger *logrus.Logger) http.Handler {
+	return pauseHandler(logger, "Processing resumed via admin API", p.Resume, p.Paused)
+}
+
+func pauseHandler(logger *logrus.Logger, message string, action func(), paused func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		action()
+		logger.WithField("remote_addr", r.RemoteAddr).Info(message)
+		writeJSON(w, http.StatusOK, PauseResponse{Paused: paused()})
+	})
+}
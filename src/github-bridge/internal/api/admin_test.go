@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+type fakePauser struct {
+	paused bool
+}
+
+func (p *fakePauser) Pause()       { p.paused = true }
+func (p *fakePauser) Resume()      { p.paused = false }
+func (p *fakePauser) Paused() bool { return p.paused }
+
+func TestPauseAndResumeHandlers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	pauser := &fakePauser{}
+	mux := http.NewServeMux()
+	mux.Handle("/admin/pause", RequireBearer(testToken, NewPauseHandler(pauser, logger)))
+	mux.Handle("/admin/resume", RequireBearer(testToken, NewResumeHandler(pauser, logger)))
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/admin/pause", ""); rec.Code != http.StatusUnauthorized || pauser.paused {
+		t.Fatalf("expected unauthenticated pause to be rejected, got %d", rec.Code)
+	}
+
+	if rec := do(http.MethodGet, "/admin/pause", testToken); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", rec.Code)
+	}
+
+	for _, step := range []struct {
+		path string
+		want bool
+	}{
+		{"/admin/pause", true},
+		{"/admin/resume", false},
+	} {
+		rec := do(http.MethodPost, step.path, testToken)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", step.path, rec.Code)
+		}
+
+		var resp PauseResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: invalid response: %v", step.path, err)
+		}
+		if resp.Paused != step.want || pauser.paused != step.want {
+			t.Errorf("%s: expected paused=%v, got response %v state %v", step.path, step.want, resp.Paused, pauser.paused)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// maxRequestBytes bounds the size of a submitted intent
+const maxRequestBytes = 32 << 20
+
+// IntentCreator persists submitted push intents
+type IntentCreator interface {
+	CreatePushIntent(ctx context.Context, intent *mongodb.PushIntent, documents []*mongodb.Document) (string, error)
+}
+
+// IntentRequest is the JSON body accepted by POST /intents
+type IntentRequest struct {
+	Repo      string           `json:"repo"`
+	Branch    string           `json:"branch"`
+	Author    string           `json:"author"`
+	Message   string           `json:"message"`
+	Documents []string         `json:"documents,omitempty"`
+	Inline    []InlineDocument `json:"inline_documents,omitempty"`
+	Priority  int              `json:"priority,omitempty"`
+}
+
+// InlineDocument is a document submitted together with its intent
+type InlineDocument struct {
+	Path      string                 `json:"path"`
+	Content   string                 `json:"content"`
+	Encoding  string                 `json:"encoding,omitempty"` // utf-8 (default) or base64
+	Operation string                 `json:"operation,omitempty"`
+	Type      string                 `json:"type,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// IntentResponse is returned when an intent was created
+type IntentResponse struct {
+	ID string `json:"id"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// IntentHandler serves the push intent submission API
+type IntentHandler struct {
+	creator IntentCreator
+	logger  *logrus.Logger
+}
+
+// NewIntentHandler creates a handler writing intents through creator
+func NewIntentHandler(creator IntentCreator, logger *logrus.Logger) *IntentHandler {
+	return &IntentHandler{creator: creator, logger: logger}
+}
+
+// ServeHTTP implements http.Handler
+func (h *IntentHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req IntentRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON body: %v", err))
+		return
+	}
+
+	intent, documents, err := req.build()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	id, err := h.creator.CreatePushIntent(r.Context(), intent, documents)
+	if err != nil {
+		h.logger.WithError(err).Error("Failed to create push intent")
+		writeError(w, http.StatusInternalServerError, "failed to create push intent")
+		return
+	}
+
+	h.logger.WithFields(logrus.Fields{
+		"id":        id,
+		"repo":      intent.Repo,
+		"branch":    intent.Branch,
+		"documents": len(intent.Documents) + len(documents),
+	}).Info("Accepted push intent")
+
+	writeJSON(w, http.StatusCreated, IntentResponse{ID: id})
+}
+
+// build validates the request and converts it to storage types
+func (req *IntentRequest) build() (*mongodb.PushIntent, []*mongodb.Document, error) {
+	var problems []string
+	required := map[string]string{
+		"repo":    req.Repo,
+		"branch":  req.Branch,
+		"author":  req.Author,
+		"message": req.Message,
+	}
+	for _, field := range []string{"repo", "branch", "author", "message"} {
+		if strings.TrimSpace(required[field]) == "" {
+			problems = append(problems, field+" is required")
+		}
+	}
+
+	if len(req.Documents) == 0 && len(req.Inline) == 0 {
+		problems = append(problems, "documents or inline_documents is required")
+	}
+
+	for i, id := range req.Documents {
+		if strings.TrimSpace(id) == "" {
+			problems = append(problems, fmt.Sprintf("documents[%d] is empty", i))
+		}
+	}
+
+	now := time.Now()
+	documents := make([]*mongodb.Document, 0, len(req.Inline))
+	for i, inline := range req.Inline {
+		doc, err := inline.build(req, now)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("inline_documents[%d]: %v", i, err))
+			continue
+		}
+		documents = append(documents, doc)
+	}
+
+	if len(problems) > 0 {
+		return nil, nil, errors.New(strings.Join(problems, "; "))
+	}
+
+	intent := &mongodb.PushIntent{
+		Repo:      req.Repo,
+		Branch:    req.Branch,
+		Author:    req.Author,
+		Message:   req.Message,
+		Timestamp: now,
+		Documents: append([]string(nil), req.Documents...),
+		Priority:  req.Priority,
+	}
+
+	return intent, documents, nil
+}
+
+func (d *InlineDocument) build(req *IntentRequest, now time.Time) (*mongodb.Document, error) {
+	if err := git.ValidatePath(d.Path); err != nil {
+		return nil, err
+	}
+
+	operation := d.Operation
+	if operation == "" {
+		operation = "update"
+	}
+	switch operation {
+	case "create", "update", "delete":
+	default:
+		return nil, fmt.Errorf("unknown operation %q", operation)
+	}
+
+	var blob []byte
+	switch d.Encoding {
+	case "", "utf-8":
+		blob = []byte(d.Content)
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(d.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+		blob = decoded
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", d.Encoding)
+	}
+
+	metadata := make(map[string]interface{}, len(d.Metadata)+1)
+	for k, v := range d.Metadata {
+		metadata[k] = v
+	}
+	metadata["operation"] = operation
+
+	return &mongodb.Document{
+		Repo:      req.Repo,
+		Branch:    req.Branch,
+		Path:      d.Path,
+		Blob:      blob,
+		Author:    req.Author,
+		Timestamp: now,
+		Type:      d.Type,
+		Metadata:  metadata,
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+type fakeCreator struct {
+	intent    *mongodb.PushIntent
+	documents []*mongodb.Document
+	err       error
+}
+
+func (f *fakeCreator) CreatePushIntent(ctx context.Context, intent *mongodb.PushIntent, documents []*mongodb.Document) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.intent = intent
+	f.documents = documents
+	return "intent-1", nil
+}
+
+const testToken = "s3cret"
+
+func newTestHandler(creator IntentCreator) http.Handler {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return RequireBearer(testToken, NewIntentHandler(creator, logger))
+}
+
+func postIntent(handler http.Handler, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/intents", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateIntentWithInlineDocuments(t *testing.T) {
+	creator := &fakeCreator{}
+	handler := newTestHandler(creator)
+
+	rec := postIntent(handler, testToken, `{
+		"repo": "org/repo",
+		"branch": "main",
+		"author": "alice",
+		"message": "Update config",
+		"documents": ["existing-doc"],
+		"inline_documents": [
+			{"path": "config/app.yaml", "content": "key: value\n"},
+			{"path": "assets/logo.bin", "content": "AAEC", "encoding": "base64", "operation": "create"}
+		]
+	}`)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+
+	var resp IntentResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != "intent-1" {
+		t.Errorf("expected intent-1, got %q", resp.ID)
+	}
+
+	if creator.intent.Repo != "org/repo" || creator.intent.Branch != "main" || creator.intent.Processed {
+		t.Errorf("unexpected intent: %+v", creator.intent)
+	}
+	if len(creator.intent.Documents) != 1 || creator.intent.Documents[0] != "existing-doc" {
+		t.Errorf("unexpected referenced documents: %v", creator.intent.Documents)
+	}
+
+	if len(creator.documents) != 2 {
+		t.Fatalf("expected 2 inline documents, got %d", len(creator.documents))
+	}
+	if op := creator.documents[0].Metadata["operation"]; op != "update" {
+		t.Errorf("expected default operation update, got %v", op)
+	}
+	if got := creator.documents[1].Blob; string(got) != "\x00\x01\x02" {
+		t.Errorf("expected decoded base64 blob, got %v", got)
+	}
+}
+
+func TestCreateIntentValidationFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+		msg  string
+	}{
+		{"malformed json", `{"repo":`, http.StatusBadRequest, "invalid JSON"},
+		{"unknown field", `{"repository": "x"}`, http.StatusBadRequest, "unknown field"},
+		{"missing fields", `{"documents": ["a"]}`, http.StatusUnprocessableEntity, "repo is required"},
+		{"no documents", `{"repo":"r","branch":"b","author":"a","message":"m"}`, http.StatusUnprocessableEntity, "documents or inline_documents is required"},
+		{"path traversal", `{"repo":"r","branch":"b","author":"a","message":"m","inline_documents":[{"path":"../etc/passwd","content":"x"}]}`, http.StatusUnprocessableEntity, "escapes the repository"},
+		{"bad operation", `{"repo":"r","branch":"b","author":"a","message":"m","inline_documents":[{"path":"a.txt","operation":"rename"}]}`, http.StatusUnprocessableEntity, "unknown operation"},
+		{"bad base64", `{"repo":"r","branch":"b","author":"a","message":"m","inline_documents":[{"path":"a.txt","content":"!!","encoding":"base64"}]}`, http.StatusUnprocessableEntity, "invalid base64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creator := &fakeCreator{}
+			rec := postIntent(newTestHandler(creator), testToken, tt.body)
+
+			if rec.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, rec.Code, rec.Body)
+			}
+			if !strings.Contains(rec.Body.String(), tt.msg) {
+				t.Errorf("expected error containing %q, got %s", tt.msg, rec.Body)
+			}
+			if creator.intent != nil {
+				t.Error("invalid request should not create an intent")
+			}
+		})
+	}
+}
+
+func TestCreateIntentRequiresToken(t *testing.T) {
+	body := `{"repo":"r","branch":"b","author":"a","message":"m","documents":["d"]}`
+
+	for _, token := range []string{"", "wrong"} {
+		creator := &fakeCreator{}
+		rec := postIntent(newTestHandler(creator), token, body)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: expected 401, got %d", token, rec.Code)
+		}
+		if creator.intent != nil {
+			t.Errorf("token %q: unauthorized request created an intent", token)
+		}
+	}
+}
+
+func TestCreateIntentStoreFailure(t *testing.T) {
+	creator := &fakeCreator{err: errors.New("mongo down")}
+	rec := postIntent(newTestHandler(creator), testToken, `{"repo":"r","branch":"b","author":"a","message":"m","documents":["d"]}`)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Body.String(), "mongo down") {
+		t.Error("internal error details should not leak to the client")
+	}
+}
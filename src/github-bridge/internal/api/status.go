@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+	"time"
+)
+
+// Circuit states reported by the status endpoint. The circuit is open while
+// processed marks are failing and being deferred to the reconciler.
+const (
+	CircuitClosed = "closed"
+	CircuitOpen   = "open"
+)
+
+// BuildInfo identifies the running binary
+type BuildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"date"`
+}
+
+// Status is a point-in-time snapshot of the bridge for on-call
+type Status struct {
+	BuildInfo
+	StartedAt     time.Time  `json:"started_at"`
+	UptimeSeconds float64    `json:"uptime_seconds"`
+	Workers       int        `json:"workers"`
+	InFlight      int64      `json:"in_flight"`
+	QueueDepth    int64      `json:"queue_depth"`
+	Backlog       int64      `json:"backlog"`
+	Leader        bool       `json:"leader"`
+	Paused        bool       `json:"paused"`
+	Circuit       string     `json:"circuit"`
+	PendingMarks  int64      `json:"pending_marks"`
+	LastPushAt    *time.Time `json:"last_push_at"`
+}
+
+// StatusProvider reports the bridge status
+type StatusProvider interface {
+	Status() Status
+}
+
+// NewStatusHandler returns a handler serving the status as JSON on GET
+func NewStatusHandler(p StatusProvider) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+
+		writeJSON(w, http.StatusOK, p.Status())
+	})
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeStatus Status
+
+func (s fakeStatus) Status() Status { return Status(s) }
+
+func TestStatusHandlerServesJSONSnapshot(t *testing.T) {
+	lastPush := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	handler := NewStatusHandler(fakeStatus{
+		BuildInfo:  BuildInfo{Version: "1.2.3", Commit: "abc123", Date: "2024-05-01"},
+		Workers:    3,
+		QueueDepth: 7,
+		Circuit:    CircuitClosed,
+		LastPushAt: &lastPush,
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+
+	for _, key := range []string{
+		"version", "commit", "date", "started_at", "uptime_seconds", "workers",
+		"in_flight", "queue_depth", "backlog", "leader", "paused", "circuit",
+		"pending_marks", "last_push_at",
+	} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("expected %q in status, got %v", key, body)
+		}
+	}
+	if body["version"] != "1.2.3" || body["queue_depth"] != float64(7) || body["last_push_at"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("unexpected status values %v", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
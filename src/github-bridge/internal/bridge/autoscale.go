@@ -0,0 +1,73 @@
+package bridge
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// autoscaling reports whether the worker count follows the backlog
+func (b *Bridge) autoscaling() bool {
+	return b.config.MaxWorkers > 0
+}
+
+// initialWorkers is how many workers Start launches: WORKER_COUNT, kept
+// within the autoscaling bounds when they are set
+func (b *Bridge) initialWorkers() int {
+	n := b.config.WorkerCount
+	if !b.autoscaling() {
+		return n
+	}
+	if n < b.config.MinWorkers {
+		n = b.config.MinWorkers
+	}
+	if n > b.config.MaxWorkers {
+		n = b.config.MaxWorkers
+	}
+	return n
+}
+
+// workerCount is the size of the worker pool: the running count when
+// autoscaling, WORKER_COUNT otherwise
+func (b *Bridge) workerCount() int {
+	if b.autoscaling() {
+		return int(b.workers.Load())
+	}
+	return b.config.WorkerCount
+}
+
+// startWorker launches one more worker. Callers hold a wg slot themselves,
+// so the Add never races a Wait that has already seen zero.
+func (b *Bridge) startWorker() {
+	id := int(b.nextWorkerID.Add(1)) - 1
+	b.workers.Add(1)
+	b.wg.Add(1)
+	go b.worker(id)
+}
+
+// autoscale adds a worker while the pending backlog is above the high
+// watermark and retires an idle one while it is below the low watermark, one
+// step per backlog check. Between the watermarks the count is left alone, so
+// a backlog hovering around either threshold does not flap.
+func (b *Bridge) autoscale() {
+	if !b.autoscaling() || b.ctx.Err() != nil {
+		return
+	}
+
+	backlog := b.backlog.Load()
+	workers := int(b.workers.Load())
+	fields := logrus.Fields{"backlog": backlog, "workers": workers}
+
+	switch {
+	case backlog > int64(b.config.AutoscaleHighWatermark) && workers < b.config.MaxWorkers:
+		b.startWorker()
+		b.logger.WithFields(fields).Info("Backlog above high watermark, added a worker")
+	case backlog < int64(b.config.AutoscaleLowWatermark) && workers > b.config.MinWorkers:
+		// Only a worker waiting for an intent can take this; busy ones are
+		// never interrupted
+		select {
+		case b.retire <- struct{}{}:
+			b.workers.Add(-1)
+			b.logger.WithFields(fields).Info("Backlog below low watermark, retired an idle worker")
+		default:
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+func TestAutoscaleFollowsBacklogWithinBounds(t *testing.T) {
+	st := newFakeStore()
+	cfg := newTestConfig()
+	cfg.WorkerCount = 2
+	cfg.MinWorkers = 1
+	cfg.MaxWorkers = 3
+	cfg.AutoscaleHighWatermark = 4
+	cfg.AutoscaleLowWatermark = 2
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	b.wg.Add(1)
+	go b.dispatch()
+	for i := 0; i < b.initialWorkers(); i++ {
+		b.startWorker()
+	}
+	defer func() {
+		b.cancel()
+		close(b.workQueue)
+		b.wg.Wait()
+		if got := testutil.ToFloat64(b.metrics.ActiveWorkers); got != 0 {
+			t.Errorf("expected no active workers after shutdown, got %v", got)
+		}
+	}()
+
+	setBacklog := func(n int) {
+		st.mu.Lock()
+		st.intents = nil
+		for i := 0; i < n; i++ {
+			st.intents = append(st.intents, &mongodb.PushIntent{ID: fmt.Sprintf("pending-%d", i)})
+		}
+		st.mu.Unlock()
+	}
+
+	// check runs a backlog check and waits for the pool to settle on want. A
+	// retire only lands once a worker is idle, so the check is repeated until
+	// the count moves; the active gauge follows as workers start and exit.
+	check := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if err := b.updateBacklogAge(); err != nil {
+				t.Fatalf("updateBacklogAge failed: %v", err)
+			}
+			b.autoscale()
+			if b.workerCount() == want {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d workers, got %d", want, b.workerCount())
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		for testutil.ToFloat64(b.metrics.ActiveWorkers) != float64(want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected active workers gauge of %d, got %v", want, testutil.ToFloat64(b.metrics.ActiveWorkers))
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// Between the watermarks nothing changes
+	setBacklog(3)
+	check(2)
+
+	// Above the high watermark the pool grows one worker per check up to
+	// MAX_WORKERS
+	setBacklog(10)
+	check(3)
+	check(3)
+
+	setBacklog(3)
+	check(3)
+
+	// Below the low watermark idle workers retire down to MIN_WORKERS
+	setBacklog(0)
+	check(2)
+	check(1)
+	check(1)
+
+	// And the pool grows again with the next burst
+	setBacklog(5)
+	check(2)
+}
+
+func TestInitialWorkersClampedToAutoscaleBounds(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.WorkerCount = 8
+	b := newBridge(context.Background(), cfg, newFakeStore(), newTestMetrics(), newTestLogger())
+	if got := b.initialWorkers(); got != 8 {
+		t.Errorf("expected WORKER_COUNT without autoscaling, got %d", got)
+	}
+
+	cfg.MinWorkers = 1
+	cfg.MaxWorkers = 4
+	if got := b.initialWorkers(); got != 4 {
+		t.Errorf("expected the count clamped to MAX_WORKERS, got %d", got)
+	}
+}
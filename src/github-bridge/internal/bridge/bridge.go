@@ -2,35 +2,194 @@ package bridge
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/mail"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime/debug"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/api"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/discovery"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/hook"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/tlsconfig"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/tracing"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/transform"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// store is the subset of MongoDB operations used by the bridge
+type store interface {
+	GetPendingPushIntents(ctx context.Context, limit int) ([]*mongodb.PushIntent, error)
+	OldestPendingIntentAge(ctx context.Context) (time.Duration, error)
+	CountPendingPushIntents(ctx context.Context) (int64, error)
+	StreamDocumentsByIDs(ctx context.Context, ids []string, fn func(*mongodb.Document) error) error
+	MarkPushIntentProcessed(ctx context.Context, id string, err error) error
+	RecordPendingMark(ctx context.Context, mark *mongodb.PendingMark) error
+	GetPendingMark(ctx context.Context, intentID string) (*mongodb.PendingMark, error)
+	GetPendingMarks(ctx context.Context, limit int) ([]*mongodb.PendingMark, error)
+	DeletePendingMark(ctx context.Context, intentID string) error
+	QuarantinePushIntent(ctx context.Context, intent *mongodb.PushIntent, reason string) error
+	InsertAuditRecord(ctx context.Context, record *mongodb.AuditRecord) error
+	SaveDryRunResult(ctx context.Context, result *mongodb.DryRunResult) error
+	InsertBatchSummary(ctx context.Context, summary *mongodb.BatchSummary) error
+	CreatePushIntent(ctx context.Context, intent *mongodb.PushIntent, documents []*mongodb.Document) (string, error)
+	WatchPushIntents(ctx context.Context, since time.Time) (*mongo.ChangeStream, error)
+	UnprocessedPushIntentIDs(ctx context.Context, ids []string) (map[string]bool, error)
+	AcquireRepoLock(ctx context.Context, repo, branch, owner string, ttl time.Duration) (bool, error)
+	ReleaseRepoLock(ctx context.Context, repo, branch, owner string) error
+	Close(ctx context.Context) error
+}
+
 // Bridge handles syncing between MongoDB and GitHub
 type Bridge struct {
 	config    *config.Config
-	mongo     *mongodb.Client
+	mongo     store
 	logger    *logrus.Logger
+	metrics   *metrics.Metrics
 	ctx       context.Context
 	cancel    context.CancelFunc
 	wg        sync.WaitGroup
+	producers sync.WaitGroup
 	workQueue chan *mongodb.PushIntent
+
+	// ready hands the highest priority held intent to the next free worker
+	ready chan *mongodb.PushIntent
+
+	// workers counts running workers; an idle worker receiving from retire
+	// exits so autoscaling can shrink the pool
+	workers      atomic.Int64
+	nextWorkerID atomic.Int64
+	retire       chan struct{}
+
+	gitBackend     git.Backend
+	transformer    transform.Transformer
+	commitLocation *time.Location
+	signKey        *openpgp.Entity
+
+	shutdownOnce sync.Once
+
+	// discoverer keeps repoAllowlist current when repository discovery is
+	// enabled; without it every intent repo is accepted
+	discoverer    *discovery.Discoverer
+	repoAllowlist repoAllowlist
+
+	// cycles rolls intent outcomes up into batch summaries
+	cycles cycleTracker
+
+	// pushCooldown is how long intents for a branch wait after it was
+	// pushed; branchPushes holds the last push time per branch
+	pushCooldown time.Duration
+	branchPushMu sync.Mutex
+	branchPushes map[string]time.Time
+
+	// repoLockTTL is how long a lock on the target branch lasts without
+	// renewal; lockOwner identifies this process among the replicas
+	repoLockTTL time.Duration
+	lockOwner   string
+
+	// Status snapshot state, read concurrently by the status endpoint
+	build        api.BuildInfo
+	startedAt    time.Time
+	inFlight     atomic.Int64
+	queued       atomic.Int64
+	backlog      atomic.Int64
+	pendingMarks atomic.Int64
+	lastPush     atomic.Int64 // unix nanoseconds
+
+	// replayFrom is where the next change stream starts while replaying
+	// history, and replayUntil the live point at which replay ends. Both
+	// are only used by the change stream goroutine.
+	replayFrom  time.Time
+	replayUntil time.Time
+
+	// resumed is non-nil while processing is paused and is closed on resume
+	pauseMu sync.Mutex
+	resumed chan struct{}
+
+	// streamed holds the intents the change stream handed out that have not
+	// finished processing, so an update to a queued or in-flight intent
+	// does not queue it again
+	streamedMu sync.Mutex
+	streamed   map[string]bool
+
+	// clones keeps clones between intents with CACHE_CLONES
+	clones *cloneCache
+
+	// report collects intent outcomes in once-mode; nil otherwise
+	report *reportRecorder
+
+	// tracer starts the spans of each intent; shutdownTracing flushes them
+	// on shutdown when tracing is enabled
+	tracer          trace.Tracer
+	shutdownTracing func(context.Context) error
+
+	// postPushHook runs after each successful push; nil when not configured
+	postPushHook *hook.Hook
+
+	// workCtx carries the git and MongoDB operations of in-flight intents.
+	// It outlives ctx so that workers drain on shutdown, and is cancelled
+	// when the shutdown deadline passes. busy holds the intent each worker
+	// is processing.
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+	busyMu     sync.Mutex
+	busy       map[int]busyWorker
+}
+
+// busyWorker is the intent a worker is processing and when it started
+type busyWorker struct {
+	intentID string
+	since    time.Time
 }
 
 // New creates a new Bridge instance
-func New(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (*Bridge, error) {
+func New(ctx context.Context, cfg *config.Config, m *metrics.Metrics, logger *logrus.Logger) (*Bridge, error) {
+	transformer, err := transform.Build(cfg.Transformers, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build transformers: %w", err)
+	}
+
+	signKey, err := loadSigningKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	mongoTLS, err := tlsconfig.Load(cfg.MongoDBCAFile, cfg.MongoDBClientCert, cfg.MongoDBClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load MongoDB TLS settings: %w", err)
+	}
+
+	githubTLS, err := tlsconfig.Load(cfg.GitHubCAFile, cfg.GitHubClientCert, cfg.GitHubClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load GitHub TLS settings: %w", err)
+	}
+
 	// Connect to MongoDB
-	mongoClient, err := mongodb.NewClient(ctx, cfg.MongoDBURI, cfg.MongoDBDatabase)
+	mongoClient, err := mongodb.NewClient(ctx, mongodb.ClientOptions{
+		URI:                    cfg.MongoDBURI,
+		Database:               cfg.MongoDBDatabase,
+		MaxPoolSize:            uint64(cfg.MongoDBMaxPoolSize),
+		ConnectTimeout:         time.Duration(cfg.MongoDBConnectTimeout) * time.Second,
+		ServerSelectionTimeout: time.Duration(cfg.MongoDBServerSelectionTimeout) * time.Second,
+		TLSConfig:              mongoTLS,
+		DocFetchChunkSize:      cfg.DocFetchChunkSize,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create MongoDB client: %w", err)
 	}
@@ -40,90 +199,420 @@ func New(ctx context.Context, cfg *config.Config, logger *logrus.Logger) (*Bridg
 		logger.WithError(err).Warn("Failed to create indexes")
 	}
 
+	git.InstallHTTPClient(git.NewHTTPClient(git.HTTPOptions{
+		MaxIdleConnsPerHost: cfg.GitHTTPMaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.GitHTTPIdleConnTimeout) * time.Second,
+		KeepAlive:           time.Duration(cfg.GitHTTPKeepAlive) * time.Second,
+		EnableHTTP2:         cfg.GitHTTP2,
+		TLSConfig:           githubTLS,
+		Metrics:             m,
+	}), m)
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracingEndpoint != "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up tracing: %w", err)
+	}
+
+	b := newBridge(ctx, cfg, mongoClient, m, logger)
+	b.transformer = transformer
+	b.signKey = signKey
+	b.shutdownTracing = shutdownTracing
+
+	if cfg.RepoDiscoveryPattern != "" {
+		httpClient := http.DefaultClient
+		if githubTLS != nil {
+			httpClient = &http.Client{Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: githubTLS,
+			}}
+		}
+
+		b.discoverer, err = discovery.New(discovery.Options{
+			Organization: cfg.GitHubOrganization,
+			Pattern:      cfg.RepoDiscoveryPattern,
+			Token:        cfg.GitHubToken,
+			Interval:     time.Duration(cfg.RepoDiscoveryInterval) * time.Second,
+			HTTPClient:   httpClient,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up repository discovery: %w", err)
+		}
+		b.repoAllowlist = b.discoverer.Allowlist()
+	}
+
+	return b, nil
+}
+
+// repoAllowlist decides which intent repos may be pushed
+type repoAllowlist interface {
+	Ready() bool
+	Allowed(repo string) bool
+}
+
+// loadSigningKey loads the configured signing key, refusing to start when it
+// would produce commits GitHub cannot verify for any committer email
+func loadSigningKey(cfg *config.Config) (*openpgp.Entity, error) {
+	if !cfg.EnableSigning {
+		return nil, nil
+	}
+
+	key, err := git.LoadSigningKey(cfg.GPGKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := git.VerifySignerIdentity(key, cfg.GitUserEmail); err != nil {
+		return nil, fmt.Errorf("GPG_KEY_PATH cannot sign as GIT_USER_EMAIL: %w", err)
+	}
+	for _, identity := range cfg.IdentityMap {
+		if err := git.VerifySignerIdentity(key, identity.Email); err != nil {
+			return nil, fmt.Errorf("GPG_KEY_PATH cannot sign as IDENTITY_MAP email for %s: %w", identity.Branch, err)
+		}
+	}
+
+	return key, nil
+}
+
+// newBridge wires a Bridge around an already connected store
+func newBridge(ctx context.Context, cfg *config.Config, st store, m *metrics.Metrics, logger *logrus.Logger) *Bridge {
 	bridgeCtx, cancel := context.WithCancel(ctx)
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+
+	// Validate has already checked the zone; fall back to UTC regardless
+	location, err := time.LoadLocation(cfg.CommitTimezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	var postPushHook *hook.Hook
+	if cfg.PostPushHook != "" {
+		postPushHook = hook.New(hook.Options{
+			Target:  cfg.PostPushHook,
+			Timeout: time.Duration(cfg.PostPushHookTimeout) * time.Second,
+		}, logger)
+	}
 
 	return &Bridge{
 		config:    cfg,
-		mongo:     mongoClient,
+		mongo:     st,
 		logger:    logger,
+		metrics:   m,
 		ctx:       bridgeCtx,
 		cancel:    cancel,
 		workQueue: make(chan *mongodb.PushIntent, cfg.BatchSize),
-	}, nil
+		ready:     make(chan *mongodb.PushIntent),
+		retire:    make(chan struct{}),
+
+		gitBackend:     git.NetworkBackend{},
+		commitLocation: location,
+		startedAt:      time.Now(),
+		pushCooldown:   time.Duration(cfg.PushCooldown) * time.Second,
+		branchPushes:   make(map[string]time.Time),
+		streamed:       make(map[string]bool),
+		clones:         newCloneCache(),
+		repoLockTTL:    time.Duration(cfg.RepoLockTTL) * time.Second,
+		lockOwner:      lockOwner(),
+		replayFrom:     cfg.ReplaySince,
+		tracer:         otel.Tracer(tracerName),
+		postPushHook:   postPushHook,
+		workCtx:        workCtx,
+		cancelWork:     cancelWork,
+		busy:           make(map[int]busyWorker),
+	}
+}
+
+// RegisterHandlers mounts the bridge HTTP API on mux. /status is public like
+// /metrics, whose data it summarizes; the rest of the API requires
+// ADMIN_TOKEN and is not mounted when no token is configured.
+func (b *Bridge) RegisterHandlers(mux *http.ServeMux) {
+	mux.Handle("/status", api.NewStatusHandler(b))
+
+	if b.config.AdminToken == "" {
+		b.logger.Warn("ADMIN_TOKEN not set, HTTP API disabled")
+		return
+	}
+
+	mux.Handle("/intents", api.RequireBearer(b.config.AdminToken, api.NewIntentHandler(b.mongo, b.logger)))
+	mux.Handle("/admin/pause", api.RequireBearer(b.config.AdminToken, api.NewPauseHandler(b, b.logger)))
+	mux.Handle("/admin/resume", api.RequireBearer(b.config.AdminToken, api.NewResumeHandler(b, b.logger)))
+}
+
+// SetBuildInfo records the version reported by the status endpoint. Call it
+// before serving HTTP.
+func (b *Bridge) SetBuildInfo(info api.BuildInfo) {
+	b.build = info
+}
+
+// Status implements api.StatusProvider. Every field is read from atomics or
+// under its lock, so it is safe to call while the bridge runs.
+func (b *Bridge) Status() api.Status {
+	status := api.Status{
+		BuildInfo:     b.build,
+		StartedAt:     b.startedAt,
+		UptimeSeconds: time.Since(b.startedAt).Seconds(),
+		Workers:       b.workerCount(),
+		InFlight:      b.inFlight.Load(),
+		QueueDepth:    b.queued.Load(),
+		Backlog:       b.backlog.Load(),
+		// There is no leader election; every instance processes intents
+		Leader:       true,
+		Paused:       b.Paused(),
+		Circuit:      api.CircuitClosed,
+		PendingMarks: b.pendingMarks.Load(),
+	}
+
+	if status.PendingMarks > 0 {
+		status.Circuit = api.CircuitOpen
+	}
+	if last := b.lastPush.Load(); last != 0 {
+		at := time.Unix(0, last)
+		status.LastPushAt = &at
+	}
+
+	return status
+}
+
+// Pause stops producers enqueueing and workers starting new intents until
+// Resume is called. Everything not yet started stays pending in MongoDB.
+func (b *Bridge) Pause() {
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+
+	if b.resumed == nil {
+		b.resumed = make(chan struct{})
+		b.metrics.Paused.Set(1)
+	}
+}
+
+// Resume continues processing after Pause
+func (b *Bridge) Resume() {
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+
+	if b.resumed != nil {
+		close(b.resumed)
+		b.resumed = nil
+		b.metrics.Paused.Set(0)
+	}
+}
+
+// Paused reports whether processing is paused
+func (b *Bridge) Paused() bool {
+	b.pauseMu.Lock()
+	defer b.pauseMu.Unlock()
+
+	return b.resumed != nil
+}
+
+// waitWhilePaused blocks until processing is resumed. It returns false if
+// the bridge shuts down first.
+func (b *Bridge) waitWhilePaused() bool {
+	b.pauseMu.Lock()
+	resumed := b.resumed
+	b.pauseMu.Unlock()
+
+	if resumed == nil {
+		return true
+	}
+
+	select {
+	case <-resumed:
+		return true
+	case <-b.ctx.Done():
+		return false
+	}
 }
 
 // Start begins the bridge operations
 func (b *Bridge) Start() error {
 	b.logger.Info("Starting GitHub Bridge")
 
-	// Start workers
-	for i := 0; i < b.config.WorkerCount; i++ {
-		b.wg.Add(1)
-		go b.worker(i)
+	// Start workers behind the priority dispatcher
+	b.wg.Add(1)
+	go b.dispatch()
+
+	for i := 0; i < b.initialWorkers(); i++ {
+		b.startWorker()
 	}
 
 	// Start watching for changes if webhooks are disabled
 	if !b.config.EnableWebhooks {
-		b.wg.Add(1)
+		b.producers.Add(1)
 		go b.pollForChanges()
 	} else {
-		b.wg.Add(1)
+		b.producers.Add(1)
 		go b.watchChanges()
 	}
 
-	// Wait for all workers to complete
+	b.wg.Add(1)
+	go b.monitorBacklog()
+
+	b.wg.Add(1)
+	go b.reconcilePendingMarks()
+
+	if b.config.CacheClones && b.config.GCInterval > 0 {
+		b.wg.Add(1)
+		go b.collectGarbage()
+	}
+
+	if b.discoverer != nil {
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			b.discoverer.Run(b.ctx)
+		}()
+	}
+
+	// Wait for all producers and workers to complete
+	b.producers.Wait()
 	b.wg.Wait()
 	return nil
 }
 
-// Shutdown gracefully shuts down the bridge
+// Shutdown gracefully shuts down the bridge. It is safe to call more than
+// once; subsequent calls block until the first one has finished. It returns
+// ctx's error when in-flight work had to be cancelled at the deadline.
 func (b *Bridge) Shutdown(ctx context.Context) error {
+	var err error
+	b.shutdownOnce.Do(func() {
+		err = b.shutdown(ctx)
+	})
+	return err
+}
+
+// shutdownCleanupTimeout bounds closing MongoDB and flushing traces once the
+// shutdown deadline has already passed
+const shutdownCleanupTimeout = 5 * time.Second
+
+func (b *Bridge) shutdown(ctx context.Context) error {
 	b.logger.Info("Shutting down GitHub Bridge")
-	
-	// Cancel context to stop all operations
+
+	// Cancel context to stop taking new intents; in-flight intents run on
+	// the work context until they finish or the deadline passes
 	b.cancel()
-	
-	// Close work queue
-	close(b.workQueue)
-	
-	// Wait for shutdown or timeout
+	defer b.cancelWork()
+
+	// Close the work queue only once every producer has stopped sending,
+	// then wait for the workers to drain
 	done := make(chan struct{})
 	go func() {
+		b.producers.Wait()
+		close(b.workQueue)
 		b.wg.Wait()
 		close(done)
 	}()
 
+	cleanupCtx := ctx
+	var timedOut error
 	select {
 	case <-done:
 		b.logger.Info("All workers stopped")
 	case <-ctx.Done():
-		b.logger.Warn("Shutdown timeout exceeded")
+		b.logger.Warn("Shutdown timeout exceeded, cancelling in-flight work")
+		b.logBusyWorkers()
+		b.cancelWork()
+		timedOut = ctx.Err()
+
+		// ctx has expired, so cleanup gets a deadline of its own
+		var cancel context.CancelFunc
+		cleanupCtx, cancel = context.WithTimeout(context.Background(), shutdownCleanupTimeout)
+		defer cancel()
 	}
 
+	// Workers cut off at the deadline remove their clones as they finish
+	b.clones.close()
+
 	// Close MongoDB connection
-	if err := b.mongo.Close(context.Background()); err != nil {
+	if err := b.mongo.Close(cleanupCtx); err != nil {
 		b.logger.WithError(err).Error("Failed to close MongoDB connection")
 	}
 
-	return nil
+	// Flush the spans of the intents processed before stopping
+	if b.shutdownTracing != nil {
+		if err := b.shutdownTracing(cleanupCtx); err != nil {
+			b.logger.WithError(err).Warn("Failed to flush traces")
+		}
+	}
+
+	return timedOut
+}
+
+// logBusyWorkers logs each worker still processing an intent
+func (b *Bridge) logBusyWorkers() {
+	b.busyMu.Lock()
+	defer b.busyMu.Unlock()
+
+	for id, w := range b.busy {
+		b.logger.WithFields(logrus.Fields{
+			"worker_id": id,
+			"intent_id": w.intentID,
+			"busy_for":  time.Since(w.since).Round(time.Millisecond).String(),
+		}).Warn("Worker still busy at shutdown deadline")
+	}
+}
+
+// markBusy records that workerID is processing intent until the returned
+// func is called
+func (b *Bridge) markBusy(workerID int, intent *mongodb.PushIntent) func() {
+	b.busyMu.Lock()
+	b.busy[workerID] = busyWorker{intentID: intent.ID, since: time.Now()}
+	b.busyMu.Unlock()
+
+	return func() {
+		b.busyMu.Lock()
+		delete(b.busy, workerID)
+		b.busyMu.Unlock()
+	}
 }
 
 // worker processes push intents from the queue
 func (b *Bridge) worker(id int) {
 	defer b.wg.Done()
-	
+
 	b.logger.WithField("worker_id", id).Info("Worker started")
-	metrics.ActiveWorkers.Inc()
-	defer metrics.ActiveWorkers.Dec()
+	b.metrics.ActiveWorkers.Inc()
+	defer b.metrics.ActiveWorkers.Dec()
+
+	// autoscale already discounted a retired worker when handing it the signal
+	retired := false
+	defer func() {
+		if !retired {
+			b.workers.Add(-1)
+		}
+	}()
+
+	for {
+		// Idle without claiming intents while paused
+		if !b.waitWhilePaused() {
+			return
+		}
+
+		var intent *mongodb.PushIntent
+		var ok bool
+		select {
+		case intent, ok = <-b.ready:
+		case <-b.retire:
+			retired = true
+			b.logger.WithField("worker_id", id).Info("Worker retired")
+			return
+		}
+		if !ok {
+			break
+		}
+
+		// A pause may land while waiting on the queue; hold the claimed
+		// intent, which is still pending in MongoDB, until resumed
+		if !b.waitWhilePaused() {
+			return
+		}
 
-	for intent := range b.workQueue {
 		select {
 		case <-b.ctx.Done():
 			return
 		default:
-			if err := b.processPushIntent(intent); err != nil {
+			if err := b.handleIntent(id, intent); err != nil {
 				b.logger.WithError(err).WithField("intent_id", intent.ID).Error("Failed to process push intent")
-				metrics.ErrorsByType.WithLabelValues("processing").Inc()
+				b.metrics.ErrorsByType.WithLabelValues("processing").Inc()
 			}
 		}
 	}
@@ -131,10 +620,44 @@ func (b *Bridge) worker(id int) {
 	b.logger.WithField("worker_id", id).Info("Worker stopped")
 }
 
+// handleIntent processes an intent, recovering from panics so a malformed
+// intent cannot take its worker down. The offending intent is quarantined.
+func (b *Bridge) handleIntent(workerID int, intent *mongodb.PushIntent) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		b.metrics.WorkerPanics.Inc()
+		reason := fmt.Sprintf("panic: %v", r)
+		b.logger.WithFields(logrus.Fields{
+			"worker_id": workerID,
+			"intent_id": intent.ID,
+			"panic":     r,
+			"stack":     string(debug.Stack()),
+		}).Error("Worker panicked, quarantining push intent")
+
+		if qErr := b.mongo.QuarantinePushIntent(b.workCtx, intent, reason); qErr != nil {
+			b.logger.WithError(qErr).WithField("intent_id", intent.ID).Error("Failed to quarantine push intent")
+			b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+		}
+
+		err = fmt.Errorf("push intent %s quarantined after %s", intent.ID, reason)
+		b.report.record(intent.ID, outcomeFailed, "", err)
+	}()
+
+	b.inFlight.Add(1)
+	defer b.inFlight.Add(-1)
+	defer b.markBusy(workerID, intent)()
+
+	return b.processPushIntent(intent)
+}
+
 // pollForChanges polls MongoDB for new push intents
 func (b *Bridge) pollForChanges() {
-	defer b.wg.Done()
-	
+	defer b.producers.Done()
+
 	ticker := time.NewTicker(time.Duration(b.config.PollInterval) * time.Second)
 	defer ticker.Stop()
 
@@ -143,227 +666,1228 @@ func (b *Bridge) pollForChanges() {
 		case <-b.ctx.Done():
 			return
 		case <-ticker.C:
+			if b.Paused() {
+				continue
+			}
 			if err := b.checkForPushIntents(); err != nil {
 				b.logger.WithError(err).Error("Failed to check for push intents")
-				metrics.ErrorsByType.WithLabelValues("polling").Inc()
+				b.metrics.ErrorsByType.WithLabelValues("polling").Inc()
 			}
 		}
 	}
 }
 
-// watchChanges uses MongoDB change streams to watch for new push intents
-func (b *Bridge) watchChanges() {
+// monitorBacklog periodically reports the age of the oldest pending intent
+func (b *Bridge) monitorBacklog() {
 	defer b.wg.Done()
 
+	ticker := time.NewTicker(time.Duration(b.config.BacklogCheckInterval) * time.Second)
+	defer ticker.Stop()
+
 	for {
+		if err := b.updateBacklogAge(); err != nil {
+			b.logger.WithError(err).Warn("Failed to check pending intent backlog")
+			b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+		} else {
+			b.autoscale()
+		}
+
 		select {
 		case <-b.ctx.Done():
 			return
-		default:
-			if err := b.watchChangeStream(); err != nil {
-				b.logger.WithError(err).Error("Change stream error, retrying in 5 seconds")
-				metrics.ErrorsByType.WithLabelValues("changestream").Inc()
-				time.Sleep(5 * time.Second)
-			}
+		case <-ticker.C:
 		}
 	}
 }
 
-// watchChangeStream watches MongoDB for new push intents
-func (b *Bridge) watchChangeStream() error {
-	stream, err := b.mongo.WatchPushIntents(b.ctx)
+// updateBacklogAge refreshes the oldest pending intent age gauge
+func (b *Bridge) updateBacklogAge() error {
+	age, err := b.mongo.OldestPendingIntentAge(b.ctx)
 	if err != nil {
 		return err
 	}
-	defer stream.Close(b.ctx)
 
-	b.logger.Info("Watching for push intents via change stream")
+	b.metrics.OldestPendingIntentAge.Set(age.Seconds())
 
-	for stream.Next(b.ctx) {
-		var event struct {
-			FullDocument *mongodb.PushIntent `bson:"fullDocument"`
-		}
+	count, err := b.mongo.CountPendingPushIntents(b.ctx)
+	if err != nil {
+		return err
+	}
+	b.backlog.Store(count)
+	return nil
+}
 
-		if err := stream.Decode(&event); err != nil {
-			b.logger.WithError(err).Error("Failed to decode change event")
-			continue
-		}
+// watchChanges uses MongoDB change streams to watch for new push intents
+func (b *Bridge) watchChanges() {
+	defer b.producers.Done()
 
-		if event.FullDocument != nil && !event.FullDocument.Processed {
-			select {
-			case b.workQueue <- event.FullDocument:
-				metrics.QueueSize.Inc()
-			case <-b.ctx.Done():
-				return nil
+	b.producers.Add(1)
+	go b.sweepPending()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		default:
+			if err := b.watchChangeStream(); err != nil {
+				b.logger.WithError(err).Error("Change stream error, retrying in 5 seconds")
+				b.metrics.ErrorsByType.WithLabelValues("changestream").Inc()
+				time.Sleep(5 * time.Second)
 			}
 		}
 	}
+}
 
-	return stream.Err()
+// sweepPending periodically hands out pending intents alongside the change
+// stream. The stream delivers an intent once, so one left pending afterwards,
+// deferred for low disk or until the repository allowlist loads, is only
+// retried from here.
+func (b *Bridge) sweepPending() {
+	defer b.producers.Done()
+
+	ticker := time.NewTicker(time.Duration(b.config.StreamSweepInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			if b.Paused() {
+				continue
+			}
+			if err := b.sweepPendingIntents(); err != nil {
+				b.logger.WithError(err).Error("Failed to sweep pending push intents")
+				b.metrics.ErrorsByType.WithLabelValues("polling").Inc()
+			}
+		}
+	}
 }
 
-// checkForPushIntents checks for pending push intents
-func (b *Bridge) checkForPushIntents() error {
+// sweepPendingIntents enqueues pending intents, leaving out those the change
+// stream handed out that are still queued or in flight
+func (b *Bridge) sweepPendingIntents() error {
 	intents, err := b.mongo.GetPendingPushIntents(b.ctx, b.config.BatchSize)
 	if err != nil {
 		return err
 	}
 
+	intents = b.dropStreamed(intents)
 	if len(intents) == 0 {
 		return nil
 	}
 
-	b.logger.WithField("count", len(intents)).Debug("Found pending push intents")
+	b.logger.WithField("count", len(intents)).Debug("Sweeping pending push intents")
 
-	for _, intent := range intents {
-		select {
-		case b.workQueue <- intent:
-			metrics.QueueSize.Inc()
-		case <-b.ctx.Done():
-			return nil
+	b.enqueueBatch(intents)
+	return nil
+}
+
+// watchChangeStream watches MongoDB for new push intents
+func (b *Bridge) watchChangeStream() error {
+	since := b.replayFrom
+	stream, err := b.mongo.WatchPushIntents(b.ctx, since)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(b.ctx)
+
+	if since.IsZero() {
+		b.logger.Info("Watching for push intents via change stream")
+	} else {
+		if b.replayUntil.IsZero() {
+			b.replayUntil = time.Now()
 		}
+		b.logger.WithFields(logrus.Fields{
+			"since": since,
+			"until": b.replayUntil,
+		}).Info("Replaying push intents via change stream")
 	}
 
-	return nil
+	return b.drainChangeStream(stream)
 }
 
-// processPushIntent processes a single push intent
-func (b *Bridge) processPushIntent(intent *mongodb.PushIntent) error {
-	defer func() {
-		metrics.QueueSize.Dec()
-	}()
+// changeStream is the subset of *mongo.ChangeStream read by the bridge
+type changeStream interface {
+	Next(ctx context.Context) bool
+	TryNext(ctx context.Context) bool
+	Decode(val interface{}) error
+	Err() error
+}
 
-	timer := time.Now()
-	metrics.PushAttempts.Inc()
+// drainChangeStream reads change events and enqueues their intents. After
+// each blocking read it collects whatever further events are already
+// available, up to BatchSize, and hands them to the workers together.
+func (b *Bridge) drainChangeStream(stream changeStream) error {
+	batch := make([]*mongodb.PushIntent, 0, b.config.BatchSize)
 
-	b.logger.WithFields(logrus.Fields{
-		"id":     intent.ID,
-		"repo":   intent.Repo,
-		"branch": intent.Branch,
-		"author": intent.Author,
-	}).Info("Processing push intent")
+	for stream.Next(b.ctx) {
+		replaying := !b.replayFrom.IsZero()
 
-	// Process the intent
-	err := b.pushToGitHub(intent)
-	
-	// Mark as processed regardless of outcome
-	if markErr := b.mongo.MarkPushIntentProcessed(b.ctx, intent.ID, err); markErr != nil {
-		b.logger.WithError(markErr).Error("Failed to mark push intent as processed")
-		metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
-	}
+		batch = b.appendChangeEvent(batch[:0], stream)
+		for len(batch) < b.config.BatchSize && stream.TryNext(b.ctx) {
+			batch = b.appendChangeEvent(batch, stream)
+		}
 
-	metrics.BatchDuration.Observe(time.Since(timer).Seconds())
+		// Replayed events carry the intent as it was then, so check which
+		// are still pending before handing them out again
+		if replaying {
+			var err error
+			if batch, err = b.dropProcessed(batch); err != nil {
+				return err
+			}
+		}
 
-	if err != nil {
-		metrics.PushFailures.Inc()
-		return err
+		if !b.enqueueBatch(b.dropStreamed(batch)) {
+			return nil
+		}
 	}
 
-	metrics.PushSuccesses.Inc()
-	return nil
+	return stream.Err()
 }
 
-// pushToGitHub performs the actual push operation
-func (b *Bridge) pushToGitHub(intent *mongodb.PushIntent) error {
-	if b.config.DryRun {
-		b.logger.Info("DRY RUN: Would push to GitHub")
-		return nil
+// appendChangeEvent decodes the current change event and appends its intent
+// when it still needs processing
+func (b *Bridge) appendChangeEvent(batch []*mongodb.PushIntent, stream changeStream) []*mongodb.PushIntent {
+	var event struct {
+		ClusterTime  primitive.Timestamp `bson:"clusterTime"`
+		FullDocument *mongodb.PushIntent `bson:"fullDocument"`
 	}
 
-	// Get documents for this push intent
-	documents, err := b.mongo.GetDocumentsByIDs(b.ctx, intent.Documents)
+	if err := stream.Decode(&event); err != nil {
+		b.logger.WithError(err).Error("Failed to decode change event")
+		return batch
+	}
+
+	if !b.replayFrom.IsZero() {
+		b.advanceReplay(time.Unix(int64(event.ClusterTime.T), 0))
+	}
+
+	if event.FullDocument != nil && !event.FullDocument.Processed {
+		batch = append(batch, event.FullDocument)
+	}
+	return batch
+}
+
+// advanceReplay records replay progress so a reconnect resumes where the
+// stream left off, and ends replay once events reach the live point
+func (b *Bridge) advanceReplay(eventTime time.Time) {
+	if eventTime.After(b.replayFrom) {
+		b.replayFrom = eventTime
+	}
+
+	if !eventTime.Before(b.replayUntil) {
+		b.logger.WithField("until", b.replayUntil).Info("Replay caught up, streaming live")
+		b.replayFrom = time.Time{}
+	}
+}
+
+// dropStreamed removes intents the change stream already handed out that
+// are still queued or in flight, and records the rest as handed out
+func (b *Bridge) dropStreamed(intents []*mongodb.PushIntent) []*mongodb.PushIntent {
+	b.streamedMu.Lock()
+	defer b.streamedMu.Unlock()
+
+	kept := intents[:0]
+	for _, intent := range intents {
+		if b.streamed[intent.ID] {
+			b.logger.WithField("intent_id", intent.ID).Debug("Push intent already queued, ignoring change event")
+			continue
+		}
+		b.streamed[intent.ID] = true
+		kept = append(kept, intent)
+	}
+	return kept
+}
+
+// finishStreamed lets the change stream hand out intentID again
+func (b *Bridge) finishStreamed(intentID string) {
+	b.streamedMu.Lock()
+	delete(b.streamed, intentID)
+	b.streamedMu.Unlock()
+}
+
+// dropProcessed removes intents that have been processed since they were
+// inserted
+func (b *Bridge) dropProcessed(intents []*mongodb.PushIntent) ([]*mongodb.PushIntent, error) {
+	if len(intents) == 0 {
+		return intents, nil
+	}
+
+	ids := make([]string, 0, len(intents))
+	for _, intent := range intents {
+		ids = append(ids, intent.ID)
+	}
+
+	pending, err := b.mongo.UnprocessedPushIntentIDs(b.ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check replayed intents: %w", err)
+	}
+
+	kept := intents[:0]
+	for _, intent := range intents {
+		if pending[intent.ID] {
+			kept = append(kept, intent)
+		}
+	}
+	return kept, nil
+}
+
+// checkForPushIntents checks for pending push intents
+func (b *Bridge) checkForPushIntents() error {
+	intents, err := b.mongo.GetPendingPushIntents(b.ctx, b.config.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	if len(intents) == 0 {
+		return nil
+	}
+
+	b.logger.WithField("count", len(intents)).Debug("Found pending push intents")
+
+	b.enqueueBatch(intents)
+	return nil
+}
+
+// enqueue hands an intent to the workers, blocking while processing is
+// paused. It returns false once the bridge is shutting down and the intent
+// was not queued.
+func (b *Bridge) enqueue(intent *mongodb.PushIntent) bool {
+	return b.enqueueBatch([]*mongodb.PushIntent{intent})
+}
+
+// enqueueBatch hands intents to the workers in order, updating the queue
+// size gauge once for the whole batch. It returns false if the bridge shut
+// down before every intent was queued.
+func (b *Bridge) enqueueBatch(intents []*mongodb.PushIntent) (queued bool) {
+	if len(intents) == 0 {
+		return true
+	}
+
+	// Count the batch up front so workers finishing early cannot drive the
+	// gauge negative, and give back whatever was not sent
+	sent := 0
+	b.metrics.QueueSize.Add(float64(len(intents)))
+	b.queued.Add(int64(len(intents)))
+	b.cycles.start(intents, b.workerCount())
+	defer func() {
+		if unsent := len(intents) - sent; unsent > 0 {
+			b.metrics.QueueSize.Sub(float64(unsent))
+			b.queued.Add(-int64(unsent))
+			b.cycles.drop(intents[sent:])
+		}
+	}()
+
+	// Producers are stopped before the queue is closed, so this should never
+	// fire; it guards against a send racing a future change to that ordering.
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.WithField("dropped", len(intents)-sent).Warn("Work queue closed, dropping push intents")
+			queued = false
+		}
+	}()
+
+	select {
+	case <-b.ctx.Done():
+		return false
+	default:
+	}
+
+	if !b.waitWhilePaused() {
+		return false
+	}
+
+	for _, intent := range intents {
+		select {
+		case b.workQueue <- intent:
+			sent++
+		case <-b.ctx.Done():
+			return false
+		}
+	}
+
+	return true
+}
+
+// processPushIntent processes a single push intent
+func (b *Bridge) processPushIntent(intent *mongodb.PushIntent) (err error) {
+	// Anything not explicitly succeeded or skipped, including a panic,
+	// counts as failed in the batch summary
+	outcome := outcomeFailed
+	var commitHash string
+	defer func() {
+		b.metrics.QueueSize.Dec()
+		b.queued.Add(-1)
+		b.finishStreamed(intent.ID)
+		b.finishIntent(intent.ID, outcome)
+		b.report.record(intent.ID, outcome, commitHash, err)
+	}()
+
+	// The intent's spans continue the producer's trace when it left one
+	ctx, span := b.startSpan(intentTraceContext(b.workCtx, intent), "push_intent", intentAttributes(intent)...)
+	defer func() {
+		span.SetAttributes(attribute.String("outcome", outcome.String()), attribute.String("commit", commitHash))
+		endSpan(span, err)
+	}()
+
+	timer := time.Now()
+	b.metrics.PushAttempts.Inc()
+
+	b.logger.WithFields(logrus.Fields{
+		"id":     intent.ID,
+		"repo":   intent.Repo,
+		"branch": intent.Branch,
+		"author": intent.Author,
+	}).Info("Processing push intent")
+
+	// Reject oversized intents before their documents are fetched
+	if limit := b.config.MaxDocsPerIntent; limit > 0 && len(intent.Documents) > limit {
+		b.metrics.PushFailures.Inc()
+		return b.rejectPushIntent(intent, "too_many_docs",
+			fmt.Sprintf("intent references %d documents, more than the limit of %d", len(intent.Documents), limit))
+	}
+
+	if !b.branchAllowed(intent.Branch) {
+		b.metrics.PushFailures.Inc()
+		return b.rejectPushIntent(intent, "branch_not_allowed",
+			fmt.Sprintf("branch %q does not match ALLOWED_BRANCHES", intent.Branch))
+	}
+
+	// With repository discovery only discovered repos are accepted. Until
+	// the first listing succeeds intents are left pending rather than rejected.
+	if b.repoAllowlist != nil {
+		if !b.repoAllowlist.Ready() {
+			return fmt.Errorf("repository allowlist not loaded yet, leaving intent %s pending", intent.ID)
+		}
+		if !b.repoAllowlist.Allowed(intent.Repo) {
+			b.metrics.PushFailures.Inc()
+			return b.rejectPushIntent(intent, "repo_not_allowed",
+				fmt.Sprintf("repository %q is not among the discovered repositories", intent.Repo))
+		}
+	}
+
+	// An intent that was pushed but could not be marked is not pushed again;
+	// only its mark is retried. Without knowing, leave the intent pending.
+	markCtx, markSpan := b.startSpan(ctx, "mongo.get_pending_mark")
+	pending, err := b.mongo.GetPendingMark(markCtx, intent.ID)
+	endSpan(markSpan, err)
+	if err != nil {
+		b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+		return fmt.Errorf("failed to check pending mark: %w", err)
+	}
+
+	if pending != nil {
+		b.logger.WithFields(logrus.Fields{
+			"intent_id": intent.ID,
+			"commit":    pending.CommitHash,
+		}).Info("Push intent already processed, retrying its mark")
+		commitHash = pending.CommitHash
+		if pending.Error != "" {
+			err = errors.New(pending.Error)
+		}
+	} else {
+		if err := b.waitForCooldown(intent.Branch); err != nil {
+			return err
+		}
+
+		// Process the intent
+		var documents int
+		commitHash, documents, err = b.pushToGitHub(ctx, intent)
+
+		// The hook runs once the release is tagged and the repo lock is
+		// released, so a slow hook does not hold up the branch
+		if commitHash != "" {
+			b.runPostPushHook(ctx, intent, commitHash, documents)
+		}
+
+		// Leave the intent pending so it is picked up again once disk frees up
+		if errors.Is(err, git.ErrInsufficientDisk) {
+			b.logger.WithError(err).WithField("intent_id", intent.ID).Warn("Deferring push intent until disk space is available")
+			b.metrics.ErrorsByType.WithLabelValues("disk").Inc()
+			outcome = outcomeSkipped
+			return err
+		}
+	}
+
+	// Mark as processed regardless of outcome
+	_, markSpan = b.startSpan(ctx, "mongo.mark_processed")
+	b.markProcessed(intent.ID, commitHash, err, pending != nil)
+	markSpan.End()
+
+	b.metrics.BatchDuration.Observe(time.Since(timer).Seconds())
+
+	if err != nil {
+		b.metrics.PushFailures.Inc()
+		return err
+	}
+
+	b.metrics.PushSuccesses.Inc()
+	outcome = outcomeSucceeded
+	if commitHash == "" {
+		outcome = outcomeSkipped
+	}
+	return nil
+}
+
+// waitForCooldown blocks until PUSH_COOLDOWN has passed since the branch was
+// last pushed, letting GitHub settle before it is cloned again. Only the
+// calling worker waits, so other branches keep moving.
+func (b *Bridge) waitForCooldown(branch string) error {
+	if b.pushCooldown <= 0 {
+		return nil
+	}
+
+	b.branchPushMu.Lock()
+	last, ok := b.branchPushes[branch]
+	b.branchPushMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	wait := time.Until(last.Add(b.pushCooldown))
+	if wait <= 0 {
+		return nil
+	}
+
+	b.logger.WithFields(logrus.Fields{
+		"branch": branch,
+		"wait":   wait.String(),
+	}).Debug("Waiting for branch push cooldown")
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-b.ctx.Done():
+		return fmt.Errorf("cooldown for branch %s interrupted: %w", branch, b.ctx.Err())
+	}
+}
+
+// recordBranchPush starts the cooldown for a branch that was just pushed
+func (b *Bridge) recordBranchPush(branch string) {
+	b.branchPushMu.Lock()
+	defer b.branchPushMu.Unlock()
+
+	b.branchPushes[branch] = time.Now()
+}
+
+// markProcessed marks an intent processed with the outcome of its push. If
+// the mark fails the outcome is recorded as a pending mark so the intent is
+// not pushed again while the reconciler retries.
+func (b *Bridge) markProcessed(intentID, commitHash string, pushErr error, hadPendingMark bool) {
+	markErr := b.mongo.MarkPushIntentProcessed(b.workCtx, intentID, pushErr)
+	if markErr == nil {
+		if hadPendingMark {
+			if err := b.mongo.DeletePendingMark(b.workCtx, intentID); err != nil {
+				b.logger.WithError(err).WithField("intent_id", intentID).Warn("Failed to delete pending mark")
+			}
+		}
+		return
+	}
+
+	logger := b.logger.WithError(markErr).WithFields(logrus.Fields{
+		"intent_id": intentID,
+		"commit":    commitHash,
+	})
+	logger.Error("Failed to mark push intent as processed")
+	b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+
+	mark := &mongodb.PendingMark{
+		IntentID:   intentID,
+		CommitHash: commitHash,
+		CreatedAt:  time.Now(),
+	}
+	if pushErr != nil {
+		mark.Error = pushErr.Error()
+	}
+	if err := b.mongo.RecordPendingMark(b.workCtx, mark); err != nil {
+		logger.WithField("record_error", err).Error("Failed to record pending mark, intent may be pushed again")
+		b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+		return
+	}
+	b.metrics.PendingMarks.Inc()
+	b.pendingMarks.Add(1)
+}
+
+// reconcilePendingMarks periodically retries marks that failed after an
+// intent was processed
+func (b *Bridge) reconcilePendingMarks() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(b.config.PendingMarkRetryInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.reconcileOnce(); err != nil {
+				b.logger.WithError(err).Warn("Failed to reconcile pending marks")
+				b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+			}
+		}
+	}
+}
+
+// reconcileOnce retries a batch of pending marks, removing each once its
+// intent is marked
+func (b *Bridge) reconcileOnce() error {
+	marks, err := b.mongo.GetPendingMarks(b.ctx, b.config.BatchSize)
+	if err != nil {
+		return err
+	}
+	b.metrics.PendingMarks.Set(float64(len(marks)))
+	b.pendingMarks.Store(int64(len(marks)))
+
+	for _, mark := range marks {
+		var outcome error
+		if mark.Error != "" {
+			outcome = errors.New(mark.Error)
+		}
+
+		// An intent that no longer exists has nothing left to mark
+		err := b.mongo.MarkPushIntentProcessed(b.ctx, mark.IntentID, outcome)
+		if err != nil && !errors.Is(err, mongodb.ErrPushIntentNotFound) {
+			b.logger.WithError(err).WithField("intent_id", mark.IntentID).Warn("Pending mark still failing")
+			b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+			continue
+		}
+
+		if err := b.mongo.DeletePendingMark(b.ctx, mark.IntentID); err != nil {
+			return err
+		}
+		b.metrics.PendingMarks.Dec()
+		b.pendingMarks.Add(-1)
+
+		b.logger.WithFields(logrus.Fields{
+			"intent_id": mark.IntentID,
+			"commit":    mark.CommitHash,
+		}).Info("Reconciled pending mark")
+	}
+
+	return nil
+}
+
+// rejectPushIntent moves an intent to the dead-letter collection without
+// attempting it, counting the rejection under reason
+func (b *Bridge) rejectPushIntent(intent *mongodb.PushIntent, reason, detail string) error {
+	b.metrics.IntentsRejected.WithLabelValues(reason).Inc()
+	b.logger.WithFields(logrus.Fields{
+		"intent_id": intent.ID,
+		"reason":    reason,
+	}).Warn("Rejecting push intent: " + detail)
+
+	if err := b.mongo.QuarantinePushIntent(b.workCtx, intent, detail); err != nil {
+		b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+		return fmt.Errorf("failed to reject push intent %s: %w", intent.ID, err)
+	}
+
+	return fmt.Errorf("push intent %s rejected: %s", intent.ID, detail)
+}
+
+// pushToGitHub performs the actual push operation, returning the hash of the
+// last commit pushed, if any, and the number of documents it applied. Its
+// clone, apply, commit and push steps are traced as children of the span in
+// ctx.
+func (b *Bridge) pushToGitHub(ctx context.Context, intent *mongodb.PushIntent) (commitHash string, documents int, err error) {
+	if b.config.DryRun && b.config.DryRunOutput == "" {
+		b.logger.Info("DRY RUN: Would push to GitHub")
+		return "", 0, nil
+	}
+
+	// With DRY_RUN_OUTPUT a dry run stages the documents in memory and
+	// records the diff against the branch instead of committing
+	proposal := b.config.DryRun
+
+	// Held from before the clone until after the push, so replicas pushing
+	// the same branch take turns instead of racing on the ref
+	if !proposal {
+		release, err := b.lockRepo(b.targetRepo(intent), intent.Branch, intent.ID)
+		if err != nil {
+			return "", 0, err
+		}
+		defer release()
+	}
+
+	author := b.commitAuthor(intent)
+	perDocument := !proposal && b.config.CommitGranularity == config.CommitGranularityDocument
+
+	// Proposals stage in memory and are never pushed, so they always clone
+	cached := b.config.CacheClones && !proposal
+
+	// Documents are streamed and applied one at a time so only the current
+	// blob is held in memory. The repository is cloned on the first allowed
+	// document, so intents with nothing to push never clone.
+	var (
+		repo     *git.Repository
+		inMemory bool
+		lease    string // tip being amended, if any
+		found    int
+		applied  int
+		commits  []string
+		changes  []mongodb.AuditChange
+		dedup    *deduper
+	)
+	defer func() {
+		switch {
+		case repo == nil:
+		case cached && err == nil:
+			b.clones.put(b.cloneKey(intent), repo)
+		default:
+			repo.Cleanup()
+		}
+	}()
+
+	apply := func(doc *mongodb.Document) error {
+		found++
+		if !b.documentTypeAllowed(intent, doc) {
+			return nil
+		}
+
+		if repo == nil {
+			var err error
+			if cached {
+				repo, err = b.checkoutRepository(ctx, intent)
+			} else {
+				repo, err = b.cloneRepository(ctx, intent)
+			}
+			if err != nil {
+				return err
+			}
+			if proposal {
+				inMemory = true
+			} else {
+				if lease, err = b.amendLease(repo, intent.Branch, author); err != nil {
+					return err
+				}
+				inMemory = lease == "" && b.inMemoryApply(repo)
+			}
+		}
+
+		// Checked before deduplication so case variants cannot be collapsed,
+		// and skipped documents stay out of the audit
+		gitDoc := toGitDocument(doc)
+		skip, err := repo.SkipDocument(gitDoc)
+		if err != nil {
+			return err
+		}
+		if skip {
+			return nil
+		}
+
+		change := auditChange(repo, gitDoc)
+		if !dedup.accept(change.Path, doc, gitDoc) {
+			b.metrics.DocumentsSkipped.Inc()
+			return nil
+		}
+
+		// A document whose path changed also removes its previous path
+		previous, renamed, err := repo.RenamedFrom(gitDoc)
+		if err != nil {
+			return err
+		}
+
+		if perDocument {
+			message := intent.Message
+			if gitDoc.Message != "" {
+				message = gitDoc.Message
+			}
+			gitDoc.Message = b.commitMessage(message, []string{change.Path})
+
+			hashes, err := repo.CommitDocuments([]git.Document{gitDoc}, intent.Message, author, true)
+			if err != nil {
+				return fmt.Errorf("failed to commit: %w", err)
+			}
+			commits = append(commits, hashes...)
+		} else if inMemory {
+			if err := repo.StageDocuments([]git.Document{gitDoc}); err != nil {
+				return fmt.Errorf("failed to stage documents: %w", err)
+			}
+		} else if err := repo.ApplyDocuments([]git.Document{gitDoc}); err != nil {
+			return fmt.Errorf("failed to apply documents: %w", err)
+		}
+
+		applied++
+		if renamed {
+			changes = dedup.record(changes, mongodb.AuditChange{Path: previous, Operation: "delete"})
+		}
+		changes = dedup.record(changes, change)
+		return nil
+	}
+
+	// applyAll reads and applies every document of the intent
+	applyAll := func() (err error) {
+		found, applied, changes, dedup = 0, 0, nil, newDeduper()
+
+		applyCtx, applySpan := b.startSpan(ctx, "apply")
+		defer func() {
+			applySpan.SetAttributes(attribute.Int("documents.found", found), attribute.Int("documents.applied", applied))
+			endSpan(applySpan, err)
+		}()
+
+		streamCtx, streamSpan := b.startSpan(applyCtx, "mongo.stream_documents")
+		err = b.mongo.StreamDocumentsByIDs(streamCtx, intent.Documents, apply)
+		endSpan(streamSpan, err)
+		return err
+	}
+
+	// commitAll commits what applyAll wrote when committing per intent
+	commitAll := func() (err error) {
+		if perDocument {
+			return nil
+		}
+
+		_, commitSpan := b.startSpan(ctx, "commit", attribute.Bool("amend", lease != ""))
+		defer func() { endSpan(commitSpan, err) }()
+
+		paths := make([]string, len(changes))
+		for i, change := range changes {
+			paths[i] = change.Path
+		}
+
+		commit := repo.CommitChanges
+		switch {
+		case lease != "":
+			commit = repo.AmendCommit
+		case inMemory:
+			commit = repo.CommitStaged
+		}
+		hash, err := commit(b.commitMessage(intent.Message, paths), author)
+		if err != nil {
+			return fmt.Errorf("failed to commit: %w", err)
+		}
+		if hash != "" {
+			commits = append(commits, hash)
+		}
+		return nil
+	}
+
+	if err := applyAll(); err != nil {
+		return "", 0, err
+	}
+
+	if dedup.duplicates > 0 || dedup.superseded > 0 {
+		b.logger.WithFields(logrus.Fields{
+			"intent_id":  intent.ID,
+			"duplicates": dedup.duplicates,
+			"superseded": dedup.superseded,
+		}).Info("Collapsed documents writing the same path")
+	}
+
+	if found == 0 {
+		return "", 0, fmt.Errorf("no documents found for push intent")
+	}
+
+	if applied == 0 {
+		b.logger.WithField("intent_id", intent.ID).Info("No documents of an allowed type, nothing to push")
+		return "", 0, nil
+	}
+
+	if proposal {
+		return "", 0, b.recordDryRun(ctx, intent, repo)
+	}
+
+	b.metrics.DocumentsProcessed.Add(float64(applied))
+	b.metrics.BatchSize.Observe(float64(applied))
+
+	// Commit changes
+	if err := commitAll(); err != nil {
+		return "", 0, err
+	}
+
+	// A clean worktree may come from a stale read; read the documents once
+	// more before treating the intent as a no-op
+	if len(commits) == 0 && b.config.RecheckOnClean {
+		b.logger.WithField("intent_id", intent.ID).Info("No changes, re-reading documents before finalizing")
+		if err := applyAll(); err != nil {
+			return "", 0, err
+		}
+		if err := commitAll(); err != nil {
+			return "", 0, err
+		}
+		if len(commits) > 0 {
+			b.metrics.StaleReads.Inc()
+		}
+	}
+
+	if len(commits) == 0 {
+		b.logger.Info("No changes to commit")
+		b.metrics.DocumentsSkipped.Add(float64(applied))
+		return "", 0, nil
+	}
+
+	commitHash = commits[len(commits)-1]
+	b.logger.WithFields(logrus.Fields{
+		"commit":  commitHash,
+		"commits": len(commits),
+	}).Info("Created commit")
+
+	// Push to GitHub
+	pushTimer := time.Now()
+	pushCtx, pushSpan := b.startSpan(ctx, "push", attribute.String("commit", commitHash), attribute.Int("commits", len(commits)))
+	if lease != "" {
+		err = repo.ForcePushWithLease(pushCtx, lease)
+	} else {
+		err = repo.Push(pushCtx)
+	}
+	endSpan(pushSpan, err)
 	if err != nil {
-		return fmt.Errorf("failed to get documents: %w", err)
+		return "", 0, fmt.Errorf("failed to push: %w", err)
 	}
 
-	if len(documents) == 0 {
-		return fmt.Errorf("no documents found for push intent")
+	b.metrics.GitPushDuration.Observe(time.Since(pushTimer).Seconds())
+	b.lastPush.Store(time.Now().UnixNano())
+	b.recordBranchPush(intent.Branch)
+
+	b.logger.WithFields(logrus.Fields{
+		"commit":    commitHash,
+		"documents": applied,
+	}).Info("Successfully pushed to GitHub")
+	b.cycles.addDocuments(intent.ID, applied)
+
+	b.recordAudit(intent, commitHash, changes)
+
+	// The commit is already pushed, so report it even if tagging fails
+	if err := b.tagRelease(intent, repo, commitHash); err != nil {
+		return commitHash, applied, err
+	}
+
+	return commitHash, applied, nil
+}
+
+// runPostPushHook runs POST_PUSH_HOOK for a pushed intent. A failing hook is
+// counted but never fails the push; the hook logs its own outcome.
+func (b *Bridge) runPostPushHook(ctx context.Context, intent *mongodb.PushIntent, commitHash string, documents int) {
+	if b.postPushHook == nil {
+		return
+	}
+
+	ctx, span := b.startSpan(ctx, "post_push_hook")
+	err := b.postPushHook.Run(ctx, hook.Event{
+		IntentID:  intent.ID,
+		Repo:      b.targetRepo(intent),
+		Branch:    intent.Branch,
+		Commit:    commitHash,
+		Author:    intent.Author,
+		Documents: documents,
+		Metadata:  intent.Metadata,
+	})
+	endSpan(span, err)
+	if err != nil {
+		b.metrics.PostPushHookFailures.Inc()
+	}
+}
+
+// targetRepo returns the full name of the repository an intent pushes to. With
+// repository discovery that is the intent's repo in GitHubOrganization, which
+// processPushIntent has already checked against the allowlist; otherwise it is
+// the configured GITHUB_REPO.
+func (b *Bridge) targetRepo(intent *mongodb.PushIntent) string {
+	if b.repoAllowlist != nil && intent.Repo != "" {
+		return b.config.GitHubOrganization + "/" + intent.Repo
 	}
+	return b.config.GetRepoFullName()
+}
 
-	metrics.DocumentsProcessed.Add(float64(len(documents)))
-	metrics.BatchSize.Observe(float64(len(documents)))
+// cloneRepository clones the target repository for an intent and pulls the
+// latest changes
+func (b *Bridge) cloneRepository(ctx context.Context, intent *mongodb.PushIntent) (repo *git.Repository, err error) {
+	ctx, span := b.startSpan(ctx, "clone", attribute.String("repo", b.targetRepo(intent)), attribute.String("branch", intent.Branch))
+	defer func() { endSpan(span, err) }()
 
 	// Create temporary directory for git operations
 	tempDir := filepath.Join(os.TempDir(), "github-bridge")
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
-		return fmt.Errorf("failed to create temp dir: %w", err)
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
 	}
 
 	// Clone repository
 	cloneTimer := time.Now()
-	repo, err := git.Clone(b.ctx, git.CloneOptions{
-		URL:        fmt.Sprintf("https://github.com/%s.git", b.config.GetRepoFullName()),
+	repo, err = b.gitBackend.Clone(ctx, git.CloneOptions{
+		URL:        fmt.Sprintf("https://github.com/%s.git", b.targetRepo(intent)),
 		Branch:     intent.Branch,
 		Token:      b.config.GitHubToken,
 		TempDir:    tempDir,
 		RemoteName: "origin",
+		NetRetries: b.config.GitNetRetries,
+		PathPrefix: b.config.PathPrefixFor(intent.Repo),
+
+		KeepEmptyDirs:    b.config.KeepEmptyDirs,
+		StrictOperations: b.config.StrictOperations,
+		LowercasePaths:   b.config.PathCase == config.PathCaseLower,
+		IgnorePaths:      b.config.IgnorePaths,
+		Transformer:      b.transformer,
+		Location:         b.commitLocation,
+		MinFreeDiskBytes: uint64(b.config.MinFreeDiskBytes),
+		FileManifest:     b.config.IncludeFileManifest,
+		ManifestLimit:    b.config.ManifestMaxFiles,
+		SignKey:          b.signKey,
+		VerifySigner:     b.config.VerifySignerPerCommit,
+		Metrics:          b.metrics,
+
+		RejectSubmodulePaths: b.config.SubmoduleMode == config.SubmoduleModeError,
+		SyncWrites:           b.config.SyncWrites,
+		RejectBinary:         !b.config.AllowBinary,
 	}, b.logger)
 	if err != nil {
-		return fmt.Errorf("failed to clone repository: %w", err)
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
 	}
-	defer repo.Cleanup()
-	
-	metrics.GitCloneDuration.Observe(time.Since(cloneTimer).Seconds())
+
+	b.metrics.GitCloneDuration.Observe(time.Since(cloneTimer).Seconds())
 
 	// Pull latest changes
-	if err := repo.Pull(b.ctx); err != nil {
-		b.logger.WithError(err).Warn("Failed to pull latest changes")
+	pullCtx, pullSpan := b.startSpan(ctx, "pull")
+	pullErr := repo.Pull(pullCtx)
+	endSpan(pullSpan, pullErr)
+	if pullErr != nil {
+		b.logger.WithError(pullErr).Warn("Failed to pull latest changes")
 	}
 
-	// Apply documents to repository
-	gitDocs := make([]git.Document, 0, len(documents))
-	for _, doc := range documents {
-		operation := "update"
-		if meta, ok := doc.Metadata["operation"].(string); ok {
-			operation = meta
+	return repo, nil
+}
+
+// cloneKey returns the key an intent's clone is cached under
+func (b *Bridge) cloneKey(intent *mongodb.PushIntent) cloneKey {
+	return cloneKey{
+		repo:   b.targetRepo(intent),
+		branch: intent.Branch,
+		prefix: b.config.PathPrefixFor(intent.Repo),
+	}
+}
+
+// checkoutRepository takes the cached clone for an intent and brings it up
+// to date with the remote, cloning afresh when none is cached or it cannot
+// be refreshed
+func (b *Bridge) checkoutRepository(ctx context.Context, intent *mongodb.PushIntent) (*git.Repository, error) {
+	repo := b.clones.take(b.cloneKey(intent))
+	if repo == nil {
+		return b.cloneRepository(ctx, intent)
+	}
+
+	refreshCtx, span := b.startSpan(ctx, "refresh", attribute.String("repo", b.targetRepo(intent)), attribute.String("branch", intent.Branch))
+	err := repo.Refresh(refreshCtx)
+	endSpan(span, err)
+	if err != nil {
+		b.logger.WithError(err).WithField("intent_id", intent.ID).Warn("Failed to refresh cached clone, cloning again")
+		repo.Cleanup()
+		return b.cloneRepository(ctx, intent)
+	}
+	return repo, nil
+}
+
+// amendLease returns the tip an intent amends instead of committing on top
+// of, or "" to commit as usual. Only branches matching AMEND_BRANCHES are
+// amended, only when committing per intent, and only while the tip is the
+// bot's own commit; a human commit at the tip is never rewritten.
+func (b *Bridge) amendLease(repo *git.Repository, branch string, author git.CommitAuthor) (string, error) {
+	if b.config.CommitGranularity == config.CommitGranularityDocument || !b.amendBranch(branch) {
+		return "", nil
+	}
+
+	ok, err := repo.CanAmend(author)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		b.logger.WithField("branch", branch).Info("Branch tip is not the bot's commit, adding a new commit")
+		return "", nil
+	}
+	return repo.HeadHash()
+}
+
+// branchAllowed reports whether intents may target branch. Every branch is
+// allowed when ALLOWED_BRANCHES is empty.
+func (b *Bridge) branchAllowed(branch string) bool {
+	if len(b.config.AllowedBranches) == 0 {
+		return true
+	}
+	for _, pattern := range b.config.AllowedBranches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
 		}
+	}
+	return false
+}
 
-		gitDocs = append(gitDocs, git.Document{
-			Path:      doc.Path,
-			Content:   doc.Blob,
-			Operation: operation,
-		})
+// amendBranch reports whether branch matches AMEND_BRANCHES
+func (b *Bridge) amendBranch(branch string) bool {
+	for _, pattern := range b.config.AmendBranches {
+		if ok, _ := path.Match(pattern, branch); ok {
+			return true
+		}
 	}
+	return false
+}
+
+// inMemoryApply reports whether an intent's documents are committed as a tree
+// built in memory. APPLY_MODE=tree falls back to the worktree when committing
+// per document or when the clone cannot be staged in memory.
+func (b *Bridge) inMemoryApply(repo *git.Repository) bool {
+	if b.config.ApplyMode != config.ApplyModeTree {
+		return false
+	}
+	if b.config.CommitGranularity == config.CommitGranularityDocument || !repo.SupportsInMemoryApply() {
+		b.logger.Debug("In-memory apply unsupported, applying documents through the worktree")
+		return false
+	}
+	return true
+}
 
-	if err := repo.ApplyDocuments(gitDocs); err != nil {
-		return fmt.Errorf("failed to apply documents: %w", err)
+// toGitDocument converts a stored document into a change for the repository
+func toGitDocument(doc *mongodb.Document) git.Document {
+	operation := "update"
+	if meta, ok := doc.Metadata["operation"].(string); ok {
+		operation = meta
 	}
 
-	// Check if there are changes
-	status, err := repo.GetStatus()
+	message, _ := doc.Metadata["message"].(string)
+	previousPath, _ := doc.Metadata["previous_path"].(string)
+
+	return git.Document{
+		Path:         doc.Path,
+		Content:      doc.Blob,
+		Operation:    operation,
+		Message:      message,
+		Tombstone:    isTombstone(doc),
+		PreviousPath: previousPath,
+	}
+}
+
+// tombstoneType is the document type of a soft delete
+const tombstoneType = "tombstone"
+
+// isTombstone reports whether doc is a soft delete: the producer marks
+// deletions with metadata.deleted or the tombstone type instead of a delete
+// operation
+func isTombstone(doc *mongodb.Document) bool {
+	deleted, _ := doc.Metadata["deleted"].(bool)
+	return deleted || doc.Type == tombstoneType
+}
+
+// committer returns the identity commits and tags on branch are made with,
+// taken from IDENTITY_MAP when the branch matches an entry
+func (b *Bridge) committer(branch string) git.CommitAuthor {
+	name, email := b.config.IdentityFor(branch)
+	return git.CommitAuthor{Name: name, Email: email}
+}
+
+// commitAuthor returns the signatures for an intent's commits: committed by
+// the branch's committer and authored by the intent's author when it is an
+// email address, such as "Alice <alice@tekfly.io>". Other authors are only
+// recorded in the audit, and the committer is the author too.
+func (b *Bridge) commitAuthor(intent *mongodb.PushIntent) git.CommitAuthor {
+	committer := b.committer(intent.Branch)
+	addr, err := mail.ParseAddress(intent.Author)
 	if err != nil {
-		return fmt.Errorf("failed to get status: %w", err)
+		return committer
 	}
 
-	if status.IsClean() {
-		b.logger.Info("No changes to commit")
-		metrics.DocumentsSkipped.Add(float64(len(documents)))
+	name := addr.Name
+	if name == "" {
+		name = addr.Address
+	}
+	return git.CommitAuthor{
+		Name:           name,
+		Email:          addr.Address,
+		CommitterName:  committer.Name,
+		CommitterEmail: committer.Email,
+	}
+}
+
+// tagRelease creates and pushes the annotated tag requested by an intent's
+// metadata.tag, if any
+func (b *Bridge) tagRelease(intent *mongodb.PushIntent, repo *git.Repository, commitHash string) error {
+	tag, _ := intent.Metadata["tag"].(string)
+	if tag == "" {
 		return nil
 	}
 
-	// Commit changes
-	commitHash, err := repo.Commit(intent.Message, git.CommitAuthor{
-		Name:  b.config.GitUserName,
-		Email: b.config.GitUserEmail,
-	})
+	message, _ := intent.Metadata["tag_message"].(string)
+	if message == "" {
+		message = intent.Message
+	}
+
+	logger := b.logger.WithFields(logrus.Fields{"tag": tag, "commit": commitHash})
+
+	exists, err := repo.TagExists(b.workCtx, tag)
+	if err == nil && exists {
+		err = fmt.Errorf("%w: %s", git.ErrTagExists, tag)
+	}
+	if err == nil {
+		err = repo.CreateTag(tag, commitHash, message, b.committer(intent.Branch))
+	}
+	if errors.Is(err, git.ErrTagExists) && b.config.TagExistsPolicy == config.TagExistsSkip {
+		logger.Warn("Tag already exists, skipping")
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+		return fmt.Errorf("failed to tag release: %w", err)
 	}
 
-	b.logger.WithField("commit", commitHash).Info("Created commit")
+	if err := repo.PushTag(b.workCtx, tag); err != nil {
+		return fmt.Errorf("failed to push tag: %w", err)
+	}
 
-	// Push to GitHub
-	pushTimer := time.Now()
-	if err := repo.Push(b.ctx); err != nil {
-		return fmt.Errorf("failed to push: %w", err)
+	logger.Info("Pushed release tag")
+	return nil
+}
+
+// documentTypeAllowed reports whether a document's type is in the
+// configured allowlist, logging and counting documents that are filtered out.
+// Every type is allowed when no allowlist is configured. Tombstones always
+// pass, since dropping them would leave deleted files behind.
+func (b *Bridge) documentTypeAllowed(intent *mongodb.PushIntent, doc *mongodb.Document) bool {
+	if len(b.config.DocumentTypes) == 0 || doc.Type == tombstoneType {
+		return true
+	}
+
+	for _, t := range b.config.DocumentTypes {
+		if doc.Type == t {
+			return true
+		}
 	}
-	
-	metrics.GitPushDuration.Observe(time.Since(pushTimer).Seconds())
 
 	b.logger.WithFields(logrus.Fields{
-		"commit":    commitHash,
-		"documents": len(documents),
-	}).Info("Successfully pushed to GitHub")
+		"intent_id":   intent.ID,
+		"document_id": doc.ID,
+		"path":        doc.Path,
+		"type":        doc.Type,
+	}).Info("Filtered document of disallowed type")
+	b.metrics.DocumentsSkipped.Inc()
 
-	return nil
-}
\ No newline at end of file
+	return false
+}
+
+// auditChange resolves the repository path written for a document
+func auditChange(repo *git.Repository, doc git.Document) mongodb.AuditChange {
+	path, err := repo.ResolvePath(doc.Path)
+	if err != nil {
+		path = doc.Path
+	}
+	return mongodb.AuditChange{Path: path, Operation: doc.EffectiveOperation()}
+}
+
+// recordAudit writes the audit record for a successful push. Failures are
+// logged and counted but never fail the push.
+func (b *Bridge) recordAudit(intent *mongodb.PushIntent, commitHash string, changes []mongodb.AuditChange) {
+	committer := b.committer(intent.Branch)
+	record := &mongodb.AuditRecord{
+		IntentID:   intent.ID,
+		Repo:       intent.Repo,
+		Branch:     intent.Branch,
+		CommitHash: commitHash,
+		Changes:    changes,
+		Author:     intent.Author,
+		Committer:  fmt.Sprintf("%s <%s>", committer.Name, committer.Email),
+		Timestamp:  time.Now(),
+	}
+
+	if err := b.mongo.InsertAuditRecord(b.workCtx, record); err != nil {
+		b.logger.WithError(err).WithField("intent_id", intent.ID).Error("Failed to write audit record")
+		b.metrics.ErrorsByType.WithLabelValues("audit").Inc()
+	}
+}
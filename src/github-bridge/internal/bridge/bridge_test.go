@@ -0,0 +1,1190 @@
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/api"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeStore is an in-memory store used by bridge tests
+type fakeStore struct {
+	mu        sync.Mutex
+	intents   []*mongodb.PushIntent
+	documents map[string]*mongodb.Document
+	processed map[string]error
+	audit     []*mongodb.AuditRecord
+	auditErr  error
+	closed    int
+	// closeCtxErr is the error of the context the store was closed with
+	closeCtxErr error
+
+	deadLetters  map[string]string
+	fetches      int
+	pendingMarks map[string]*mongodb.PendingMark
+	summaries    []*mongodb.BatchSummary
+	// stale documents are served in place of documents on their first read
+	stale map[string]*mongodb.Document
+	// markErr makes MarkPushIntentProcessed fail
+	markErr error
+	// panicOn makes fetching the given document IDs panic
+	panicOn map[string]bool
+	// repoLocks maps repo@branch to the held lock
+	repoLocks map[string]mongodb.RepoLock
+	// dryRuns maps intent IDs to their saved dry run result
+	dryRuns map[string]*mongodb.DryRunResult
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		documents: make(map[string]*mongodb.Document),
+		processed: make(map[string]error),
+
+		deadLetters:  make(map[string]string),
+		panicOn:      make(map[string]bool),
+		pendingMarks: make(map[string]*mongodb.PendingMark),
+		repoLocks:    make(map[string]mongodb.RepoLock),
+		dryRuns:      make(map[string]*mongodb.DryRunResult),
+	}
+}
+
+func (s *fakeStore) GetPendingPushIntents(ctx context.Context, limit int) ([]*mongodb.PushIntent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []*mongodb.PushIntent
+	for _, intent := range s.intents {
+		if _, done := s.processed[intent.ID]; done || intent.Processed {
+			continue
+		}
+		pending = append(pending, intent)
+	}
+
+	// Match the store's priority desc, timestamp asc order
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority > pending[j].Priority
+		}
+		return pending[i].Timestamp.Before(pending[j].Timestamp)
+	})
+	if len(pending) > limit {
+		pending = pending[:limit]
+	}
+	return pending, nil
+}
+
+func (s *fakeStore) OldestPendingIntentAge(ctx context.Context) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var oldest time.Time
+	for _, intent := range s.intents {
+		if _, done := s.processed[intent.ID]; done || intent.Processed {
+			continue
+		}
+		if oldest.IsZero() || intent.Timestamp.Before(oldest) {
+			oldest = intent.Timestamp
+		}
+	}
+
+	if oldest.IsZero() {
+		return 0, nil
+	}
+	return time.Since(oldest), nil
+}
+
+func (s *fakeStore) CountPendingPushIntents(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for _, intent := range s.intents {
+		if _, done := s.processed[intent.ID]; !done && !intent.Processed {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *fakeStore) StreamDocumentsByIDs(ctx context.Context, ids []string, fn func(*mongodb.Document) error) error {
+	s.mu.Lock()
+	s.fetches++
+	s.mu.Unlock()
+
+	for _, id := range ids {
+		if s.panicOn[id] {
+			panic("malformed document " + id)
+		}
+
+		s.mu.Lock()
+		doc, ok := s.documents[id]
+		if staleDoc, isStale := s.stale[id]; isStale {
+			doc, ok = staleDoc, true
+			delete(s.stale, id)
+		}
+		s.mu.Unlock()
+
+		if !ok {
+			continue
+		}
+		projected, err := projectDocument(doc)
+		if err != nil {
+			return err
+		}
+		if err := fn(projected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectDocument round-trips doc through BSON keeping only the fields of
+// mongodb.StreamProjection, as the real store streams it
+func projectDocument(doc *mongodb.Document) (*mongodb.Document, error) {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for key := range fields {
+		if _, ok := mongodb.StreamProjection[key]; !ok {
+			delete(fields, key)
+		}
+	}
+
+	if raw, err = bson.Marshal(fields); err != nil {
+		return nil, err
+	}
+	projected := &mongodb.Document{}
+	return projected, bson.Unmarshal(raw, projected)
+}
+
+func (s *fakeStore) MarkPushIntentProcessed(ctx context.Context, id string, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.markErr != nil {
+		return s.markErr
+	}
+	s.processed[id] = err
+	return nil
+}
+
+func (s *fakeStore) InsertBatchSummary(ctx context.Context, summary *mongodb.BatchSummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summaries = append(s.summaries, summary)
+	return nil
+}
+
+func (s *fakeStore) RecordPendingMark(ctx context.Context, mark *mongodb.PendingMark) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pendingMarks[mark.IntentID] = mark
+	return nil
+}
+
+func (s *fakeStore) GetPendingMark(ctx context.Context, intentID string) (*mongodb.PendingMark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pendingMarks[intentID], nil
+}
+
+func (s *fakeStore) GetPendingMarks(ctx context.Context, limit int) ([]*mongodb.PendingMark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var marks []*mongodb.PendingMark
+	for _, mark := range s.pendingMarks {
+		if len(marks) == limit {
+			break
+		}
+		marks = append(marks, mark)
+	}
+	return marks, nil
+}
+
+func (s *fakeStore) DeletePendingMark(ctx context.Context, intentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pendingMarks, intentID)
+	return nil
+}
+
+func (s *fakeStore) QuarantinePushIntent(ctx context.Context, intent *mongodb.PushIntent, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadLetters[intent.ID] = reason
+	s.processed[intent.ID] = errors.New(reason)
+	return nil
+}
+
+func (s *fakeStore) SaveDryRunResult(ctx context.Context, result *mongodb.DryRunResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dryRuns[result.IntentID] = result
+	return nil
+}
+
+func (s *fakeStore) InsertAuditRecord(ctx context.Context, record *mongodb.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.auditErr != nil {
+		return s.auditErr
+	}
+	s.audit = append(s.audit, record)
+	return nil
+}
+
+func (s *fakeStore) CreatePushIntent(ctx context.Context, intent *mongodb.PushIntent, documents []*mongodb.Document) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, doc := range documents {
+		doc.ID = fmt.Sprintf("doc-%d", len(s.documents)+1)
+		s.documents[doc.ID] = doc
+		intent.Documents = append(intent.Documents, doc.ID)
+	}
+	if intent.ID == "" {
+		intent.ID = fmt.Sprintf("intent-%d", len(s.intents)+1)
+	}
+	s.intents = append(s.intents, intent)
+	return intent.ID, nil
+}
+
+func (s *fakeStore) UnprocessedPushIntentIDs(ctx context.Context, ids []string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := make(map[string]bool)
+	for _, intent := range s.intents {
+		if _, done := s.processed[intent.ID]; done || intent.Processed {
+			continue
+		}
+		for _, id := range ids {
+			if id == intent.ID {
+				pending[id] = true
+			}
+		}
+	}
+	return pending, nil
+}
+
+func (s *fakeStore) AcquireRepoLock(ctx context.Context, repo, branch, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := repo + "@" + branch
+	if lock, ok := s.repoLocks[key]; ok && lock.Owner != owner && time.Now().Before(lock.ExpiresAt) {
+		return false, nil
+	}
+	s.repoLocks[key] = mongodb.RepoLock{
+		ID:        mongodb.RepoLockKey{Repo: repo, Branch: branch},
+		Owner:     owner,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	return true, nil
+}
+
+func (s *fakeStore) ReleaseRepoLock(ctx context.Context, repo, branch, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := repo + "@" + branch
+	if lock, ok := s.repoLocks[key]; ok && lock.Owner == owner {
+		delete(s.repoLocks, key)
+	}
+	return nil
+}
+
+func (s *fakeStore) WatchPushIntents(ctx context.Context, since time.Time) (*mongo.ChangeStream, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *fakeStore) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed++
+	s.closeCtxErr = ctx.Err()
+	return nil
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+func newTestMetrics() *metrics.Metrics {
+	return metrics.Init(prometheus.NewRegistry())
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		GitUserName:  "Virtual DOM Bot",
+		GitUserEmail: "bot@tekfly.io",
+		PollInterval: 1,
+		BatchSize:    10,
+		WorkerCount:  2,
+		DryRun:       true,
+		AllowBinary:  true,
+
+		BacklogCheckInterval: 30,
+
+		PendingMarkRetryInterval: 30,
+	}
+}
+
+func TestShutdownConcurrentCallsDoNotPanic(t *testing.T) {
+	st := newFakeStore()
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+
+	go b.Start()
+	// Give Start a moment to register its producers and workers
+	time.Sleep(50 * time.Millisecond)
+
+	// Keep a producer busy sending while shutdown races it
+	b.producers.Add(1)
+	go func() {
+		defer b.producers.Done()
+		for i := 0; i < 1000; i++ {
+			if !b.enqueue(&mongodb.PushIntent{ID: "intent"}) {
+				return
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := b.Shutdown(ctx); err != nil {
+				t.Errorf("Shutdown returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if st.closed != 1 {
+		t.Errorf("expected store to be closed once, got %d", st.closed)
+	}
+
+	if b.enqueue(&mongodb.PushIntent{ID: "late"}) {
+		t.Error("expected enqueue after shutdown to be rejected")
+	}
+}
+
+// blockingBackend stands in for a clone that hangs until its context is
+// cancelled
+type blockingBackend struct {
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func (f *blockingBackend) Clone(ctx context.Context, opts git.CloneOptions, logger *logrus.Logger) (*git.Repository, error) {
+	close(f.started)
+	<-ctx.Done()
+	close(f.cancelled)
+	return nil, ctx.Err()
+}
+
+func TestShutdownCancelsSlowWorkerAtDeadline(t *testing.T) {
+	b, st, _, intent := newPushTest(t, newTestConfig())
+	backend := &blockingBackend{started: make(chan struct{}), cancelled: make(chan struct{})}
+	b.gitBackend = backend
+	logger, logs := test.NewNullLogger()
+	b.logger = logger
+
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		b.handleIntent(1, intent)
+	}()
+	<-backend.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := b.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Shutdown to report the deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected Shutdown to return within its timeout, took %v", elapsed)
+	}
+	if st.closeCtxErr != nil {
+		t.Errorf("expected the store to be closed with a live context, got %v", st.closeCtxErr)
+	}
+
+	select {
+	case <-backend.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the in-flight clone to be cancelled")
+	}
+
+	var busy *logrus.Entry
+	for _, entry := range logs.AllEntries() {
+		if entry.Message == "Worker still busy at shutdown deadline" {
+			busy = entry
+		}
+	}
+	if busy == nil {
+		t.Fatal("expected the busy worker to be logged")
+	}
+	if busy.Data["worker_id"] != 1 || busy.Data["intent_id"] != intent.ID {
+		t.Errorf("unexpected busy worker fields: %v", busy.Data)
+	}
+}
+
+func TestUpdateBacklogAgeReportsOldestPendingIntent(t *testing.T) {
+	st := newFakeStore()
+	now := time.Now()
+	st.intents = []*mongodb.PushIntent{
+		{ID: "processed", Timestamp: now.Add(-2 * time.Hour), Processed: true},
+		{ID: "oldest", Timestamp: now.Add(-10 * time.Minute)},
+		{ID: "newest", Timestamp: now.Add(-time.Minute)},
+	}
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+
+	if err := b.updateBacklogAge(); err != nil {
+		t.Fatalf("updateBacklogAge failed: %v", err)
+	}
+
+	age := testutil.ToFloat64(b.metrics.OldestPendingIntentAge)
+	if age < 600 || age > 660 {
+		t.Errorf("expected oldest pending age of about 600s, got %v", age)
+	}
+
+	st.processed["oldest"] = nil
+	st.processed["newest"] = nil
+	if err := b.updateBacklogAge(); err != nil {
+		t.Fatalf("updateBacklogAge failed: %v", err)
+	}
+
+	if age := testutil.ToFloat64(b.metrics.OldestPendingIntentAge); age != 0 {
+		t.Errorf("expected zero age with nothing pending, got %v", age)
+	}
+}
+
+func TestRecordAuditWritesChangeList(t *testing.T) {
+	st := newFakeStore()
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+
+	intent := &mongodb.PushIntent{ID: "intent-1", Repo: "org/repo", Branch: "main", Author: "alice"}
+	changes := []mongodb.AuditChange{
+		{Path: "docs/a.md", Operation: "create"},
+		{Path: "docs/b.md", Operation: "delete"},
+	}
+
+	b.recordAudit(intent, "abc123", changes)
+
+	if len(st.audit) != 1 {
+		t.Fatalf("expected 1 audit record, got %d", len(st.audit))
+	}
+
+	record := st.audit[0]
+	if record.IntentID != "intent-1" || record.Repo != "org/repo" || record.Branch != "main" {
+		t.Errorf("unexpected audit target: %+v", record)
+	}
+	if record.CommitHash != "abc123" {
+		t.Errorf("expected commit abc123, got %s", record.CommitHash)
+	}
+	if record.Author != "alice" || record.Committer != "Virtual DOM Bot <bot@tekfly.io>" {
+		t.Errorf("unexpected identities: author=%q committer=%q", record.Author, record.Committer)
+	}
+	if len(record.Changes) != 2 || record.Changes[0] != changes[0] || record.Changes[1] != changes[1] {
+		t.Errorf("unexpected change list: %+v", record.Changes)
+	}
+}
+
+func TestRecordAuditFailureIsCounted(t *testing.T) {
+	st := newFakeStore()
+	st.auditErr = errors.New("mongo unavailable")
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+
+	before := testutil.ToFloat64(b.metrics.ErrorsByType.WithLabelValues("audit"))
+	b.recordAudit(&mongodb.PushIntent{ID: "intent-1"}, "abc123", nil)
+
+	if got := testutil.ToFloat64(b.metrics.ErrorsByType.WithLabelValues("audit")); got != before+1 {
+		t.Errorf("expected audit error counter to increase by 1, got %v -> %v", before, got)
+	}
+}
+
+func TestDocumentTypeAllowed(t *testing.T) {
+	documents := []*mongodb.Document{
+		{ID: "1", Path: "app.yaml", Type: "config"},
+		{ID: "2", Path: "index.html", Type: "content"},
+		{ID: "3", Path: "db.yaml", Type: "config"},
+		{ID: "4", Path: "untyped.txt"},
+		{ID: "5", Path: "removed.html", Type: "tombstone"},
+	}
+
+	tests := []struct {
+		name    string
+		allowed []string
+		want    []string
+	}{
+		{"no allowlist", nil, []string{"1", "2", "3", "4", "5"}},
+		{"config only", []string{"config"}, []string{"1", "3", "5"}},
+		{"content only", []string{"content"}, []string{"2", "5"}},
+		{"multiple types", []string{"config", "content"}, []string{"1", "2", "3", "5"}},
+		{"no matches", []string{"asset"}, []string{"5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.DocumentTypes = tt.allowed
+			b := newBridge(context.Background(), cfg, newFakeStore(), newTestMetrics(), newTestLogger())
+
+			var got []string
+			for _, doc := range documents {
+				if b.documentTypeAllowed(&mongodb.PushIntent{ID: "intent"}, doc) {
+					got = append(got, doc.ID)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestToGitDocumentTombstones(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *mongodb.Document
+		want bool
+	}{
+		{"deleted metadata", &mongodb.Document{Metadata: map[string]interface{}{"deleted": true}}, true},
+		{"tombstone type", &mongodb.Document{Type: "tombstone"}, true},
+		{"deleted false", &mongodb.Document{Metadata: map[string]interface{}{"deleted": false}}, false},
+		{"deleted not a bool", &mongodb.Document{Metadata: map[string]interface{}{"deleted": "yes"}}, false},
+		{"plain document", &mongodb.Document{Type: "content"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := toGitDocument(tt.doc)
+			if doc.Tombstone != tt.want {
+				t.Errorf("expected tombstone %v, got %v", tt.want, doc.Tombstone)
+			}
+			if tt.want && doc.EffectiveOperation() != "delete" {
+				t.Errorf("expected a delete, got %q", doc.EffectiveOperation())
+			}
+		})
+	}
+}
+
+func TestIntentWithOnlyFilteredDocumentsIsNoOp(t *testing.T) {
+	st := newFakeStore()
+	st.documents["1"] = &mongodb.Document{ID: "1", Path: "index.html", Type: "content"}
+
+	cfg := newTestConfig()
+	cfg.DryRun = false
+	cfg.DocumentTypes = []string{"config"}
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	// Returns before any clone is attempted
+	if _, _, err := b.pushToGitHub(context.Background(), &mongodb.PushIntent{ID: "intent", Documents: []string{"1"}}); err != nil {
+		t.Fatalf("expected no-op, got %v", err)
+	}
+}
+
+func TestWorkerRecoversFromPanicAndQuarantinesIntent(t *testing.T) {
+	st := newFakeStore()
+	st.panicOn["bad-doc"] = true
+
+	cfg := newTestConfig()
+	cfg.DryRun = false
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	b.workQueue <- &mongodb.PushIntent{ID: "poison", Documents: []string{"bad-doc"}}
+	b.workQueue <- &mongodb.PushIntent{ID: "healthy", Documents: []string{"missing-doc"}}
+	close(b.workQueue)
+
+	before := testutil.ToFloat64(b.metrics.WorkerPanics)
+
+	b.wg.Add(2)
+	go b.dispatch()
+	done := make(chan struct{})
+	go func() {
+		b.worker(0)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not finish")
+	}
+
+	if reason, ok := st.deadLetters["poison"]; !ok || !strings.Contains(reason, "malformed document bad-doc") {
+		t.Errorf("expected poison intent to be quarantined with the panic reason, got %q", reason)
+	}
+	if _, ok := st.deadLetters["healthy"]; ok {
+		t.Error("healthy intent should not be quarantined")
+	}
+	if _, ok := st.processed["healthy"]; !ok {
+		t.Error("worker should keep processing intents after a panic")
+	}
+	if got := testutil.ToFloat64(b.metrics.WorkerPanics); got != before+1 {
+		t.Errorf("expected worker panics to increase by 1, got %v -> %v", before, got)
+	}
+}
+
+func TestOversizedIntentIsRejectedBeforeFetch(t *testing.T) {
+	st := newFakeStore()
+
+	cfg := newTestConfig()
+	cfg.DryRun = false
+	cfg.MaxDocsPerIntent = 2
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	before := testutil.ToFloat64(b.metrics.IntentsRejected.WithLabelValues("too_many_docs"))
+
+	intent := &mongodb.PushIntent{ID: "huge", Documents: []string{"1", "2", "3"}}
+	if err := b.processPushIntent(intent); err == nil {
+		t.Fatal("expected oversized intent to be rejected")
+	}
+
+	if st.fetches != 0 {
+		t.Errorf("expected no document fetch, got %d", st.fetches)
+	}
+	if reason := st.deadLetters["huge"]; !strings.Contains(reason, "3 documents") || !strings.Contains(reason, "limit of 2") {
+		t.Errorf("expected dead letter with a clear reason, got %q", reason)
+	}
+	if got := testutil.ToFloat64(b.metrics.IntentsRejected.WithLabelValues("too_many_docs")); got != before+1 {
+		t.Errorf("expected rejection counter to increase by 1, got %v -> %v", before, got)
+	}
+}
+
+func TestIntentForDisallowedBranchIsRejected(t *testing.T) {
+	st := newFakeStore()
+	cfg := newTestConfig()
+	cfg.AllowedBranches = []string{"main", "team-*/*"}
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	for _, branch := range []string{"gh-pages", "release/1.0", "team-a"} {
+		if err := b.processPushIntent(&mongodb.PushIntent{ID: branch, Branch: branch}); err == nil {
+			t.Errorf("expected an intent for %s to be rejected", branch)
+		}
+		if reason := st.deadLetters[branch]; !strings.Contains(reason, fmt.Sprintf("%q", branch)) {
+			t.Errorf("expected dead letter naming the branch, got %q", reason)
+		}
+	}
+	if got := testutil.ToFloat64(b.metrics.IntentsRejected.WithLabelValues("branch_not_allowed")); got != 3 {
+		t.Errorf("expected 3 branch_not_allowed rejections, got %v", got)
+	}
+
+	for _, branch := range []string{"main", "team-a/feature"} {
+		if err := b.processPushIntent(&mongodb.PushIntent{ID: branch, Branch: branch}); err != nil {
+			t.Errorf("expected an intent for %s to be processed, got %v", branch, err)
+		}
+		if _, ok := st.deadLetters[branch]; ok {
+			t.Errorf("intent for allowed branch %s should not be rejected", branch)
+		}
+	}
+}
+
+// staticAllowlist is a repoAllowlist with a fixed set of repos
+type staticAllowlist map[string]bool
+
+func (a staticAllowlist) Ready() bool              { return a != nil }
+func (a staticAllowlist) Allowed(repo string) bool { return a[repo] }
+
+func TestIntentForUndiscoveredRepoIsRejected(t *testing.T) {
+	st := newFakeStore()
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+	b.repoAllowlist = staticAllowlist{"svc-auth": true}
+
+	if err := b.processPushIntent(&mongodb.PushIntent{ID: "other", Repo: "website"}); err == nil {
+		t.Fatal("expected an intent for an undiscovered repo to be rejected")
+	}
+	if reason := st.deadLetters["other"]; !strings.Contains(reason, `"website"`) {
+		t.Errorf("expected dead letter naming the repo, got %q", reason)
+	}
+	if got := testutil.ToFloat64(b.metrics.IntentsRejected.WithLabelValues("repo_not_allowed")); got != 1 {
+		t.Errorf("expected 1 repo_not_allowed rejection, got %v", got)
+	}
+
+	if err := b.processPushIntent(&mongodb.PushIntent{ID: "allowed", Repo: "svc-auth"}); err != nil {
+		t.Fatalf("expected an intent for a discovered repo to be processed, got %v", err)
+	}
+	if _, ok := st.deadLetters["allowed"]; ok {
+		t.Error("intent for a discovered repo should not be rejected")
+	}
+}
+
+func TestIntentLeftPendingUntilAllowlistLoads(t *testing.T) {
+	st := newFakeStore()
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+	b.repoAllowlist = staticAllowlist(nil)
+
+	if err := b.processPushIntent(&mongodb.PushIntent{ID: "early", Repo: "svc-auth"}); err == nil {
+		t.Fatal("expected the intent to fail while the allowlist is not loaded")
+	}
+	if _, ok := st.deadLetters["early"]; ok {
+		t.Error("intent should not be rejected before the allowlist loads")
+	}
+	if _, ok := st.processed["early"]; ok {
+		t.Error("intent should be left pending before the allowlist loads")
+	}
+}
+
+// writeSigningKey writes an armored private key for email and returns its path
+func writeSigningKey(t *testing.T, email string) string {
+	t.Helper()
+
+	key, err := openpgp.NewEntity("Virtual DOM Bot", "", email, nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := armor.Encode(&buf, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to armor key: %v", err)
+	}
+	if err := key.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("failed to serialize key: %v", err)
+	}
+	w.Close()
+
+	path := filepath.Join(t.TempDir(), "key.asc")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return path
+}
+
+func TestLoadSigningKeyRejectsMismatchedIdentity(t *testing.T) {
+	for _, tt := range []struct {
+		keyEmail string
+		wantErr  bool
+	}{
+		{"bot@tekfly.io", false},
+		{"someone-else@example.com", true},
+	} {
+		cfg := newTestConfig()
+		cfg.EnableSigning = true
+		cfg.GPGKeyPath = writeSigningKey(t, tt.keyEmail)
+
+		loaded, err := loadSigningKey(cfg)
+		if tt.wantErr {
+			if !errors.Is(err, git.ErrSignerIdentityMismatch) {
+				t.Errorf("%s: expected identity mismatch, got %v", tt.keyEmail, err)
+			}
+			continue
+		}
+		if err != nil || loaded == nil {
+			t.Errorf("%s: expected key to load, got %v", tt.keyEmail, err)
+		}
+	}
+}
+
+func TestLoadSigningKeyChecksIdentityMapEmails(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.EnableSigning = true
+	cfg.GPGKeyPath = writeSigningKey(t, "bot@tekfly.io")
+	cfg.IdentityMap = []config.Identity{
+		{Branch: "main", Name: "Virtual DOM Bot", Email: "bot@tekfly.io"},
+		{Branch: "release/*", Name: "Release Bot", Email: "release@tekfly.io"},
+	}
+
+	if _, err := loadSigningKey(cfg); !errors.Is(err, git.ErrSignerIdentityMismatch) {
+		t.Fatalf("expected identity mismatch for the release email, got %v", err)
+	}
+
+	cfg.IdentityMap = cfg.IdentityMap[:1]
+	if key, err := loadSigningKey(cfg); err != nil || key == nil {
+		t.Errorf("expected key to load when every email matches, got %v", err)
+	}
+}
+
+func TestPauseHoldsIntentsUntilResume(t *testing.T) {
+	st := newFakeStore()
+	st.intents = []*mongodb.PushIntent{
+		{ID: "first", Timestamp: time.Now()},
+		{ID: "second", Timestamp: time.Now()},
+	}
+
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+	b.Pause()
+	if got := testutil.ToFloat64(b.metrics.Paused); got != 1 {
+		t.Errorf("expected paused gauge 1, got %v", got)
+	}
+
+	go b.Start()
+	defer b.Shutdown(context.Background())
+
+	processed := func() int {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		return len(st.processed)
+	}
+
+	// Several poll intervals pass without anything being processed
+	time.Sleep(1500 * time.Millisecond)
+	if n := processed(); n != 0 {
+		t.Fatalf("expected no intents processed while paused, got %d", n)
+	}
+
+	b.Resume()
+	if b.Paused() || testutil.ToFloat64(b.metrics.Paused) != 0 {
+		t.Error("expected bridge to report running after resume")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for processed() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected both intents processed after resume, got %d", processed())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// fakeChangeStream replays insert events that are all immediately available
+type fakeChangeStream struct {
+	events [][]byte
+	pos    int
+	nexts  int
+}
+
+func (s *fakeChangeStream) Next(ctx context.Context) bool {
+	s.nexts++
+	return s.TryNext(ctx)
+}
+
+func (s *fakeChangeStream) TryNext(ctx context.Context) bool {
+	if s.pos >= len(s.events) {
+		return false
+	}
+	s.pos++
+	return true
+}
+
+func (s *fakeChangeStream) Decode(val interface{}) error {
+	return bson.Unmarshal(s.events[s.pos-1], val)
+}
+
+func (s *fakeChangeStream) Err() error {
+	return nil
+}
+
+func TestDrainChangeStreamEnqueuesBurstInBatches(t *testing.T) {
+	const total = 500
+
+	stream := &fakeChangeStream{}
+	for i := 0; i < total; i++ {
+		event, err := bson.Marshal(bson.M{"fullDocument": &mongodb.PushIntent{ID: fmt.Sprintf("intent-%d", i)}})
+		if err != nil {
+			t.Fatalf("failed to encode event: %v", err)
+		}
+		stream.events = append(stream.events, event)
+	}
+	// Already processed intents are skipped
+	skipped, _ := bson.Marshal(bson.M{"fullDocument": &mongodb.PushIntent{ID: "done", Processed: true}})
+	stream.events = append(stream.events, skipped)
+
+	b := newBridge(context.Background(), newTestConfig(), newFakeStore(), newTestMetrics(), newTestLogger())
+	before := testutil.ToFloat64(b.metrics.QueueSize)
+
+	received := make(chan []string)
+	go func() {
+		var ids []string
+		for intent := range b.workQueue {
+			ids = append(ids, intent.ID)
+		}
+		received <- ids
+	}()
+
+	if err := b.drainChangeStream(stream); err != nil {
+		t.Fatalf("drainChangeStream failed: %v", err)
+	}
+	close(b.workQueue)
+	ids := <-received
+
+	if len(ids) != total {
+		t.Fatalf("expected %d intents enqueued, got %d", total, len(ids))
+	}
+	for i, id := range ids {
+		if want := fmt.Sprintf("intent-%d", i); id != want {
+			t.Fatalf("expected %s at position %d, got %s", want, i, id)
+		}
+	}
+
+	// With every event available, each blocking read starts a full batch,
+	// plus the final read that ends the stream
+	events := len(stream.events)
+	batchSize := b.config.BatchSize
+	if want := (events+batchSize-1)/batchSize + 1; stream.nexts != want {
+		t.Errorf("expected %d blocking reads, got %d", want, stream.nexts)
+	}
+
+	if got := testutil.ToFloat64(b.metrics.QueueSize); got != before+total {
+		t.Errorf("expected queue size to grow by %d, got %v -> %v", total, before, got)
+	}
+}
+
+func TestChangeStreamDeliversRequeuedIntentOnce(t *testing.T) {
+	st := newFakeStore()
+	intent := &mongodb.PushIntent{ID: "requeued"}
+	st.intents = append(st.intents, intent)
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+
+	requeue := func() []byte {
+		t.Helper()
+		event, err := bson.Marshal(bson.M{
+			"operationType":     "update",
+			"updateDescription": bson.M{"updatedFields": bson.M{"processed": false}},
+			"fullDocument":      intent,
+		})
+		if err != nil {
+			t.Fatalf("failed to encode event: %v", err)
+		}
+		return event
+	}
+
+	drain := func(stream *fakeChangeStream) []*mongodb.PushIntent {
+		t.Helper()
+
+		received := make(chan []*mongodb.PushIntent)
+		b.workQueue = make(chan *mongodb.PushIntent, b.config.BatchSize)
+		go func() {
+			var intents []*mongodb.PushIntent
+			for intent := range b.workQueue {
+				intents = append(intents, intent)
+			}
+			received <- intents
+		}()
+
+		if err := b.drainChangeStream(stream); err != nil {
+			t.Fatalf("drainChangeStream failed: %v", err)
+		}
+		close(b.workQueue)
+		return <-received
+	}
+
+	// Setting processed back to false delivers the intent; a second update
+	// while it is still queued does not queue it twice
+	queued := drain(&fakeChangeStream{events: [][]byte{requeue(), requeue()}})
+	if len(queued) != 1 || queued[0].ID != intent.ID {
+		t.Fatalf("expected the requeued intent delivered once, got %v", queued)
+	}
+	if got := drain(&fakeChangeStream{events: [][]byte{requeue()}}); len(got) != 0 {
+		t.Fatalf("expected an in-flight intent not to be delivered again, got %v", got)
+	}
+
+	if err := b.processPushIntent(queued[0]); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	// Once processed it can be requeued again
+	if got := drain(&fakeChangeStream{events: [][]byte{requeue()}}); len(got) != 1 {
+		t.Errorf("expected the intent delivered after it was processed, got %v", got)
+	}
+}
+
+func newChangeEvent(t *testing.T, at time.Time, intent *mongodb.PushIntent) []byte {
+	t.Helper()
+
+	event, err := bson.Marshal(bson.M{
+		"clusterTime":  primitive.Timestamp{T: uint32(at.Unix())},
+		"fullDocument": intent,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	return event
+}
+
+func TestReplayDeliversHistoricalEventsThenGoesLive(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	st := newFakeStore()
+	for _, id := range []string{"old-pending", "old-processed", "live-1", "live-2"} {
+		st.intents = append(st.intents, &mongodb.PushIntent{ID: id})
+	}
+	st.processed["old-processed"] = nil
+
+	cfg := newTestConfig()
+	cfg.ReplaySince = now.Add(-2 * time.Hour)
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+	// As set by watchChangeStream when the replaying stream opens
+	b.replayUntil = now.Add(-time.Hour)
+
+	drain := func(stream *fakeChangeStream) []string {
+		t.Helper()
+
+		received := make(chan []string)
+		b.workQueue = make(chan *mongodb.PushIntent, cfg.BatchSize)
+		go func() {
+			var ids []string
+			for intent := range b.workQueue {
+				ids = append(ids, intent.ID)
+			}
+			received <- ids
+		}()
+
+		if err := b.drainChangeStream(stream); err != nil {
+			t.Fatalf("drainChangeStream failed: %v", err)
+		}
+		close(b.workQueue)
+		return <-received
+	}
+
+	// The first stream is cut off part way through the history
+	historical := &fakeChangeStream{events: [][]byte{
+		newChangeEvent(t, now.Add(-110*time.Minute), &mongodb.PushIntent{ID: "old-pending"}),
+		newChangeEvent(t, now.Add(-100*time.Minute), &mongodb.PushIntent{ID: "old-processed"}),
+	}}
+	if got := drain(historical); fmt.Sprint(got) != "[old-pending]" {
+		t.Errorf("expected only the unprocessed historical intent, got %v", got)
+	}
+	if want := now.Add(-100 * time.Minute); !b.replayFrom.Equal(want) {
+		t.Errorf("expected a reconnect to resume replay at %v, got %v", want, b.replayFrom)
+	}
+
+	// The reconnected stream reaches the live point and stops replaying
+	live := &fakeChangeStream{events: [][]byte{
+		newChangeEvent(t, now.Add(-30*time.Minute), &mongodb.PushIntent{ID: "live-1"}),
+		newChangeEvent(t, now, &mongodb.PushIntent{ID: "live-2"}),
+	}}
+	if got := drain(live); fmt.Sprint(got) != "[live-1 live-2]" {
+		t.Errorf("expected live intents to be delivered, got %v", got)
+	}
+	if !b.replayFrom.IsZero() {
+		t.Errorf("expected replay to end after catching up, got %v", b.replayFrom)
+	}
+}
+
+func TestHighPriorityIntentIsSelectedFirst(t *testing.T) {
+	now := time.Now()
+	st := newFakeStore()
+	st.intents = []*mongodb.PushIntent{
+		{ID: "old-1", Timestamp: now.Add(-2 * time.Hour)},
+		{ID: "old-2", Timestamp: now.Add(-time.Hour)},
+		{ID: "hotfix", Timestamp: now, Priority: 10},
+	}
+
+	pending, err := st.GetPendingPushIntents(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("GetPendingPushIntents failed: %v", err)
+	}
+	if len(pending) != 2 || pending[0].ID != "hotfix" || pending[1].ID != "old-1" {
+		t.Fatalf("expected hotfix then old-1 from the store, got %v", intentIDs(pending))
+	}
+
+	// Producers hand over in arrival order; workers still see the hotfix first
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+	for _, intent := range st.intents {
+		b.workQueue <- intent
+	}
+	close(b.workQueue)
+
+	b.wg.Add(1)
+	go b.dispatch()
+
+	// Wait for the dispatcher to hold every intent before a worker asks
+	deadline := time.Now().Add(5 * time.Second)
+	for len(b.workQueue) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	var order []*mongodb.PushIntent
+	for intent := range b.ready {
+		order = append(order, intent)
+	}
+	if got := intentIDs(order); fmt.Sprint(got) != "[hotfix old-1 old-2]" {
+		t.Errorf("expected hotfix ahead of older intents, got %v", got)
+	}
+}
+
+func intentIDs(intents []*mongodb.PushIntent) []string {
+	ids := make([]string, len(intents))
+	for i, intent := range intents {
+		ids[i] = intent.ID
+	}
+	return ids
+}
+
+func TestStatusEndpointReportsBridgeState(t *testing.T) {
+	st := newFakeStore()
+	st.intents = []*mongodb.PushIntent{{ID: "a"}, {ID: "b"}}
+	b := newBridge(context.Background(), newTestConfig(), st, newTestMetrics(), newTestLogger())
+	b.SetBuildInfo(api.BuildInfo{Version: "1.2.3", Commit: "abc123", Date: "2024-05-01"})
+
+	if err := b.updateBacklogAge(); err != nil {
+		t.Fatalf("updateBacklogAge failed: %v", err)
+	}
+	b.enqueueBatch([]*mongodb.PushIntent{{ID: "a"}})
+	b.Pause()
+	defer b.Resume()
+	b.pendingMarks.Add(1)
+
+	mux := http.NewServeMux()
+	b.RegisterHandlers(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 without an admin token, got %d", rec.Code)
+	}
+
+	var status api.Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("invalid status JSON: %v", err)
+	}
+
+	if status.Version != "1.2.3" || status.Workers != 2 || !status.Paused {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.Backlog != 2 || status.QueueDepth != 1 {
+		t.Errorf("expected backlog 2 and queue depth 1, got %d and %d", status.Backlog, status.QueueDepth)
+	}
+	if status.Circuit != api.CircuitOpen || status.LastPushAt != nil {
+		t.Errorf("expected an open circuit and no push yet, got %q and %v", status.Circuit, status.LastPushAt)
+	}
+}
@@ -0,0 +1,128 @@
+package bridge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+)
+
+// gcLockOwner stands in for the intent ID in repo locks taken to repack
+const gcLockOwner = "gc"
+
+// cloneKey identifies a cached clone. The path prefix is fixed when cloning,
+// so repos with several prefixes keep a clone per prefix.
+type cloneKey struct {
+	repo   string
+	branch string
+	prefix string
+}
+
+// cloneCache keeps clones between intents with CACHE_CLONES. A clone is
+// taken out while an intent or the repack uses it, so it is never shared,
+// and put back afterwards.
+type cloneCache struct {
+	mu     sync.Mutex
+	clones map[cloneKey]*git.Repository
+	closed bool
+}
+
+func newCloneCache() *cloneCache {
+	return &cloneCache{clones: make(map[cloneKey]*git.Repository)}
+}
+
+// take removes and returns the clone cached for key, or nil
+func (c *cloneCache) take(key cloneKey) *git.Repository {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	repo := c.clones[key]
+	delete(c.clones, key)
+	return repo
+}
+
+// put caches repo for key. A clone is removed instead when one is already
+// cached for key or the cache is closed.
+func (c *cloneCache) put(key cloneKey, repo *git.Repository) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.clones[key]; ok || c.closed {
+		repo.Cleanup()
+		return
+	}
+	c.clones[key] = repo
+}
+
+// keys returns the keys of the cached clones
+func (c *cloneCache) keys() []cloneKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]cloneKey, 0, len(c.clones))
+	for key := range c.clones {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// close removes every cached clone; clones put back afterwards are removed
+// straight away
+func (c *cloneCache) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	for key, repo := range c.clones {
+		repo.Cleanup()
+		delete(c.clones, key)
+	}
+}
+
+// collectGarbage repacks cached clones every GC_INTERVAL
+func (b *Bridge) collectGarbage() {
+	defer b.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(b.config.GCInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-ticker.C:
+			b.gcClones()
+		}
+	}
+}
+
+// gcClones repacks each cached clone holding more than
+// GC_LOOSE_OBJECT_THRESHOLD loose objects. Each clone is repacked under the
+// repo lock of its repository and branch so a push never races it.
+func (b *Bridge) gcClones() {
+	for _, key := range b.clones.keys() {
+		if b.ctx.Err() != nil {
+			return
+		}
+
+		fields := logrus.Fields{"repo": key.repo, "branch": key.branch}
+		release, err := b.lockRepo(key.repo, key.branch, gcLockOwner)
+		if err != nil {
+			b.logger.WithError(err).WithFields(fields).Warn("Failed to lock cached clone for repacking")
+			continue
+		}
+
+		// An intent may have taken the clone, or dropped it, meanwhile
+		if repo := b.clones.take(key); repo != nil {
+			if _, err := repo.GC(b.config.GCLooseObjectThreshold); err != nil {
+				b.logger.WithError(err).WithFields(fields).Warn("Failed to repack cached clone, removing it")
+				b.metrics.ErrorsByType.WithLabelValues("gc").Inc()
+				repo.Cleanup()
+			} else {
+				b.clones.put(key, repo)
+			}
+		}
+		release()
+	}
+}
@@ -0,0 +1,111 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+func TestCachedCloneIsReusedAndRepackedPastThreshold(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CacheClones = true
+	cfg.GCLooseObjectThreshold = 3
+	cfg.RepoLockTTL = 60
+	b, st, memory, intent := newPushTest(t, cfg)
+	backend := &diskFullBackend{MemoryBackend: memory}
+	b.gitBackend = backend
+	defer b.clones.close()
+
+	push := func(id, docID, path, content string) {
+		t.Helper()
+		st.mu.Lock()
+		st.documents[docID] = &mongodb.Document{ID: docID, Path: path, Blob: []byte(content)}
+		next := &mongodb.PushIntent{ID: id, Repo: "site", Branch: "main", Message: "Publish " + path, Documents: []string{docID}}
+		st.intents = append(st.intents, next)
+		st.mu.Unlock()
+		if err := b.processPushIntent(next); err != nil {
+			t.Fatalf("processPushIntent %s failed: %v", id, err)
+		}
+	}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+	push("intent-2", "3", "docs/guide.md", "# Guide\n")
+
+	if n := backend.clones.Load(); n != 1 {
+		t.Fatalf("expected the clone to be reused, cloned %d times", n)
+	}
+
+	key := b.cloneKey(intent)
+	loose := func() int {
+		t.Helper()
+		repo := b.clones.take(key)
+		if repo == nil {
+			t.Fatal("expected a cached clone")
+		}
+		defer b.clones.put(key, repo)
+		n, err := repo.LooseObjects()
+		if err != nil {
+			t.Fatalf("LooseObjects failed: %v", err)
+		}
+		return n
+	}
+	if n := loose(); n <= cfg.GCLooseObjectThreshold {
+		t.Fatalf("expected the pushes to leave more than %d loose objects, got %d", cfg.GCLooseObjectThreshold, n)
+	}
+
+	// The repack waits for the repo lock like a push would
+	st.mu.Lock()
+	st.repoLocks["tekfly/site@main"] = mongodb.RepoLock{Owner: "other-worker", ExpiresAt: time.Now().Add(time.Hour)}
+	st.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.gcClones()
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if got := testutil.ToFloat64(b.metrics.RepoGC); got != 0 {
+		t.Fatalf("expected no repack while the repo is locked, got %v", got)
+	}
+
+	st.mu.Lock()
+	delete(st.repoLocks, "tekfly/site@main")
+	st.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the repack")
+	}
+
+	if got := testutil.ToFloat64(b.metrics.RepoGC); got != 1 {
+		t.Errorf("expected repo_gc_total 1, got %v", got)
+	}
+	if n := loose(); n != 0 {
+		t.Errorf("expected no loose objects after the repack, got %d", n)
+	}
+
+	// Below the threshold nothing is repacked
+	b.gcClones()
+	if got := testutil.ToFloat64(b.metrics.RepoGC); got != 1 {
+		t.Errorf("expected no further repack, got %v", got)
+	}
+
+	// The repacked clone still pushes
+	push("intent-3", "4", "docs/faq.md", "# FAQ\n")
+	commits, err := memory.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 4 || commits[0].Message != "Publish docs/faq.md" {
+		t.Errorf("expected the third push on the remote, got %d commits", len(commits))
+	}
+	if n := backend.clones.Load(); n != 1 {
+		t.Errorf("expected the repacked clone to be reused, cloned %d times", n)
+	}
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"crypto/sha256"
+	"time"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// pathWrite is the change last accepted for a repository path, with the
+// version and timestamp of the document it came from
+type pathWrite struct {
+	operation string
+	sum       [sha256.Size]byte
+	version   int64
+	timestamp time.Time
+}
+
+// deduper collapses documents of one intent that write the same repository
+// path. Only a hash is kept per path, so documents can still be streamed.
+type deduper struct {
+	writes     map[string]pathWrite
+	duplicates int
+	superseded int
+
+	// audited maps paths to their position in the intent's audit changes
+	audited map[string]int
+}
+
+func newDeduper() *deduper {
+	return &deduper{writes: make(map[string]pathWrite), audited: make(map[string]int)}
+}
+
+// record appends change to changes, or replaces the change already recorded
+// for its path so a superseded write is not audited alongside its winner
+func (d *deduper) record(changes []mongodb.AuditChange, change mongodb.AuditChange) []mongodb.AuditChange {
+	if i, ok := d.audited[change.Path]; ok {
+		changes[i] = change
+		return changes
+	}
+	d.audited[change.Path] = len(changes)
+	return append(changes, change)
+}
+
+// accept reports whether a document resolving to path should be applied.
+// Exact duplicates of the accepted change are dropped. When the content
+// differs the newer document wins by version, then timestamp, then position
+// in the intent; a winner arriving later simply overwrites the earlier write.
+func (d *deduper) accept(path string, doc *mongodb.Document, change git.Document) bool {
+	write := pathWrite{
+		operation: change.EffectiveOperation(),
+		sum:       sha256.Sum256(change.Content),
+		version:   doc.Version,
+		timestamp: doc.Timestamp,
+	}
+
+	prev, ok := d.writes[path]
+	if !ok {
+		d.writes[path] = write
+		return true
+	}
+
+	if prev.operation == write.operation && prev.sum == write.sum {
+		d.duplicates++
+		return false
+	}
+
+	if write.olderThan(prev) {
+		d.superseded++
+		return false
+	}
+
+	d.superseded++
+	d.writes[path] = write
+	return true
+}
+
+// olderThan orders writes by version, then timestamp. Equal writes are not
+// older, so the later one in the intent wins.
+func (w pathWrite) olderThan(other pathWrite) bool {
+	if w.version != other.version {
+		return w.version < other.version
+	}
+	return w.timestamp.Before(other.timestamp)
+}
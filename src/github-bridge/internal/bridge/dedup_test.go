@@ -0,0 +1,77 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+func TestDeduperCollapsesExactDuplicates(t *testing.T) {
+	d := newDeduper()
+	change := git.Document{Path: "docs/a.md", Content: []byte("same"), Operation: "update"}
+
+	if !d.accept("docs/a.md", &mongodb.Document{ID: "1"}, change) {
+		t.Fatal("expected first document to be applied")
+	}
+	if d.accept("docs/a.md", &mongodb.Document{ID: "2"}, change) {
+		t.Error("expected identical document to be collapsed")
+	}
+	if !d.accept("docs/b.md", &mongodb.Document{ID: "3"}, change) {
+		t.Error("expected same content on another path to be applied")
+	}
+
+	deleted := git.Document{Path: "docs/a.md", Operation: "delete"}
+	if !d.accept("docs/a.md", &mongodb.Document{ID: "4"}, deleted) {
+		t.Error("expected a delete of the path not to count as a duplicate")
+	}
+
+	if d.duplicates != 1 {
+		t.Errorf("expected 1 duplicate, got %d", d.duplicates)
+	}
+}
+
+func TestDeduperOrdersConflictingContent(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		first       *mongodb.Document
+		second      *mongodb.Document
+		acceptLater bool
+	}{
+		{
+			name:        "higher version wins",
+			first:       &mongodb.Document{ID: "1", Version: 2},
+			second:      &mongodb.Document{ID: "2", Version: 1, Timestamp: now},
+			acceptLater: false,
+		},
+		{
+			name:        "later timestamp breaks version tie",
+			first:       &mongodb.Document{ID: "1", Version: 1, Timestamp: now},
+			second:      &mongodb.Document{ID: "2", Version: 1, Timestamp: now.Add(time.Second)},
+			acceptLater: true,
+		},
+		{
+			name:        "later in intent wins a full tie",
+			first:       &mongodb.Document{ID: "1", Version: 1, Timestamp: now},
+			second:      &mongodb.Document{ID: "2", Version: 1, Timestamp: now},
+			acceptLater: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDeduper()
+			d.accept("a.md", tt.first, git.Document{Content: []byte("first"), Operation: "update"})
+
+			got := d.accept("a.md", tt.second, git.Document{Content: []byte("second"), Operation: "update"})
+			if got != tt.acceptLater {
+				t.Errorf("expected accept=%v for the second document, got %v", tt.acceptLater, got)
+			}
+			if d.superseded != 1 || d.duplicates != 0 {
+				t.Errorf("expected 1 superseded and 0 duplicates, got %d and %d", d.superseded, d.duplicates)
+			}
+		})
+	}
+}
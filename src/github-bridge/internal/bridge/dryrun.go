@@ -0,0 +1,84 @@
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// recordDryRun writes the changes staged in repo for intent to
+// DRY_RUN_OUTPUT, for review before the intent is run for real
+func (b *Bridge) recordDryRun(ctx context.Context, intent *mongodb.PushIntent, repo *git.Repository) (err error) {
+	ctx, span := b.startSpan(ctx, "dry_run")
+	defer func() { endSpan(span, err) }()
+
+	base, err := repo.HeadHash()
+	if err != nil {
+		return err
+	}
+	diff, err := repo.DiffStaged()
+	if err != nil {
+		return err
+	}
+
+	result := &mongodb.DryRunResult{
+		IntentID:   intent.ID,
+		Repo:       b.targetRepo(intent),
+		Branch:     intent.Branch,
+		BaseCommit: base,
+		Changes:    make([]mongodb.DryRunChange, 0, len(diff)),
+		CreatedAt:  time.Now(),
+	}
+	for _, change := range diff {
+		result.Changes = append(result.Changes, mongodb.DryRunChange{
+			Path:      change.Path,
+			Operation: change.Operation,
+			Diff:      change.Diff,
+		})
+	}
+
+	if b.config.DryRunOutput == config.DryRunOutputMongoDB {
+		err = b.mongo.SaveDryRunResult(ctx, result)
+	} else {
+		err = writeDryRunFile(b.config.DryRunOutput, result)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record dry run: %w", err)
+	}
+
+	b.logger.WithFields(logrus.Fields{
+		"intent_id": intent.ID,
+		"changes":   len(result.Changes),
+		"output":    b.config.DryRunOutput,
+	}).Info("DRY RUN: Recorded proposed changes")
+	return nil
+}
+
+// writeDryRunFile writes result as JSON to <intent ID>.json in dir,
+// replacing an earlier result for the intent
+func writeDryRunFile(dir string, result *mongodb.DryRunResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write then rename so a reviewer never reads a partial result
+	path := filepath.Join(dir, url.PathEscape(result.IntentID)+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+func TestDryRunRecordsResultWithoutPushing(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+	b.config.DryRun = true
+	b.config.DryRunOutput = config.DryRunOutputMongoDB
+
+	before, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	after, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(after) != len(before) || after[0].Hash != before[0].Hash {
+		t.Fatalf("expected the remote untouched, went from %d to %d commits", len(before), len(after))
+	}
+	if len(st.audit) != 0 {
+		t.Errorf("expected no audit record for a dry run, got %+v", st.audit)
+	}
+
+	result := st.dryRuns[intent.ID]
+	if result == nil {
+		t.Fatal("expected a dry run result for the intent")
+	}
+	if result.Repo != "tekfly/site" || result.Branch != "main" || result.BaseCommit != before[0].Hash.String() {
+		t.Errorf("unexpected result target %s@%s from %s", result.Repo, result.Branch, result.BaseCommit)
+	}
+
+	want := []struct{ path, operation, line string }{
+		{"README.md", "delete", "-site"},
+		{"docs/index.md", "create", "+# Hello"},
+	}
+	if len(result.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), result.Changes)
+	}
+	for i, w := range want {
+		c := result.Changes[i]
+		if c.Path != w.path || c.Operation != w.operation || !strings.Contains(c.Diff, w.line) {
+			t.Errorf("change %d: expected %s %s with %q, got %+v", i, w.operation, w.path, w.line, c)
+		}
+	}
+}
+
+func TestDryRunWritesResultToDirectory(t *testing.T) {
+	b, st, _, intent := newPushTest(t, newTestConfig())
+	dir := filepath.Join(t.TempDir(), "dry-runs")
+	b.config.DryRun = true
+	b.config.DryRunOutput = dir
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+	if len(st.dryRuns) != 0 {
+		t.Errorf("expected nothing written to MongoDB, got %v", st.dryRuns)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, intent.ID+".json"))
+	if err != nil {
+		t.Fatalf("expected a result file: %v", err)
+	}
+	var result mongodb.DryRunResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("invalid result file: %v", err)
+	}
+	if result.IntentID != intent.ID || len(result.Changes) != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
@@ -0,0 +1,59 @@
+package bridge
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ellipsis marks where a subject or body was cut
+const ellipsis = "…"
+
+// commitMessage renders the commit message for changes to paths: the scope
+// prefix is applied first and the result sanitized, before the git package
+// appends any manifest
+func (b *Bridge) commitMessage(message string, paths []string) string {
+	message = scopeMessage(b.config.CommitScopeFormat, message, paths)
+	return sanitizeMessage(message, b.config.CommitSubjectMaxLength, b.config.CommitBodyMaxLength)
+}
+
+// sanitizeMessage strips control characters other than newlines and tabs,
+// then shortens a subject line longer than maxSubject runes with an
+// ellipsis, moving the overflow to the start of the body, and cuts a body
+// longer than maxBody runes. Zero limits disable truncation. Messages within
+// the limits are returned unchanged.
+func sanitizeMessage(message string, maxSubject, maxBody int) string {
+	message = strings.ReplaceAll(message, "\r\n", "\n")
+	message = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			return r
+		}
+		return -1
+	}, strings.ToValidUTF8(message, ""))
+
+	subject, body, hasBody := strings.Cut(message, "\n")
+
+	if runes := []rune(subject); maxSubject > 1 && len(runes) > maxSubject {
+		cut := maxSubject - 1
+		overflow := ellipsis + strings.TrimSpace(string(runes[cut:]))
+		subject = strings.TrimRight(string(runes[:cut]), " \t") + ellipsis
+
+		if body = strings.TrimLeft(body, "\n"); body != "" {
+			body = overflow + "\n\n" + body
+		} else {
+			body = overflow
+		}
+		body = "\n" + body
+		hasBody = true
+	}
+
+	// The blank lines separating the body from the subject do not count
+	text := strings.TrimLeft(body, "\n")
+	if runes := []rune(text); maxBody > 1 && len(runes) > maxBody {
+		body = body[:len(body)-len(text)] + string(runes[:maxBody-1]) + ellipsis
+	}
+
+	if !hasBody {
+		return subject
+	}
+	return subject + "\n" + body
+}
@@ -0,0 +1,45 @@
+package bridge
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		subject int
+		body    int
+		want    string
+	}{
+		{"normal message unchanged", "Publish docs\n\nUpdates the\tindex page.", 20, 100, "Publish docs\n\nUpdates the\tindex page."},
+		{"subject only unchanged", "Publish docs", 20, 100, "Publish docs"},
+		{"control characters stripped", "Publish\x1b[31m docs\x00\r\n\r\nBody\x07 text", 20, 100, "Publish[31m docs\n\nBody text"},
+		{"oversize subject", "Publish the new landing page copy", 20, 100, "Publish the new lan…\n\n…ding page copy"},
+		{"oversize subject with body", "Publish the new landing page copy\n\nDetails", 20, 100, "Publish the new lan…\n\n…ding page copy\n\nDetails"},
+		{"oversize body", "Publish docs\n\n" + strings.Repeat("x", 50), 20, 10, "Publish docs\n\nxxxxxxxxx…"},
+		{"limits disabled", "Publish the new landing page copy", 0, 0, "Publish the new landing page copy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeMessage(tt.message, tt.subject, tt.body); got != tt.want {
+				t.Errorf("sanitizeMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeMessageCountsRunes(t *testing.T) {
+	got := sanitizeMessage(strings.Repeat("ü", 30), 10, 0)
+
+	subject, _, _ := strings.Cut(got, "\n")
+	if n := utf8.RuneCountInString(subject); n != 10 {
+		t.Errorf("expected a 10 rune subject, got %d in %q", n, subject)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("expected valid UTF-8, got %q", got)
+	}
+}
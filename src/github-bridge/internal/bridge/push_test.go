@@ -0,0 +1,805 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git/gittest"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// newPushTest wires a bridge to an in-memory remote holding tekfly/site with
+// a README on main, and seeds the store with an intent touching two files
+func newPushTest(t *testing.T, cfg *config.Config) (*Bridge, *fakeStore, *gittest.MemoryBackend, *mongodb.PushIntent) {
+	t.Helper()
+
+	// Keep clones out of the shared temp dir
+	t.Setenv("TMPDIR", t.TempDir())
+
+	backend := gittest.NewMemoryBackend()
+	if err := backend.CreateRepository("tekfly/site", "main", map[string]string{
+		"README.md": "site\n",
+	}); err != nil {
+		t.Fatalf("failed to create remote: %v", err)
+	}
+
+	st := newFakeStore()
+	st.documents["1"] = &mongodb.Document{ID: "1", Path: "docs/index.md", Blob: []byte("# Hello\n")}
+	st.documents["2"] = &mongodb.Document{
+		ID:       "2",
+		Path:     "README.md",
+		Metadata: map[string]interface{}{"operation": "delete"},
+	}
+
+	cfg.DryRun = false
+	cfg.GitHubRepo = "tekfly/site"
+	cfg.GitHubBranch = "main"
+	if cfg.CommitGranularity == "" {
+		cfg.CommitGranularity = config.CommitGranularityIntent
+	}
+
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+	b.gitBackend = backend
+
+	intent := &mongodb.PushIntent{
+		ID:        "intent-1",
+		Repo:      "site",
+		Branch:    "main",
+		Author:    "alice",
+		Message:   "Publish docs",
+		Documents: []string{"1", "2"},
+	}
+	st.intents = append(st.intents, intent)
+
+	return b, st, backend, intent
+}
+
+func TestPushIntentLandsOnRemote(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	if err, ok := st.processed[intent.ID]; !ok || err != nil {
+		t.Fatalf("expected intent to be marked processed without error, got %v (marked=%v)", err, ok)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits on the remote, got %d", len(commits))
+	}
+
+	head := commits[0]
+	if head.Message != "Publish docs" {
+		t.Errorf("unexpected commit message %q", head.Message)
+	}
+	if head.Author.Name != "Virtual DOM Bot" || head.Author.Email != "bot@tekfly.io" {
+		t.Errorf("unexpected commit author %s <%s>", head.Author.Name, head.Author.Email)
+	}
+
+	content, err := backend.File("tekfly/site", "main", "docs/index.md")
+	if err != nil {
+		t.Fatalf("expected docs/index.md on the remote: %v", err)
+	}
+	if content != "# Hello\n" {
+		t.Errorf("unexpected remote content %q", content)
+	}
+	if _, err := backend.File("tekfly/site", "main", "README.md"); err == nil {
+		t.Error("expected README.md to be deleted on the remote")
+	}
+
+	if len(st.audit) != 1 || st.audit[0].CommitHash != head.Hash.String() {
+		t.Fatalf("expected an audit record for %s, got %+v", head.Hash, st.audit)
+	}
+	want := []mongodb.AuditChange{
+		{Path: "docs/index.md", Operation: "update"},
+		{Path: "README.md", Operation: "delete"},
+	}
+	if fmt.Sprint(st.audit[0].Changes) != fmt.Sprint(want) {
+		t.Errorf("expected audited changes %v, got %v", want, st.audit[0].Changes)
+	}
+}
+
+func TestPushIntentPerDocumentCommits(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CommitGranularity = config.CommitGranularityDocument
+	b, _, backend, intent := newPushTest(t, cfg)
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 3 {
+		t.Errorf("expected one commit per document on the remote, got %d commits", len(commits))
+	}
+}
+
+func TestPushIntentInMemoryApply(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.ApplyMode = config.ApplyModeTree
+	b, _, backend, intent := newPushTest(t, cfg)
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Message != "Publish docs" {
+		t.Fatalf("expected a single intent commit on the remote, got %d commits", len(commits))
+	}
+
+	content, err := backend.File("tekfly/site", "main", "docs/index.md")
+	if err != nil || content != "# Hello\n" {
+		t.Errorf("expected docs/index.md on the remote, got %q, %v", content, err)
+	}
+	if _, err := backend.File("tekfly/site", "main", "README.md"); err == nil {
+		t.Error("expected README.md to be deleted on the remote")
+	}
+}
+
+func TestAmendKeepsOneBotCommitOnBranch(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AmendBranches = []string{"main"}
+	b, st, backend, intent := newPushTest(t, cfg)
+
+	// The tip is a human's commit, so the first intent stacks on it
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected the first intent to add a commit, got %d commits", len(commits))
+	}
+	base := commits[1].Hash
+
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "docs/about.md", Blob: []byte("# About\n")}
+	second := &mongodb.PushIntent{
+		ID:        "intent-2",
+		Repo:      "site",
+		Branch:    "main",
+		Author:    "alice",
+		Message:   "Publish about page",
+		Documents: []string{"3"},
+	}
+	st.intents = append(st.intents, second)
+	if err := b.processPushIntent(second); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err = backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Message != "Publish about page" {
+		t.Fatalf("expected the bot commit to be amended, got %d commits", len(commits))
+	}
+	if len(commits[0].ParentHashes) != 1 || commits[0].ParentHashes[0] != base {
+		t.Errorf("expected the amended commit to sit on %s, got %v", base, commits[0].ParentHashes)
+	}
+	for _, path := range []string{"docs/index.md", "docs/about.md"} {
+		if _, err := backend.File("tekfly/site", "main", path); err != nil {
+			t.Errorf("expected %s on the remote: %v", path, err)
+		}
+	}
+	if _, err := backend.File("tekfly/site", "main", "README.md"); err == nil {
+		t.Error("expected README.md to stay deleted")
+	}
+	if err, ok := st.processed[second.ID]; !ok || err != nil {
+		t.Errorf("expected the second intent to be processed, got %v (marked=%v)", err, ok)
+	}
+}
+
+func TestRenamedDocumentRemovesPreviousPath(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+
+	// README.md moves under docs/ rather than being deleted
+	st.documents["2"] = &mongodb.Document{
+		ID:       "2",
+		Path:     "docs/README.md",
+		Blob:     []byte("site\n"),
+		Metadata: map[string]interface{}{"previous_path": "README.md"},
+	}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected the rename in a single commit, got %d commits", len(commits))
+	}
+	if _, err := backend.File("tekfly/site", "main", "README.md"); err == nil {
+		t.Error("expected the previous path to be removed")
+	}
+	if content, err := backend.File("tekfly/site", "main", "docs/README.md"); err != nil || content != "site\n" {
+		t.Errorf("expected docs/README.md on the remote, got %q, %v", content, err)
+	}
+
+	// The audit records the removal alongside the write
+	if len(st.audit) != 1 {
+		t.Fatalf("expected one audit record, got %d", len(st.audit))
+	}
+	var removed bool
+	for _, change := range st.audit[0].Changes {
+		removed = removed || change == mongodb.AuditChange{Path: "README.md", Operation: "delete"}
+	}
+	if !removed {
+		t.Errorf("expected the audit to record README.md as deleted, got %v", st.audit[0].Changes)
+	}
+}
+
+func TestPostPushHookRunsAfterPush(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "commit")
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$BRIDGE_REPO $BRIDGE_COMMIT\" > "+out+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newTestConfig()
+	cfg.PostPushHook = script
+	cfg.PostPushHookTimeout = 5
+	b, _, backend, intent := newPushTest(t, cfg)
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("expected the hook to run: %v", err)
+	}
+	if got, want := strings.TrimSpace(string(data)), "tekfly/site "+commits[0].Hash.String(); got != want {
+		t.Errorf("expected the hook to get %q, got %q", want, got)
+	}
+}
+
+func TestPostPushHookRunsOutsideRepoLock(t *testing.T) {
+	dir := t.TempDir()
+	started := filepath.Join(dir, "started")
+	done := filepath.Join(dir, "done")
+	script := filepath.Join(dir, "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ntouch "+started+"\nwhile [ ! -f "+done+" ]; do sleep 0.05; done\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newTestConfig()
+	cfg.PostPushHook = script
+	cfg.PostPushHookTimeout = 5
+	b, st, _, intent := newPushTest(t, cfg)
+
+	result := make(chan error, 1)
+	go func() { result <- b.processPushIntent(intent) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(started); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the hook to start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	st.mu.Lock()
+	held := len(st.repoLocks)
+	st.mu.Unlock()
+	if held != 0 {
+		t.Errorf("expected the repo lock to be released while the hook runs, %d held", held)
+	}
+
+	if err := os.WriteFile(done, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-result; err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+}
+
+func TestPostPushHookFailureDoesNotFailPush(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := newTestConfig()
+	cfg.PostPushHook = script
+	cfg.PostPushHookTimeout = 5
+	b, st, _, intent := newPushTest(t, cfg)
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("expected the push to succeed despite the hook, got %v", err)
+	}
+	if err, ok := st.processed[intent.ID]; !ok || err != nil {
+		t.Errorf("expected intent to be marked processed without error, got %v (marked=%v)", err, ok)
+	}
+	if got := testutil.ToFloat64(b.metrics.PostPushHookFailures); got != 1 {
+		t.Errorf("expected 1 hook failure, got %v", got)
+	}
+}
+
+func TestMarkFailureIsReconciledWithoutPushingAgain(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+
+	// MongoDB drops out between the push and the mark
+	st.markErr = errors.New("connection reset")
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	head := commits[0].Hash.String()
+
+	mark := st.pendingMarks[intent.ID]
+	if mark == nil || mark.CommitHash != head || mark.Error != "" {
+		t.Fatalf("expected a pending mark for %s, got %+v", head, mark)
+	}
+
+	// Redelivery while the mark is still failing must not push again
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("redelivered processPushIntent failed: %v", err)
+	}
+	if again, _ := backend.Commits("tekfly/site", "main"); len(again) != len(commits) {
+		t.Fatalf("expected no new commits on redelivery, got %d -> %d", len(commits), len(again))
+	}
+
+	// Once MongoDB recovers the reconciler completes the mark
+	st.markErr = nil
+	if err := b.reconcileOnce(); err != nil {
+		t.Fatalf("reconcileOnce failed: %v", err)
+	}
+
+	if err, ok := st.processed[intent.ID]; !ok || err != nil {
+		t.Errorf("expected intent marked processed without error, got %v (marked=%v)", err, ok)
+	}
+	if _, ok := st.pendingMarks[intent.ID]; ok {
+		t.Error("expected pending mark to be removed after reconciling")
+	}
+}
+
+func TestPendingMarkLookupFailureLeavesIntentPending(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+	b.mongo = &failingMarkLookup{fakeStore: st}
+
+	if err := b.processPushIntent(intent); err == nil {
+		t.Fatal("expected an error when pending marks cannot be checked")
+	}
+
+	if _, ok := st.processed[intent.ID]; ok {
+		t.Error("expected intent to stay pending")
+	}
+	if commits, _ := backend.Commits("tekfly/site", "main"); len(commits) != 1 {
+		t.Errorf("expected nothing pushed, got %d commits", len(commits))
+	}
+}
+
+// failingMarkLookup is a store whose pending mark lookups fail
+type failingMarkLookup struct {
+	*fakeStore
+}
+
+func (s *failingMarkLookup) GetPendingMark(ctx context.Context, intentID string) (*mongodb.PendingMark, error) {
+	return nil, errors.New("connection reset")
+}
+
+func TestPushIntentKeepsNewestVersionOfSamePath(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "docs/index.md", Blob: []byte("# Stale\n"), Version: 1}
+	st.documents["1"].Version = 2
+	st.documents["4"] = &mongodb.Document{ID: "4", Path: "docs/index.md", Blob: []byte("# Hello\n"), Version: 2}
+	intent.Documents = []string{"1", "4", "3", "2"}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	content, err := backend.File("tekfly/site", "main", "docs/index.md")
+	if err != nil {
+		t.Fatalf("expected docs/index.md on the remote: %v", err)
+	}
+	if content != "# Hello\n" {
+		t.Errorf("expected the newest version on the remote, got %q", content)
+	}
+
+	if len(st.audit) != 1 || len(st.audit[0].Changes) != 2 {
+		t.Errorf("expected one audited change per path, got %+v", st.audit)
+	}
+}
+
+func TestPushIntentSupersededWriteIsAuditedOnce(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "docs/index.md", Blob: []byte("# Stale\n"), Version: 1, Timestamp: older}
+	st.documents["1"].Version = 1
+	st.documents["1"].Timestamp = older.Add(time.Hour)
+	st.documents["5"] = &mongodb.Document{ID: "5", Path: "docs/index.md", Blob: []byte("# Stale\n"), Version: 1, Timestamp: older}
+	intent.Documents = []string{"3", "2", "1", "5"}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	content, err := backend.File("tekfly/site", "main", "docs/index.md")
+	if err != nil {
+		t.Fatalf("expected docs/index.md on the remote: %v", err)
+	}
+	if content != "# Hello\n" {
+		t.Errorf("expected the later timestamp to win, got %q", content)
+	}
+
+	if len(st.audit) != 1 {
+		t.Fatalf("expected 1 audit record, got %d", len(st.audit))
+	}
+	want := []mongodb.AuditChange{
+		{Path: "docs/index.md", Operation: "update"},
+		{Path: "README.md", Operation: "delete"},
+	}
+	if fmt.Sprint(st.audit[0].Changes) != fmt.Sprint(want) {
+		t.Errorf("expected changes %v, got %v", want, st.audit[0].Changes)
+	}
+}
+
+func TestPushIntentFailsOnCaseCollision(t *testing.T) {
+	b, st, backend, intent := newPushTest(t, newTestConfig())
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "Docs/Index.md", Blob: []byte("# Hello\n")}
+	intent.Documents = []string{"1", "3"}
+
+	if err := b.processPushIntent(intent); !errors.Is(err, git.ErrCaseCollision) {
+		t.Fatalf("expected a case collision, got %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Errorf("expected nothing pushed, got %d commits", len(commits))
+	}
+}
+
+func TestBatchSummaryCountsCycleOutcomes(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.IgnorePaths = []string{"*.tmp"}
+	b, st, _, intent := newPushTest(t, cfg)
+
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "scratch.tmp", Blob: []byte("x")}
+	skipped := &mongodb.PushIntent{ID: "intent-skipped", Branch: "main", Documents: []string{"3"}}
+	failed := &mongodb.PushIntent{ID: "intent-failed", Branch: "main", Documents: []string{"missing"}}
+
+	if !b.enqueueBatch([]*mongodb.PushIntent{intent, skipped, failed}) {
+		t.Fatal("expected the batch to be enqueued")
+	}
+	close(b.workQueue)
+
+	b.wg.Add(2)
+	go b.dispatch()
+	go b.worker(0)
+	b.wg.Wait()
+
+	if len(st.summaries) != 1 {
+		t.Fatalf("expected one batch summary, got %d", len(st.summaries))
+	}
+
+	summary := st.summaries[0]
+	if summary.Intents != 3 || summary.Succeeded != 1 || summary.Failed != 1 || summary.Skipped != 1 {
+		t.Errorf("unexpected outcome counts %+v", summary)
+	}
+	if summary.DocumentsWritten != 2 {
+		t.Errorf("expected 2 documents written, got %d", summary.DocumentsWritten)
+	}
+	if summary.Workers != cfg.WorkerCount {
+		t.Errorf("expected worker count %d, got %d", cfg.WorkerCount, summary.Workers)
+	}
+	if summary.CompletedAt.Before(summary.StartedAt) || summary.DurationSeconds < 0 {
+		t.Errorf("unexpected timing %v -> %v (%vs)", summary.StartedAt, summary.CompletedAt, summary.DurationSeconds)
+	}
+}
+
+func TestRecheckOnCleanFindsChangeMissedByStaleRead(t *testing.T) {
+	for _, recheck := range []bool{false, true} {
+		cfg := newTestConfig()
+		cfg.RecheckOnClean = recheck
+		b, st, backend, intent := newPushTest(t, cfg)
+
+		// The first read still returns what the remote already has
+		st.documents["3"] = &mongodb.Document{ID: "3", Path: "README.md", Blob: []byte("site v2\n")}
+		st.stale = map[string]*mongodb.Document{
+			"3": {ID: "3", Path: "README.md", Blob: []byte("site\n")},
+		}
+		intent.Documents = []string{"3"}
+
+		if err := b.processPushIntent(intent); err != nil {
+			t.Fatalf("recheck=%v: processPushIntent failed: %v", recheck, err)
+		}
+
+		content, err := backend.File("tekfly/site", "main", "README.md")
+		if err != nil {
+			t.Fatalf("recheck=%v: failed to read README.md: %v", recheck, err)
+		}
+
+		want := "site\n"
+		if recheck {
+			want = "site v2\n"
+		}
+		if content != want {
+			t.Errorf("recheck=%v: expected remote README.md %q, got %q", recheck, want, content)
+		}
+	}
+}
+
+func TestCommitScopeReflectsPrefixedPaths(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.CommitScopeFormat = "feat({scope}): "
+	cfg.PathPrefixes = map[string]string{"site": "services/site"}
+	b, st, backend, intent := newPushTest(t, cfg)
+
+	// README.md is only deleted under the prefix, so create it there first
+	if err := backend.CreateRepository("tekfly/site", "main", map[string]string{
+		"services/site/README.md": "site\n",
+	}); err != nil {
+		t.Fatalf("failed to create remote: %v", err)
+	}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+	if err := st.processed[intent.ID]; err != nil {
+		t.Fatalf("expected the intent to succeed, got %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if got := commits[0].Message; got != "feat(services): Publish docs" {
+		t.Errorf("expected the scope of the routed path, got %q", got)
+	}
+}
+
+func TestPushCooldownSpacesSameBranchIntents(t *testing.T) {
+	b, st, _, first := newPushTest(t, newTestConfig())
+	b.pushCooldown = 300 * time.Millisecond
+
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "docs/next.md", Blob: []byte("next\n")}
+	second := &mongodb.PushIntent{ID: "intent-2", Branch: "main", Message: "Next", Documents: []string{"3"}}
+
+	if err := b.processPushIntent(first); err != nil {
+		t.Fatalf("first intent failed: %v", err)
+	}
+	pushed := time.Now()
+
+	if err := b.processPushIntent(second); err != nil {
+		t.Fatalf("second intent failed: %v", err)
+	}
+	if elapsed := time.Since(pushed); elapsed < b.pushCooldown {
+		t.Errorf("expected the second push to wait out the %v cooldown, took %v", b.pushCooldown, elapsed)
+	}
+
+	// Another branch is not held back
+	if err := b.waitForCooldown("release"); err != nil {
+		t.Errorf("expected no cooldown for another branch, got %v", err)
+	}
+
+	// Shutdown interrupts the wait and leaves the intent pending
+	b.recordBranchPush("main")
+	b.cancel()
+	third := &mongodb.PushIntent{ID: "intent-3", Branch: "main", Documents: []string{"3"}}
+	if err := b.processPushIntent(third); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cooldown to end with the context, got %v", err)
+	}
+	if _, ok := st.processed[third.ID]; ok {
+		t.Error("expected an interrupted intent to stay pending")
+	}
+}
+
+func TestIdentityMapSetsCommitterForMatchingBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		wantName string
+		wantMail string
+	}{
+		{"matching branch", "ma*", "Team A Bot", "team-a@tekfly.io"},
+		{"fallback", "team-a/*", "Virtual DOM Bot", "bot@tekfly.io"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			cfg.IdentityMap = []config.Identity{{Branch: tt.pattern, Name: "Team A Bot", Email: "team-a@tekfly.io"}}
+			b, st, backend, intent := newPushTest(t, cfg)
+
+			if err := b.processPushIntent(intent); err != nil {
+				t.Fatalf("processPushIntent failed: %v", err)
+			}
+
+			commits, err := backend.Commits("tekfly/site", "main")
+			if err != nil {
+				t.Fatalf("failed to read remote commits: %v", err)
+			}
+			head := commits[0]
+			if head.Committer.Name != tt.wantName || head.Committer.Email != tt.wantMail {
+				t.Errorf("unexpected committer %s <%s>", head.Committer.Name, head.Committer.Email)
+			}
+
+			want := fmt.Sprintf("%s <%s>", tt.wantName, tt.wantMail)
+			if len(st.audit) != 1 || st.audit[0].Committer != want {
+				t.Errorf("expected audit committer %q, got %+v", want, st.audit)
+			}
+		})
+	}
+}
+
+func TestIdentityMapCommitsOnBehalfOfIntentAuthor(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.IdentityMap = []config.Identity{{Branch: "main", Name: "Team A Bot", Email: "team-a@tekfly.io"}}
+	b, st, backend, intent := newPushTest(t, cfg)
+	intent.Author = "Alice <alice@tekfly.io>"
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	head := commits[0]
+	if head.Author.Name != "Alice" || head.Author.Email != "alice@tekfly.io" {
+		t.Errorf("unexpected author %s <%s>", head.Author.Name, head.Author.Email)
+	}
+	if head.Committer.Name != "Team A Bot" || head.Committer.Email != "team-a@tekfly.io" {
+		t.Errorf("unexpected committer %s <%s>", head.Committer.Name, head.Committer.Email)
+	}
+
+	if len(st.audit) != 1 || st.audit[0].Author != intent.Author || st.audit[0].Committer != "Team A Bot <team-a@tekfly.io>" {
+		t.Errorf("unexpected audit record %+v", st.audit)
+	}
+}
+
+func TestPushIntentTargetsDiscoveredRepo(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.GitHubOrganization = "tekfly"
+	b, st, backend, intent := newPushTest(t, cfg)
+	b.repoAllowlist = staticAllowlist{"site": true, "docs": true}
+	if err := backend.CreateRepository("tekfly/docs", "main", map[string]string{"README.md": "docs\n"}); err != nil {
+		t.Fatalf("failed to create remote: %v", err)
+	}
+	intent.Repo = "docs"
+
+	// A lock on the configured repo must not hold up a push to another one
+	st.repoLocks["tekfly/site@main"] = mongodb.RepoLock{Owner: "other-worker", ExpiresAt: time.Now().Add(time.Hour)}
+
+	result := make(chan error, 1)
+	go func() { result <- b.processPushIntent(intent) }()
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("processPushIntent failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		b.cancel()
+		t.Fatal("push to the discovered repo waited for the configured repo's lock")
+	}
+
+	docs, err := backend.Commits("tekfly/docs", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(docs) != 2 || docs[0].Message != "Publish docs" {
+		t.Errorf("expected the intent's commit on tekfly/docs, got %d commits", len(docs))
+	}
+
+	site, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(site) != 1 {
+		t.Errorf("expected tekfly/site untouched, got %d commits", len(site))
+	}
+}
+
+// diskFullBackend refuses clones for lack of disk space until full is cleared
+type diskFullBackend struct {
+	*gittest.MemoryBackend
+	full   atomic.Bool
+	clones atomic.Int32
+}
+
+func (f *diskFullBackend) Clone(ctx context.Context, opts git.CloneOptions, logger *logrus.Logger) (*git.Repository, error) {
+	f.clones.Add(1)
+	if f.full.Load() {
+		return nil, fmt.Errorf("%w: 0 bytes free", git.ErrInsufficientDisk)
+	}
+	return f.MemoryBackend.Clone(ctx, opts, logger)
+}
+
+func TestStreamedIntentDeferredForDiskIsPushedOnceFreed(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.EnableWebhooks = true
+	cfg.StreamSweepInterval = 1
+	b, st, memory, intent := newPushTest(t, cfg)
+	backend := &diskFullBackend{MemoryBackend: memory}
+	backend.full.Store(true)
+	b.gitBackend = backend
+
+	go b.Start()
+	defer b.Shutdown(context.Background())
+
+	event, err := bson.Marshal(bson.M{"fullDocument": intent})
+	if err != nil {
+		t.Fatalf("failed to encode event: %v", err)
+	}
+	if err := b.drainChangeStream(&fakeChangeStream{events: [][]byte{event}}); err != nil {
+		t.Fatalf("drainChangeStream failed: %v", err)
+	}
+
+	waitFor := func(what string, done func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", what)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	pushed := func() bool {
+		st.mu.Lock()
+		defer st.mu.Unlock()
+		_, ok := st.processed[intent.ID]
+		return ok
+	}
+
+	waitFor("the intent to be deferred", func() bool { return backend.clones.Load() > 0 })
+	if pushed() {
+		t.Fatal("expected the intent to stay pending while the disk is full")
+	}
+
+	backend.full.Store(false)
+	waitFor("the deferred intent to be pushed", pushed)
+
+	commits, err := memory.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Errorf("expected the intent's commit on the remote, got %d commits", len(commits))
+	}
+}
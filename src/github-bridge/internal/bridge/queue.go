@@ -0,0 +1,102 @@
+package bridge
+
+import (
+	"container/heap"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// queuedIntent is a pending intent with its arrival order, which breaks
+// ties so equal intents keep their FIFO order
+type queuedIntent struct {
+	intent *mongodb.PushIntent
+	seq    uint64
+}
+
+// intentHeap orders intents by priority (highest first), then timestamp
+// (oldest first), then arrival. It implements heap.Interface.
+type intentHeap struct {
+	items []queuedIntent
+	seq   uint64
+}
+
+func (h *intentHeap) Len() int { return len(h.items) }
+
+func (h *intentHeap) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if a.intent.Priority != b.intent.Priority {
+		return a.intent.Priority > b.intent.Priority
+	}
+	if !a.intent.Timestamp.Equal(b.intent.Timestamp) {
+		return a.intent.Timestamp.Before(b.intent.Timestamp)
+	}
+	return a.seq < b.seq
+}
+
+func (h *intentHeap) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *intentHeap) Push(x interface{}) { h.items = append(h.items, x.(queuedIntent)) }
+
+func (h *intentHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func (h *intentHeap) push(intent *mongodb.PushIntent) {
+	h.seq++
+	heap.Push(h, queuedIntent{intent: intent, seq: h.seq})
+}
+
+func (h *intentHeap) peek() *mongodb.PushIntent {
+	return h.items[0].intent
+}
+
+func (h *intentHeap) pop() *mongodb.PushIntent {
+	return heap.Pop(h).(queuedIntent).intent
+}
+
+// dispatch moves intents from the producers' work queue to the workers,
+// always handing out the highest priority intent held. It holds at most a
+// batch so producers still block when the workers fall behind. Once the work
+// queue is closed the held intents are handed out before the workers'
+// channel is closed.
+func (b *Bridge) dispatch() {
+	defer b.wg.Done()
+	defer close(b.ready)
+
+	var pending intentHeap
+	incoming := b.workQueue
+	limit := cap(b.workQueue)
+	if limit < 1 {
+		limit = 1
+	}
+
+	for incoming != nil || pending.Len() > 0 {
+		var (
+			in   chan *mongodb.PushIntent
+			out  chan *mongodb.PushIntent
+			next *mongodb.PushIntent
+		)
+		if pending.Len() < limit {
+			in = incoming
+		}
+		if pending.Len() > 0 {
+			out = b.ready
+			next = pending.peek()
+		}
+
+		select {
+		case intent, ok := <-in:
+			if !ok {
+				incoming = nil
+				continue
+			}
+			pending.push(intent)
+		case out <- next:
+			pending.pop()
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
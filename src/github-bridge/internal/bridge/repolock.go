@@ -0,0 +1,99 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// repoLockRetryBase and repoLockRetryMax bound the backoff between attempts
+// to take a repo lock held by another worker
+var (
+	repoLockRetryBase = 250 * time.Millisecond
+	repoLockRetryMax  = 5 * time.Second
+)
+
+// repoLockReleaseTimeout bounds releasing a lock, which also runs during
+// shutdown after the bridge context is cancelled
+const repoLockReleaseTimeout = 10 * time.Second
+
+// lockOwner identifies this process in repo locks
+func lockOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// lockRepo takes the advisory lock on repo and branch shared by every
+// replica, backing off while another worker holds it. The lock is renewed
+// until the returned release is called, so a push outlasting REPO_LOCK_TTL
+// keeps it; a crashed worker's lock expires after the TTL.
+func (b *Bridge) lockRepo(repo, branch, intentID string) (func(), error) {
+	if b.repoLockTTL <= 0 {
+		return func() {}, nil
+	}
+
+	owner := b.lockOwner + "/" + intentID
+	fields := logrus.Fields{"repo": repo, "branch": branch, "intent_id": intentID}
+
+	delay := repoLockRetryBase
+	for {
+		acquired, err := b.mongo.AcquireRepoLock(b.workCtx, repo, branch, owner, b.repoLockTTL)
+		if err != nil {
+			b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+			return nil, err
+		}
+		if acquired {
+			break
+		}
+
+		b.logger.WithFields(fields).WithField("delay", delay).Debug("Repo lock held by another worker, retrying")
+		select {
+		case <-time.After(delay):
+		case <-b.ctx.Done():
+			return nil, fmt.Errorf("waiting for lock on %s@%s interrupted: %w", repo, branch, b.ctx.Err())
+		}
+		if delay *= 2; delay > repoLockRetryMax {
+			delay = repoLockRetryMax
+		}
+	}
+
+	done := make(chan struct{})
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		ticker := time.NewTicker(b.repoLockTTL / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				held, err := b.mongo.AcquireRepoLock(b.workCtx, repo, branch, owner, b.repoLockTTL)
+				if err != nil || !held {
+					b.logger.WithError(err).WithFields(fields).Warn("Failed to renew repo lock")
+				}
+			case <-done:
+				return
+			case <-b.workCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-renewed
+
+		ctx, cancel := context.WithTimeout(context.Background(), repoLockReleaseTimeout)
+		defer cancel()
+		if err := b.mongo.ReleaseRepoLock(ctx, repo, branch, owner); err != nil {
+			b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+			b.logger.WithError(err).WithFields(fields).Warn("Failed to release repo lock, it expires after REPO_LOCK_TTL")
+		}
+	}, nil
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRepoLockIsMutuallyExclusive(t *testing.T) {
+	defer func(base time.Duration) { repoLockRetryBase = base }(repoLockRetryBase)
+	repoLockRetryBase = time.Millisecond
+
+	st := newFakeStore()
+	cfg := newTestConfig()
+	cfg.RepoLockTTL = 60
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	var (
+		holders  atomic.Int32
+		overlaps atomic.Int32
+		acquired atomic.Int32
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				release, err := b.lockRepo("tekfly/site", "main", fmt.Sprintf("intent-%d-%d", worker, j))
+				if err != nil {
+					t.Errorf("lockRepo failed: %v", err)
+					return
+				}
+
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				acquired.Add(1)
+				time.Sleep(time.Millisecond)
+				holders.Add(-1)
+
+				release()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := overlaps.Load(); got != 0 {
+		t.Errorf("expected one holder of the lock at a time, saw %d overlaps", got)
+	}
+	if got := acquired.Load(); got != 20 {
+		t.Errorf("expected every acquisition to succeed eventually, got %d", got)
+	}
+	if len(st.repoLocks) != 0 {
+		t.Errorf("expected every lock released, got %+v", st.repoLocks)
+	}
+}
+
+func TestRepoLockWaitRespectsContext(t *testing.T) {
+	st := newFakeStore()
+	cfg := newTestConfig()
+	cfg.RepoLockTTL = 60
+	b := newBridge(context.Background(), cfg, st, newTestMetrics(), newTestLogger())
+
+	// Another replica holds the lock on main only
+	if ok, _ := st.AcquireRepoLock(context.Background(), "tekfly/site", "main", "other-replica", time.Minute); !ok {
+		t.Fatal("failed to take the lock for another replica")
+	}
+
+	release, err := b.lockRepo("tekfly/site", "release", "intent-1")
+	if err != nil {
+		t.Fatalf("expected a different branch to be lockable, got %v", err)
+	}
+	release()
+
+	time.AfterFunc(20*time.Millisecond, b.cancel)
+	if _, err := b.lockRepo("tekfly/site", "main", "intent-2"); err == nil {
+		t.Fatal("expected waiting for a held lock to stop on shutdown")
+	}
+	if st.repoLocks["tekfly/site@main"].Owner != "other-replica" {
+		t.Errorf("expected the other replica to keep its lock, got %+v", st.repoLocks)
+	}
+}
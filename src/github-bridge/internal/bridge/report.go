@@ -0,0 +1,159 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// ReportVersion is the version of the Report format. It changes only when a
+// field is removed or changes meaning; new fields may be added within one.
+const ReportVersion = 1
+
+// Report is the machine-readable result of a once-mode run
+type Report struct {
+	Version   int `json:"version"`
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Skipped   int `json:"skipped"`
+
+	// Failures and Commits are in the order intents were processed
+	Failures []ReportFailure `json:"failures"`
+	Commits  []ReportCommit  `json:"commits"`
+}
+
+// ReportFailure is an intent that failed and why
+type ReportFailure struct {
+	IntentID string `json:"intent_id"`
+	Error    string `json:"error"`
+}
+
+// ReportCommit is the last commit pushed for an intent
+type ReportCommit struct {
+	IntentID string `json:"intent_id"`
+	Commit   string `json:"commit"`
+}
+
+// Write encodes the report as a single line of JSON
+func (r *Report) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// reportResult is the recorded outcome of one intent
+type reportResult struct {
+	outcome intentOutcome
+	commit  string
+	err     error
+}
+
+// reportRecorder collects intent outcomes for a Report. A nil recorder
+// ignores them, so outcomes can be recorded unconditionally.
+type reportRecorder struct {
+	mu      sync.Mutex
+	order   []string
+	results map[string]reportResult
+}
+
+func newReportRecorder() *reportRecorder {
+	return &reportRecorder{results: make(map[string]reportResult)}
+}
+
+// record sets an intent's outcome, replacing one recorded earlier, as when
+// a panic follows the intent's own bookkeeping
+func (r *reportRecorder) record(intentID string, outcome intentOutcome, commit string, err error) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.results[intentID]; !ok {
+		r.order = append(r.order, intentID)
+	}
+	r.results[intentID] = reportResult{outcome: outcome, commit: commit, err: err}
+}
+
+// report builds the Report of everything recorded so far
+func (r *reportRecorder) report() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &Report{
+		Version:  ReportVersion,
+		Failures: []ReportFailure{},
+		Commits:  []ReportCommit{},
+	}
+	for _, id := range r.order {
+		result := r.results[id]
+		report.Total++
+		switch result.outcome {
+		case outcomeSucceeded:
+			report.Succeeded++
+		case outcomeSkipped:
+			report.Skipped++
+		default:
+			report.Failed++
+			failure := ReportFailure{IntentID: id}
+			if result.err != nil {
+				failure.Error = result.err.Error()
+			}
+			report.Failures = append(report.Failures, failure)
+		}
+		if result.commit != "" {
+			report.Commits = append(report.Commits, ReportCommit{IntentID: id, Commit: result.commit})
+		}
+	}
+	return report
+}
+
+// RunOnce processes pending intents one at a time until a fetch returns only
+// intents it has already attempted, then reports their outcomes. Intents
+// left pending, such as those deferred for disk space, are attempted once.
+// Call it instead of Start, then Shutdown.
+func (b *Bridge) RunOnce() (*Report, error) {
+	b.report = newReportRecorder()
+	attempted := make(map[string]bool)
+
+	for b.ctx.Err() == nil {
+		intents, err := b.mongo.GetPendingPushIntents(b.ctx, b.config.BatchSize)
+		if err != nil {
+			b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+			return b.report.report(), fmt.Errorf("failed to fetch push intents: %w", err)
+		}
+
+		var fresh []*mongodb.PushIntent
+		for _, intent := range intents {
+			if !attempted[intent.ID] {
+				attempted[intent.ID] = true
+				fresh = append(fresh, intent)
+			}
+		}
+		if len(fresh) == 0 {
+			break
+		}
+
+		b.cycles.start(fresh, 1)
+		for _, intent := range fresh {
+			b.metrics.QueueSize.Inc()
+			b.queued.Add(1)
+			if err := b.handleIntent(0, intent); err != nil {
+				b.logger.WithError(err).WithField("intent_id", intent.ID).Error("Failed to process push intent")
+				b.metrics.ErrorsByType.WithLabelValues("processing").Inc()
+			}
+		}
+	}
+
+	report := b.report.report()
+	b.logger.WithFields(logrus.Fields{
+		"total":     report.Total,
+		"succeeded": report.Succeeded,
+		"failed":    report.Failed,
+		"skipped":   report.Skipped,
+	}).Info("Once-mode run complete")
+	return report, b.ctx.Err()
+}
@@ -0,0 +1,79 @@
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+func TestRunOnceReportsIntentOutcomes(t *testing.T) {
+	b, st, backend, pushed := newPushTest(t, newTestConfig())
+
+	// One intent fails without documents, another rewrites what the first
+	// already pushed and is skipped
+	start := time.Now()
+	pushed.Timestamp = start
+	st.documents["3"] = &mongodb.Document{ID: "3", Path: "docs/index.md", Blob: []byte("# Hello\n")}
+	st.intents = append(st.intents,
+		&mongodb.PushIntent{ID: "intent-2", Repo: "site", Branch: "main", Message: "Broken", Documents: []string{"missing"}, Timestamp: start.Add(time.Second)},
+		&mongodb.PushIntent{ID: "intent-3", Repo: "site", Branch: "main", Message: "Same again", Documents: []string{"3"}, Timestamp: start.Add(2 * time.Second)},
+	)
+
+	report, err := b.RunOnce()
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := report.Write(&out); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	var parsed Report
+	if err := json.Unmarshal(out.Bytes(), &parsed); err != nil {
+		t.Fatalf("report is not valid JSON: %v\n%s", err, out.String())
+	}
+
+	if parsed.Version != ReportVersion {
+		t.Errorf("expected report version %d, got %d", ReportVersion, parsed.Version)
+	}
+	if parsed.Total != 3 || parsed.Succeeded != 1 || parsed.Failed != 1 || parsed.Skipped != 1 {
+		t.Errorf("expected 3 intents: 1 succeeded, 1 failed, 1 skipped; got %+v", parsed)
+	}
+
+	if len(parsed.Failures) != 1 || parsed.Failures[0].IntentID != "intent-2" ||
+		!strings.Contains(parsed.Failures[0].Error, "no documents found") {
+		t.Errorf("expected intent-2 reported as failed, got %+v", parsed.Failures)
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	want := ReportCommit{IntentID: "intent-1", Commit: commits[0].Hash.String()}
+	if len(parsed.Commits) != 1 || parsed.Commits[0] != want {
+		t.Errorf("expected commits %+v, got %+v", want, parsed.Commits)
+	}
+
+	// Every intent was marked, so nothing is left for another run
+	for _, id := range []string{"intent-1", "intent-2", "intent-3"} {
+		if _, ok := st.processed[id]; !ok {
+			t.Errorf("expected %s to be marked processed", id)
+		}
+	}
+}
+
+func TestEmptyReportListsAreNotNull(t *testing.T) {
+	var out bytes.Buffer
+	if err := newReportRecorder().report().Write(&out); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+
+	want := `{"version":1,"total":0,"succeeded":0,"failed":0,"skipped":0,"failures":[],"commits":[]}` + "\n"
+	if out.String() != want {
+		t.Errorf("expected %s, got %s", want, out.String())
+	}
+}
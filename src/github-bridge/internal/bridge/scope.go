@@ -0,0 +1,41 @@
+package bridge
+
+import (
+	"strings"
+)
+
+// scopePlaceholder is replaced by the derived scope in COMMIT_SCOPE_FORMAT
+const scopePlaceholder = "{scope}"
+
+// commitScope returns the top-level directory shared by every repository
+// path, or "" when the paths span several or include a root-level file
+func commitScope(paths []string) string {
+	scope := ""
+	for _, p := range paths {
+		top, _, nested := strings.Cut(p, "/")
+		if !nested || (scope != "" && top != scope) {
+			return ""
+		}
+		scope = top
+	}
+	return scope
+}
+
+// scopeMessage prefixes the subject line of message with format, with the
+// scope derived from paths substituted for {scope}. Without a single scope
+// "({scope})" is dropped, so "feat({scope}): " becomes "feat: ".
+func scopeMessage(format, message string, paths []string) string {
+	if format == "" {
+		return message
+	}
+
+	prefix := format
+	if scope := commitScope(paths); scope != "" {
+		prefix = strings.ReplaceAll(prefix, scopePlaceholder, scope)
+	} else {
+		prefix = strings.ReplaceAll(prefix, "("+scopePlaceholder+")", "")
+		prefix = strings.ReplaceAll(prefix, scopePlaceholder, "")
+	}
+
+	return prefix + message
+}
@@ -0,0 +1,26 @@
+package bridge
+
+import "testing"
+
+func TestScopeMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		paths  []string
+		want   string
+	}{
+		{"single scope", "feat({scope}): ", []string{"services/api/main.go", "services/web/index.html"}, "feat(services): Publish docs"},
+		{"multiple scopes", "feat({scope}): ", []string{"services/api/main.go", "docs/index.md"}, "feat: Publish docs"},
+		{"root file", "feat({scope}): ", []string{"services/api/main.go", "README.md"}, "feat: Publish docs"},
+		{"bare placeholder", "[{scope}] ", []string{"docs/a.md"}, "[docs] Publish docs"},
+		{"disabled", "", []string{"docs/a.md"}, "Publish docs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := scopeMessage(tt.format, "Publish docs", tt.paths); got != tt.want {
+				t.Errorf("scopeMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
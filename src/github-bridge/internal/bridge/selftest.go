@@ -0,0 +1,77 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+)
+
+// selftestMarker is the file the startup self-test overwrites on every run
+const selftestMarker = ".vdom-healthcheck/selftest"
+
+// SelfTest runs a full clone, commit and push cycle against the self-test
+// repository and branch, overwriting a timestamped marker file. It catches
+// token scope and branch protection problems before any intent is taken.
+func (b *Bridge) SelfTest(ctx context.Context) (err error) {
+	repoName, branch := b.config.SelftestTarget()
+	logger := b.logger.WithFields(logrus.Fields{"repo": repoName, "branch": branch})
+	logger.Info("Running startup self-test")
+
+	ctx, span := b.startSpan(ctx, "selftest")
+	defer func() { endSpan(span, err) }()
+
+	release, err := b.lockRepo(repoName, branch, "selftest")
+	if err != nil {
+		return fmt.Errorf("self-test: %w", err)
+	}
+	defer release()
+
+	tempDir := filepath.Join(os.TempDir(), "github-bridge")
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return fmt.Errorf("self-test: failed to create temp dir: %w", err)
+	}
+
+	repo, err := b.gitBackend.Clone(ctx, git.CloneOptions{
+		URL:              fmt.Sprintf("https://github.com/%s.git", repoName),
+		Branch:           branch,
+		Token:            b.config.GitHubToken,
+		TempDir:          tempDir,
+		RemoteName:       "origin",
+		NetRetries:       b.config.GitNetRetries,
+		Location:         b.commitLocation,
+		MinFreeDiskBytes: uint64(b.config.MinFreeDiskBytes),
+		SignKey:          b.signKey,
+		VerifySigner:     b.config.VerifySignerPerCommit,
+		Metrics:          b.metrics,
+	}, b.logger)
+	if err != nil {
+		return fmt.Errorf("self-test: failed to clone: %w", err)
+	}
+	defer repo.Cleanup()
+
+	now := time.Now().UTC()
+	marker := git.Document{
+		Path:      selftestMarker,
+		Content:   []byte(fmt.Sprintf("%s %s\n", now.Format(time.RFC3339Nano), b.lockOwner)),
+		Operation: "update",
+	}
+	if err := repo.ApplyDocuments([]git.Document{marker}); err != nil {
+		return fmt.Errorf("self-test: failed to write marker: %w", err)
+	}
+
+	hash, err := repo.CommitChanges("chore: bridge startup self-test", b.committer(branch))
+	if err != nil {
+		return fmt.Errorf("self-test: failed to commit: %w", err)
+	}
+	if err := repo.Push(ctx); err != nil {
+		return fmt.Errorf("self-test: failed to push: %w", err)
+	}
+
+	logger.WithField("commit", hash).Info("Startup self-test passed")
+	return nil
+}
@@ -0,0 +1,60 @@
+package bridge
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git/gittest"
+)
+
+func TestSelfTestPushesMarker(t *testing.T) {
+	b, _, backend, _ := newPushTest(t, newTestConfig())
+
+	for run := 1; run <= 2; run++ {
+		if err := b.SelfTest(context.Background()); err != nil {
+			t.Fatalf("self-test run %d failed: %v", run, err)
+		}
+	}
+
+	commits, err := backend.Commits("tekfly/site", "main")
+	if err != nil {
+		t.Fatalf("failed to read remote commits: %v", err)
+	}
+	if len(commits) != 3 {
+		t.Fatalf("expected a marker commit per run, got %d commits", len(commits))
+	}
+	if commits[0].Message != "chore: bridge startup self-test" {
+		t.Errorf("unexpected commit message %q", commits[0].Message)
+	}
+
+	// Later runs overwrite the marker instead of adding files
+	stats, err := commits[0].Stats()
+	if err != nil {
+		t.Fatalf("failed to read commit stats: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Name != selftestMarker {
+		t.Errorf("expected the second run to only change %s, got %v", selftestMarker, stats)
+	}
+	content, err := backend.File("tekfly/site", "main", selftestMarker)
+	if err != nil {
+		t.Fatalf("expected the marker on the remote: %v", err)
+	}
+	if !strings.Contains(content, b.lockOwner) {
+		t.Errorf("expected the marker to name this process, got %q", content)
+	}
+}
+
+func TestSelfTestFailsWithoutRepository(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.GitHubRepo = "tekfly/missing"
+	cfg.GitHubBranch = "main"
+	b := newBridge(context.Background(), cfg, newFakeStore(), newTestMetrics(), newTestLogger())
+	b.gitBackend = gittest.NewMemoryBackend()
+	t.Setenv("TMPDIR", t.TempDir())
+
+	err := b.SelfTest(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "self-test: failed to clone") {
+		t.Errorf("expected the self-test to fail on clone, got %v", err)
+	}
+}
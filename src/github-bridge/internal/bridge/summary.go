@@ -0,0 +1,130 @@
+package bridge
+
+import (
+	"sync"
+	"time"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// intentOutcome is how processing an intent ended, for batch summaries
+type intentOutcome int
+
+const (
+	outcomeFailed intentOutcome = iota
+	outcomeSucceeded
+	outcomeSkipped
+)
+
+func (o intentOutcome) String() string {
+	switch o {
+	case outcomeSucceeded:
+		return "succeeded"
+	case outcomeSkipped:
+		return "skipped"
+	default:
+		return "failed"
+	}
+}
+
+// cycle accumulates the outcomes of the intents enqueued together by one
+// poll or change stream batch
+type cycle struct {
+	remaining int
+	summary   mongodb.BatchSummary
+}
+
+// cycleTracker maps in-flight intents to their cycle
+type cycleTracker struct {
+	mu       sync.Mutex
+	byIntent map[string]*cycle
+}
+
+// start opens a cycle for intents. Intents already in flight for an earlier
+// cycle, such as those fetched again by a later poll, stay with that cycle.
+func (t *cycleTracker) start(intents []*mongodb.PushIntent, workers int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.byIntent == nil {
+		t.byIntent = make(map[string]*cycle)
+	}
+
+	c := &cycle{summary: mongodb.BatchSummary{StartedAt: time.Now(), Workers: workers}}
+	for _, intent := range intents {
+		if _, ok := t.byIntent[intent.ID]; ok {
+			continue
+		}
+		t.byIntent[intent.ID] = c
+		c.remaining++
+	}
+}
+
+// drop forgets intents that were never handed to the workers
+func (t *cycleTracker) drop(intents []*mongodb.PushIntent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, intent := range intents {
+		if c, ok := t.byIntent[intent.ID]; ok {
+			c.remaining--
+			delete(t.byIntent, intent.ID)
+		}
+	}
+}
+
+// addDocuments counts documents written for an in-flight intent
+func (t *cycleTracker) addDocuments(intentID string, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if c, ok := t.byIntent[intentID]; ok {
+		c.summary.DocumentsWritten += n
+	}
+}
+
+// finish records an intent's outcome, returning its cycle's summary once
+// every intent of the cycle has finished
+func (t *cycleTracker) finish(intentID string, outcome intentOutcome) *mongodb.BatchSummary {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	c, ok := t.byIntent[intentID]
+	if !ok {
+		return nil
+	}
+	delete(t.byIntent, intentID)
+
+	c.summary.Intents++
+	switch outcome {
+	case outcomeSucceeded:
+		c.summary.Succeeded++
+	case outcomeSkipped:
+		c.summary.Skipped++
+	default:
+		c.summary.Failed++
+	}
+
+	c.remaining--
+	if c.remaining > 0 {
+		return nil
+	}
+
+	c.summary.CompletedAt = time.Now()
+	c.summary.DurationSeconds = c.summary.CompletedAt.Sub(c.summary.StartedAt).Seconds()
+	return &c.summary
+}
+
+// finishIntent records an intent's outcome and writes its cycle's summary
+// when it was the last one. Failures are logged and never fail the intent.
+func (b *Bridge) finishIntent(intentID string, outcome intentOutcome) {
+	summary := b.cycles.finish(intentID, outcome)
+	if summary == nil {
+		return
+	}
+
+	if err := b.mongo.InsertBatchSummary(b.workCtx, summary); err != nil {
+		b.logger.WithError(err).Warn("Failed to write batch summary")
+		b.metrics.ErrorsByType.WithLabelValues("mongodb").Inc()
+	}
+}
@@ -0,0 +1,56 @@
+package bridge
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// tracerName identifies the bridge's instrumentation
+const tracerName = "github.com/tekfly/virtual-dom-gateway/github-bridge/internal/bridge"
+
+// intentTraceKeys are the metadata fields a producer sets to continue its
+// trace in the bridge
+var intentTraceKeys = []string{"traceparent", "tracestate"}
+
+// intentTraceContext returns ctx carrying the trace context the producer
+// recorded in the intent's metadata, if any, so the intent's spans join the
+// producer's trace
+func intentTraceContext(ctx context.Context, intent *mongodb.PushIntent) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, key := range intentTraceKeys {
+		if value, ok := intent.Metadata[key].(string); ok {
+			carrier[key] = value
+		}
+	}
+	return propagation.TraceContext{}.Extract(ctx, carrier)
+}
+
+// intentAttributes describe the intent a span belongs to
+func intentAttributes(intent *mongodb.PushIntent) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("intent.id", intent.ID),
+		attribute.String("repo", intent.Repo),
+		attribute.String("branch", intent.Branch),
+		attribute.Int("documents", len(intent.Documents)),
+	}
+}
+
+// startSpan starts a span named name as a child of the span in ctx
+func (b *Bridge) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return b.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan marks span as failed when err is set, then ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
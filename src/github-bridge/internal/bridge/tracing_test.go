@@ -0,0 +1,144 @@
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/mongodb"
+)
+
+// producerTraceparent is the trace context a producer leaves on an intent
+const producerTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// traceBridge records b's spans in memory
+func traceBridge(t *testing.T, b *Bridge) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	b.tracer = provider.Tracer(tracerName)
+	return exporter
+}
+
+// spanChildren returns the names of the recorded children of each span name
+func spanChildren(spans tracetest.SpanStubs) map[string][]string {
+	names := make(map[trace.SpanID]string, len(spans))
+	for _, span := range spans {
+		names[span.SpanContext.SpanID()] = span.Name
+	}
+
+	children := make(map[string][]string)
+	for _, span := range spans {
+		if parent, ok := names[span.Parent.SpanID()]; ok {
+			children[parent] = append(children[parent], span.Name)
+		}
+	}
+	for _, names := range children {
+		sort.Strings(names)
+	}
+	return children
+}
+
+func TestPushIntentSpanTree(t *testing.T) {
+	b, _, _, intent := newPushTest(t, newTestConfig())
+	exporter := traceBridge(t, b)
+	intent.Metadata = map[string]interface{}{"traceparent": producerTraceparent}
+
+	if err := b.processPushIntent(intent); err != nil {
+		t.Fatalf("processPushIntent failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	var root *tracetest.SpanStub
+	for i := range spans {
+		if spans[i].Name == "push_intent" {
+			root = &spans[i]
+		}
+		if got := spans[i].SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("expected span %s in the producer's trace, got %s", spans[i].Name, got)
+		}
+	}
+	if root == nil {
+		t.Fatal("expected a push_intent span")
+	}
+
+	// The root span continues the producer's span
+	if !root.Parent.IsRemote() || root.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the producer's span as parent, got %v", root.Parent)
+	}
+	want := map[attribute.Key]attribute.Value{
+		"intent.id": attribute.StringValue("intent-1"),
+		"repo":      attribute.StringValue("site"),
+		"branch":    attribute.StringValue("main"),
+		"documents": attribute.IntValue(2),
+		"outcome":   attribute.StringValue("succeeded"),
+	}
+	for _, kv := range root.Attributes {
+		if v, ok := want[kv.Key]; ok {
+			if kv.Value != v {
+				t.Errorf("expected %s=%v, got %v", kv.Key, v.Emit(), kv.Value.Emit())
+			}
+			delete(want, kv.Key)
+		}
+	}
+	if len(want) > 0 {
+		t.Errorf("missing root span attributes %v", want)
+	}
+
+	children := spanChildren(spans)
+	expected := map[string][]string{
+		"push_intent": {"apply", "clone", "commit", "mongo.get_pending_mark", "mongo.mark_processed", "push"},
+		"clone":       {"pull"},
+		"apply":       {"mongo.stream_documents"},
+	}
+	for parent, names := range expected {
+		if got := children[parent]; fmt.Sprint(got) != fmt.Sprint(names) {
+			t.Errorf("expected %s to have children %v, got %v", parent, names, got)
+		}
+	}
+}
+
+func TestPushIntentSpanRecordsFailure(t *testing.T) {
+	b, st, _, intent := newPushTest(t, newTestConfig())
+	exporter := traceBridge(t, b)
+	intent.Documents = []string{"missing"}
+	delete(st.documents, "1")
+
+	if err := b.processPushIntent(intent); err == nil {
+		t.Fatal("expected processing to fail")
+	}
+
+	for _, span := range exporter.GetSpans() {
+		if span.Name != "push_intent" {
+			continue
+		}
+		if span.Status.Code != codes.Error {
+			t.Errorf("expected the root span to record the failure, got %v", span.Status)
+		}
+		// Without a producer trace the intent starts its own
+		if span.Parent.IsValid() {
+			t.Errorf("expected a new trace, got parent %v", span.Parent)
+		}
+		return
+	}
+	t.Fatal("expected a push_intent span")
+}
+
+func TestIntentTraceContextIgnoresInvalidTraceparent(t *testing.T) {
+	intent := &mongodb.PushIntent{Metadata: map[string]interface{}{"traceparent": "not-a-trace"}}
+
+	ctx := intentTraceContext(context.Background(), intent)
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("expected an invalid traceparent to be ignored")
+	}
+}
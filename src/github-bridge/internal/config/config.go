@@ -1,10 +1,13 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the configuration for the GitHub Bridge
@@ -13,31 +16,276 @@ type Config struct {
 	MongoDBURI      string
 	MongoDBDatabase string
 
+	MongoDBMaxPoolSize            int
+	MongoDBConnectTimeout         int // seconds
+	MongoDBServerSelectionTimeout int // seconds
+
+	// DocFetchChunkSize caps the document IDs in a single $in query
+	DocFetchChunkSize int
+
+	// TLS for MongoDB behind a private CA or requiring client certificates
+	MongoDBCAFile     string
+	MongoDBClientCert string
+	MongoDBClientKey  string
+
 	// GitHub configuration
 	GitHubToken        string
 	GitHubOrganization string
 	GitHubRepo         string
 	GitHubBranch       string
-	
+
+	// TLS for GitHub Enterprise behind a private CA or requiring client
+	// certificates
+	GitHubCAFile     string
+	GitHubClientCert string
+	GitHubClientKey  string
+
+	// RepoDiscoveryPattern enables discovery of GitHubOrganization's
+	// repositories whose names match this glob; only discovered repositories
+	// are accepted as intent repos, and intents push to their own repo
+	RepoDiscoveryPattern  string
+	RepoDiscoveryInterval int // seconds
+
 	// Git configuration
-	GitUserName  string
-	GitUserEmail string
-	
+	GitUserName   string
+	GitUserEmail  string
+	GitNetRetries int
+
+	// Git HTTP transport tuning, shared by every clone, fetch and push
+	GitHTTPMaxIdleConnsPerHost int
+	GitHTTPIdleConnTimeout     int // seconds
+	GitHTTPKeepAlive           int // seconds
+	GitHTTP2                   bool
+
+	// CommitTimezone is the IANA zone used for commit and tag signatures
+	CommitTimezone string
+
 	// Bridge configuration
-	PollInterval   int // seconds
-	BatchSize      int
-	WorkerCount    int
-	MetricsPort    int
-	
+	PollInterval int // seconds
+	BatchSize    int
+	WorkerCount  int
+	MetricsPort  int
+
+	// MaxWorkers enables autoscaling: between MinWorkers and MaxWorkers a
+	// worker is added per backlog check while more intents than the high
+	// watermark are pending, and an idle one retired while fewer than the
+	// low watermark are
+	MinWorkers             int
+	MaxWorkers             int
+	AutoscaleHighWatermark int
+	AutoscaleLowWatermark  int
+
+	BacklogCheckInterval int // seconds
+
+	// PushCooldown delays intents for a branch this long after it was pushed
+	PushCooldown int // seconds
+
+	// RepoLockTTL is how long a worker's lock on the target branch lasts
+	// without renewal; the lock is shared by every replica. Zero disables it.
+	RepoLockTTL int // seconds
+
+	// PendingMarkRetryInterval is how often failed marks are retried
+	PendingMarkRetryInterval int // seconds
+
+	// ReplaySince starts the change stream at this time so historical
+	// inserts are processed again; zero streams live only
+	ReplaySince time.Time
+
+	// StreamSweepInterval is how often the change stream mode also fetches
+	// pending intents, picking up those deferred after the stream delivered
+	// them
+	StreamSweepInterval int // seconds
+
+	// MaxDocsPerIntent rejects intents referencing more documents; 0 disables
+	MaxDocsPerIntent int
+
 	// Security
-	EnableSigning  bool
-	GPGKeyPath     string
-	
+	EnableSigning bool
+	GPGKeyPath    string
+	AdminToken    string // bearer token for the HTTP API
+
+	// VerifySignerPerCommit rechecks the signing key identity against the
+	// author email of every commit, not only GitUserEmail at startup
+	VerifySignerPerCommit bool
+
 	// Feature flags
 	DryRun         bool
 	EnableWebhooks bool
+
+	// DryRunOutput is where dry runs record the changes they would push:
+	// "mongodb" for the dry_run_results collection, or a directory. Dry
+	// runs only log when it is empty.
+	DryRunOutput string
+
+	// PathPrefix is prepended to every document path; PathPrefixes overrides
+	// it per intent repo for monorepo routing
+	PathPrefix   string
+	PathPrefixes map[string]string
+
+	// PathCase is "preserve" or "lower"; lower normalizes document paths for
+	// consumers on case-insensitive filesystems
+	PathCase string
+
+	// MinFreeDiskBytes refuses new clones below this much free work dir space
+	MinFreeDiskBytes int64
+
+	// DocumentTypes restricts processing to documents of these types
+	DocumentTypes []string
+
+	// IgnorePaths are gitignore-style patterns for document paths that are
+	// never written
+	IgnorePaths []string
+
+	// RecheckOnClean re-reads an intent's documents once when they leave the
+	// worktree clean, so a stale read is not finalized as a no-op
+	RecheckOnClean bool
+
+	// KeepEmptyDirs writes a .gitkeep into directories emptied by deletes
+	KeepEmptyDirs bool
+
+	// StrictOperations fails documents whose operation disagrees with the
+	// repository: creating an existing path, updating or deleting a missing one
+	StrictOperations bool
+
+	// SubmoduleMode is "ignore" or "error" for documents whose path falls
+	// inside a submodule of the target repository; ignore skips them
+	SubmoduleMode string
+
+	// SyncWrites fsyncs document files before renaming them into place
+	SyncWrites bool
+
+	// ApplyMode is "worktree" or "tree"; tree builds each intent's commit
+	// in memory without writing documents to disk, falling back to the
+	// worktree for per-document commits and KEEP_EMPTY_DIRS
+	ApplyMode string
+
+	// AllowBinary accepts documents whose content is detected as binary;
+	// when false they fail the intent
+	AllowBinary bool
+
+	// Transformers names the content transformers to run, in order
+	Transformers         []string
+	ContentSubstitutions []Substitution
+	RedactPatterns       []string
+
+	// IncludeFileManifest appends changed paths to commit messages, listing
+	// at most ManifestMaxFiles of them
+	IncludeFileManifest bool
+	ManifestMaxFiles    int
+
+	// CommitScopeFormat prefixes commit subjects, with {scope} replaced by
+	// the top-level directory shared by every changed path, e.g.
+	// "feat({scope}): "; empty leaves messages unchanged
+	CommitScopeFormat string
+
+	// CommitSubjectMaxLength and CommitBodyMaxLength cap commit messages in
+	// runes; longer subjects are shortened with an ellipsis and the overflow
+	// moved to the body. Zero disables a limit.
+	CommitSubjectMaxLength int
+	CommitBodyMaxLength    int
+
+	// TagExistsPolicy is "skip" or "error" when an intent's tag already exists
+	TagExistsPolicy string
+
+	// OnceReport prints a JSON report of the intents processed to stdout
+	// when running with -once
+	OnceReport bool
+
+	// AmendBranches are path.Match patterns for branches, such as generated
+	// PR branches, where an intent amends the tip instead of adding a commit
+	// when the tip is the bot's own commit
+	AmendBranches []string
+
+	// TracingEndpoint is the OTLP endpoint spans are exported to; tracing
+	// is disabled when it is empty
+	TracingEndpoint string
+
+	// PostPushHook is an executable or http(s) URL invoked after each
+	// successful push, given PostPushHookTimeout to finish
+	PostPushHook        string
+	PostPushHookTimeout int // seconds
+
+	// ShutdownTimeout bounds how long shutdown waits for in-flight intents
+	// before cancelling their git and MongoDB operations
+	ShutdownTimeout int // seconds
+
+	// IdentityMap picks the committer identity by target branch; the first
+	// entry whose pattern matches wins, and GitUserName and GitUserEmail
+	// apply when none does
+	IdentityMap []Identity
+
+	// AllowedBranches are path.Match patterns an intent's branch must match
+	// to be processed; any branch is allowed when it is empty
+	AllowedBranches []string
+
+	// StartupSelftest pushes a marker commit to SelftestBranch of
+	// SelftestRepo on startup, refusing to start if it fails. They default
+	// to the target repository and branch.
+	StartupSelftest bool
+	SelftestRepo    string
+	SelftestBranch  string
+
+	// CommitGranularity is either "intent" (one commit per intent) or
+	// "document" (one commit per document)
+	CommitGranularity string
+
+	// CacheClones keeps each repository, branch and path prefix cloned
+	// between intents instead of cloning for every push. Cached clones are
+	// repacked every GCInterval once they hold more than
+	// GCLooseObjectThreshold loose objects; a zero interval never repacks.
+	CacheClones            bool
+	GCInterval             int // seconds
+	GCLooseObjectThreshold int
+}
+
+// Substitution replaces matches of a regular expression in document content
+type Substitution struct {
+	Pattern     string
+	Replacement string
+}
+
+// Identity is the commit identity used on branches matching Branch, a
+// path.Match pattern
+type Identity struct {
+	Branch string `json:"branch"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
 }
 
+// Tag exists policies
+const (
+	TagExistsSkip  = "skip"
+	TagExistsError = "error"
+)
+
+// Path case modes
+const (
+	PathCasePreserve = "preserve"
+	PathCaseLower    = "lower"
+)
+
+// Submodule modes
+const (
+	SubmoduleModeIgnore = "ignore"
+	SubmoduleModeError  = "error"
+)
+
+// DryRunOutputMongoDB writes dry run results to MongoDB; any other
+// DryRunOutput is a directory
+const DryRunOutputMongoDB = "mongodb"
+
+// Apply modes
+const (
+	ApplyModeWorktree = "worktree"
+	ApplyModeTree     = "tree"
+)
+
+// Commit granularity modes
+const (
+	CommitGranularityIntent   = "intent"
+	CommitGranularityDocument = "document"
+)
+
 // Load configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -49,21 +297,176 @@ func Load() (*Config, error) {
 		GitHubBranch:       getEnv("GITHUB_BRANCH", "main"),
 		GitUserName:        getEnv("GIT_USER_NAME", "Virtual DOM Bot"),
 		GitUserEmail:       getEnv("GIT_USER_EMAIL", "bot@tekfly.io"),
+		GitNetRetries:      getEnvInt("GIT_NET_RETRIES", 3),
 		PollInterval:       getEnvInt("POLL_INTERVAL", 5),
 		BatchSize:          getEnvInt("BATCH_SIZE", 100),
 		WorkerCount:        getEnvInt("WORKER_COUNT", 3),
 		MetricsPort:        getEnvInt("METRICS_PORT", 9091),
 		EnableSigning:      getEnvBool("ENABLE_SIGNING", false),
 		GPGKeyPath:         getEnv("GPG_KEY_PATH", ""),
+		AdminToken:         getEnv("ADMIN_TOKEN", ""),
 		DryRun:             getEnvBool("DRY_RUN", false),
+		DryRunOutput:       getEnv("DRY_RUN_OUTPUT", ""),
 		EnableWebhooks:     getEnvBool("ENABLE_WEBHOOKS", false),
+		CommitGranularity:  getEnv("COMMIT_GRANULARITY", CommitGranularityIntent),
+		PathPrefix:         getEnv("PATH_PREFIX", ""),
+
+		CommitTimezone: getEnv("COMMIT_TIMEZONE", "UTC"),
+
+		VerifySignerPerCommit: getEnvBool("VERIFY_SIGNER_PER_COMMIT", false),
+
+		MongoDBMaxPoolSize:            getEnvInt("MONGODB_MAX_POOL_SIZE", 100),
+		MongoDBConnectTimeout:         getEnvInt("MONGODB_CONNECT_TIMEOUT", 10),
+		MongoDBServerSelectionTimeout: getEnvInt("MONGODB_SERVER_SELECTION_TIMEOUT", 10),
+
+		DocFetchChunkSize: getEnvInt("DOC_FETCH_CHUNK_SIZE", 1000),
+
+		BacklogCheckInterval: getEnvInt("BACKLOG_CHECK_INTERVAL", 30),
+		MaxDocsPerIntent:     getEnvInt("MAX_DOCS_PER_INTENT", 10000),
+		KeepEmptyDirs:        getEnvBool("KEEP_EMPTY_DIRS", false),
+		StrictOperations:     getEnvBool("STRICT_OPERATIONS", false),
+		DocumentTypes:        getEnvList("DOCUMENT_TYPES", ","),
+		IgnorePaths:          getEnvList("IGNORE_PATHS", ","),
+		MinFreeDiskBytes:     getEnvInt64("MIN_FREE_DISK_BYTES", 0),
+		Transformers:         getEnvList("TRANSFORMERS", ","),
+		RedactPatterns:       getEnvList("REDACT_PATTERNS", ";"),
+		TagExistsPolicy:      getEnv("TAG_EXISTS_POLICY", TagExistsSkip),
+		IncludeFileManifest:  getEnvBool("INCLUDE_FILE_MANIFEST", false),
+		ManifestMaxFiles:     getEnvInt("MANIFEST_MAX_FILES", 50),
+
+		PendingMarkRetryInterval: getEnvInt("PENDING_MARK_RETRY_INTERVAL", 30),
+		StreamSweepInterval:      getEnvInt("STREAM_SWEEP_INTERVAL", 60),
+
+		CacheClones:            getEnvBool("CACHE_CLONES", false),
+		GCInterval:             getEnvInt("GC_INTERVAL", 3600),
+		GCLooseObjectThreshold: getEnvInt("GC_LOOSE_OBJECT_THRESHOLD", 6700),
+
+		GitHTTPMaxIdleConnsPerHost: getEnvInt("GIT_HTTP_MAX_IDLE_CONNS_PER_HOST", 10),
+		GitHTTPIdleConnTimeout:     getEnvInt("GIT_HTTP_IDLE_CONN_TIMEOUT", 90),
+		GitHTTPKeepAlive:           getEnvInt("GIT_HTTP_KEEPALIVE", 30),
+		GitHTTP2:                   getEnvBool("GIT_HTTP2", true),
+
+		MongoDBCAFile:     getEnv("MONGODB_CA_FILE", ""),
+		MongoDBClientCert: getEnv("MONGODB_CLIENT_CERT", ""),
+		MongoDBClientKey:  getEnv("MONGODB_CLIENT_KEY", ""),
+		GitHubCAFile:      getEnv("GITHUB_CA_FILE", ""),
+		GitHubClientCert:  getEnv("GITHUB_CLIENT_CERT", ""),
+		GitHubClientKey:   getEnv("GITHUB_CLIENT_KEY", ""),
+
+		PathCase: getEnv("PATH_CASE", PathCasePreserve),
+
+		RecheckOnClean: getEnvBool("RECHECK_ON_CLEAN", false),
+
+		CommitScopeFormat: getEnv("COMMIT_SCOPE_FORMAT", ""),
+
+		PushCooldown: getEnvInt("PUSH_COOLDOWN", 0),
+
+		SubmoduleMode: getEnv("SUBMODULE_MODE", SubmoduleModeIgnore),
+
+		SyncWrites: getEnvBool("SYNC_WRITES", false),
+
+		ApplyMode: getEnv("APPLY_MODE", ApplyModeWorktree),
+
+		AllowBinary: getEnvBool("ALLOW_BINARY", true),
+
+		RepoDiscoveryPattern:  getEnv("REPO_DISCOVERY_PATTERN", ""),
+		RepoDiscoveryInterval: getEnvInt("REPO_DISCOVERY_INTERVAL", 300),
+
+		CommitSubjectMaxLength: getEnvInt("COMMIT_SUBJECT_MAX_LENGTH", 72),
+		CommitBodyMaxLength:    getEnvInt("COMMIT_BODY_MAX_LENGTH", 8192),
+
+		MinWorkers:             getEnvInt("MIN_WORKERS", 1),
+		MaxWorkers:             getEnvInt("MAX_WORKERS", 0),
+		AutoscaleHighWatermark: getEnvInt("AUTOSCALE_HIGH_WATERMARK", 100),
+		AutoscaleLowWatermark:  getEnvInt("AUTOSCALE_LOW_WATERMARK", 10),
+
+		RepoLockTTL: getEnvInt("REPO_LOCK_TTL", 300),
+
+		OnceReport: getEnvBool("ONCE_REPORT", true),
+
+		AmendBranches: getEnvList("AMEND_BRANCHES", ","),
+
+		TracingEndpoint: getEnv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", "")),
+
+		PostPushHook:        getEnv("POST_PUSH_HOOK", ""),
+		PostPushHookTimeout: getEnvInt("POST_PUSH_HOOK_TIMEOUT", 30),
+
+		ShutdownTimeout: getEnvInt("SHUTDOWN_TIMEOUT", 30),
+
+		AllowedBranches: getEnvList("ALLOWED_BRANCHES", ","),
+
+		StartupSelftest: getEnvBool("STARTUP_SELFTEST", false),
+		SelftestRepo:    getEnv("SELFTEST_REPO", ""),
+		SelftestBranch:  getEnv("SELFTEST_BRANCH", ""),
+	}
+
+	var err error
+	if cfg.PathPrefixes, err = getEnvMap("PATH_PREFIXES"); err != nil {
+		return nil, err
+	}
+
+	if since := getEnv("REPLAY_SINCE", ""); since != "" {
+		if cfg.ReplaySince, err = time.Parse(time.RFC3339, since); err != nil {
+			return nil, fmt.Errorf("REPLAY_SINCE: expected an RFC3339 timestamp: %w", err)
+		}
+	}
+
+	for _, entry := range getEnvList("CONTENT_SUBSTITUTIONS", ";") {
+		pattern, replacement, ok := strings.Cut(entry, "=>")
+		if !ok || pattern == "" {
+			return nil, fmt.Errorf("CONTENT_SUBSTITUTIONS: invalid entry %q, expected pattern=>replacement", entry)
+		}
+		cfg.ContentSubstitutions = append(cfg.ContentSubstitutions, Substitution{
+			Pattern:     pattern,
+			Replacement: replacement,
+		})
+	}
+
+	if file := getEnv("IDENTITY_MAP", ""); file != "" {
+		if cfg.IdentityMap, err = loadIdentityMap(file); err != nil {
+			return nil, err
+		}
 	}
 
 	return cfg, nil
 }
 
+// loadIdentityMap reads a JSON array of identities from file
+func loadIdentityMap(file string) ([]Identity, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("IDENTITY_MAP: %w", err)
+	}
+
+	var identities []Identity
+	if err := json.Unmarshal(data, &identities); err != nil {
+		return nil, fmt.Errorf("IDENTITY_MAP: invalid JSON in %s: %w", file, err)
+	}
+	return identities, nil
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
+	if c.MongoDBMaxPoolSize < 1 {
+		return fmt.Errorf("MONGODB_MAX_POOL_SIZE must be at least 1")
+	}
+
+	if c.MongoDBConnectTimeout < 1 {
+		return fmt.Errorf("MONGODB_CONNECT_TIMEOUT must be at least 1 second")
+	}
+
+	if c.MongoDBServerSelectionTimeout < 1 {
+		return fmt.Errorf("MONGODB_SERVER_SELECTION_TIMEOUT must be at least 1 second")
+	}
+
+	if c.DocFetchChunkSize < 1 {
+		return fmt.Errorf("DOC_FETCH_CHUNK_SIZE must be at least 1")
+	}
+
+	if (c.MongoDBClientCert == "") != (c.MongoDBClientKey == "") {
+		return fmt.Errorf("MONGODB_CLIENT_CERT and MONGODB_CLIENT_KEY must be set together")
+	}
+
 	if c.GitHubToken == "" {
 		return fmt.Errorf("GITHUB_TOKEN is required")
 	}
@@ -76,6 +479,22 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("GITHUB_REPO is required")
 	}
 
+	if (c.GitHubClientCert == "") != (c.GitHubClientKey == "") {
+		return fmt.Errorf("GITHUB_CLIENT_CERT and GITHUB_CLIENT_KEY must be set together")
+	}
+
+	if c.RepoDiscoveryPattern != "" {
+		if c.GitHubOrganization == "" {
+			return fmt.Errorf("GITHUB_ORG is required when REPO_DISCOVERY_PATTERN is set")
+		}
+		if _, err := path.Match(c.RepoDiscoveryPattern, ""); err != nil {
+			return fmt.Errorf("invalid REPO_DISCOVERY_PATTERN: %w", err)
+		}
+		if c.RepoDiscoveryInterval < 1 {
+			return fmt.Errorf("REPO_DISCOVERY_INTERVAL must be at least 1 second")
+		}
+	}
+
 	if c.EnableSigning && c.GPGKeyPath == "" {
 		return fmt.Errorf("GPG_KEY_PATH is required when signing is enabled")
 	}
@@ -92,6 +511,140 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("WORKER_COUNT must be at least 1")
 	}
 
+	if c.MaxWorkers > 0 {
+		if c.MinWorkers < 1 || c.MaxWorkers < c.MinWorkers {
+			return fmt.Errorf("MIN_WORKERS must be at least 1 and at most MAX_WORKERS")
+		}
+		if c.AutoscaleLowWatermark < 0 || c.AutoscaleHighWatermark <= c.AutoscaleLowWatermark {
+			return fmt.Errorf("AUTOSCALE_HIGH_WATERMARK must be above AUTOSCALE_LOW_WATERMARK")
+		}
+	}
+
+	if c.BacklogCheckInterval < 1 {
+		return fmt.Errorf("BACKLOG_CHECK_INTERVAL must be at least 1 second")
+	}
+
+	if !c.ReplaySince.IsZero() && !c.EnableWebhooks {
+		return fmt.Errorf("REPLAY_SINCE requires ENABLE_WEBHOOKS, which selects the change stream")
+	}
+
+	if c.ReplaySince.After(time.Now()) {
+		return fmt.Errorf("REPLAY_SINCE must not be in the future")
+	}
+
+	if c.EnableWebhooks && c.StreamSweepInterval < 1 {
+		return fmt.Errorf("STREAM_SWEEP_INTERVAL must be at least 1 second")
+	}
+
+	if c.PushCooldown < 0 {
+		return fmt.Errorf("PUSH_COOLDOWN must not be negative")
+	}
+
+	if c.RepoLockTTL < 0 {
+		return fmt.Errorf("REPO_LOCK_TTL must not be negative")
+	}
+
+	if c.PendingMarkRetryInterval < 1 {
+		return fmt.Errorf("PENDING_MARK_RETRY_INTERVAL must be at least 1 second")
+	}
+
+	if c.MaxDocsPerIntent < 0 {
+		return fmt.Errorf("MAX_DOCS_PER_INTENT must not be negative")
+	}
+
+	if _, err := time.LoadLocation(c.CommitTimezone); err != nil {
+		return fmt.Errorf("COMMIT_TIMEZONE is not a valid time zone: %w", err)
+	}
+
+	if c.GitNetRetries < 0 {
+		return fmt.Errorf("GIT_NET_RETRIES must not be negative")
+	}
+
+	if c.GitHTTPMaxIdleConnsPerHost < 1 {
+		return fmt.Errorf("GIT_HTTP_MAX_IDLE_CONNS_PER_HOST must be at least 1")
+	}
+
+	if c.GitHTTPIdleConnTimeout < 1 {
+		return fmt.Errorf("GIT_HTTP_IDLE_CONN_TIMEOUT must be at least 1 second")
+	}
+
+	if c.GitHTTPKeepAlive < 1 {
+		return fmt.Errorf("GIT_HTTP_KEEPALIVE must be at least 1 second")
+	}
+
+	if c.MinFreeDiskBytes < 0 {
+		return fmt.Errorf("MIN_FREE_DISK_BYTES must not be negative")
+	}
+
+	if c.ManifestMaxFiles < 0 {
+		return fmt.Errorf("MANIFEST_MAX_FILES must not be negative")
+	}
+
+	if c.TagExistsPolicy != TagExistsSkip && c.TagExistsPolicy != TagExistsError {
+		return fmt.Errorf("TAG_EXISTS_POLICY must be %q or %q", TagExistsSkip, TagExistsError)
+	}
+
+	if c.PathCase != PathCasePreserve && c.PathCase != PathCaseLower {
+		return fmt.Errorf("PATH_CASE must be %q or %q", PathCasePreserve, PathCaseLower)
+	}
+
+	if c.CommitSubjectMaxLength < 0 || c.CommitBodyMaxLength < 0 {
+		return fmt.Errorf("COMMIT_SUBJECT_MAX_LENGTH and COMMIT_BODY_MAX_LENGTH must not be negative")
+	}
+
+	if c.SubmoduleMode != SubmoduleModeIgnore && c.SubmoduleMode != SubmoduleModeError {
+		return fmt.Errorf("SUBMODULE_MODE must be %q or %q", SubmoduleModeIgnore, SubmoduleModeError)
+	}
+
+	for _, pattern := range c.AmendBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid AMEND_BRANCHES pattern %q: %w", pattern, err)
+		}
+	}
+
+	if c.PostPushHook != "" && c.PostPushHookTimeout < 1 {
+		return fmt.Errorf("POST_PUSH_HOOK_TIMEOUT must be at least 1 second")
+	}
+
+	if c.ShutdownTimeout < 1 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be at least 1 second")
+	}
+
+	for _, pattern := range c.AllowedBranches {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid ALLOWED_BRANCHES pattern %q: %w", pattern, err)
+		}
+	}
+
+	for _, identity := range c.IdentityMap {
+		if _, err := path.Match(identity.Branch, ""); err != nil {
+			return fmt.Errorf("invalid IDENTITY_MAP pattern %q: %w", identity.Branch, err)
+		}
+		if identity.Branch == "" || identity.Name == "" || identity.Email == "" {
+			return fmt.Errorf("IDENTITY_MAP entries need a branch, name and email, got %+v", identity)
+		}
+	}
+
+	if c.DryRunOutput != "" && !c.DryRun {
+		return fmt.Errorf("DRY_RUN_OUTPUT requires DRY_RUN")
+	}
+
+	if c.ApplyMode != ApplyModeWorktree && c.ApplyMode != ApplyModeTree {
+		return fmt.Errorf("APPLY_MODE must be %q or %q", ApplyModeWorktree, ApplyModeTree)
+	}
+
+	if c.CommitGranularity != CommitGranularityIntent && c.CommitGranularity != CommitGranularityDocument {
+		return fmt.Errorf("COMMIT_GRANULARITY must be %q or %q", CommitGranularityIntent, CommitGranularityDocument)
+	}
+
+	if c.GCInterval < 0 {
+		return fmt.Errorf("GC_INTERVAL must not be negative")
+	}
+
+	if c.GCLooseObjectThreshold < 0 {
+		return fmt.Errorf("GC_LOOSE_OBJECT_THRESHOLD must not be negative")
+	}
+
 	return nil
 }
 
@@ -103,6 +656,39 @@ func (c *Config) GetRepoFullName() string {
 	return fmt.Sprintf("%s/%s", c.GitHubOrganization, c.GitHubRepo)
 }
 
+// IdentityFor returns the commit name and email for the given target branch
+func (c *Config) IdentityFor(branch string) (name, email string) {
+	for _, identity := range c.IdentityMap {
+		if ok, _ := path.Match(identity.Branch, branch); ok {
+			return identity.Name, identity.Email
+		}
+	}
+	return c.GitUserName, c.GitUserEmail
+}
+
+// SelftestTarget returns the repository (org/repo) and branch the startup
+// self-test pushes to
+func (c *Config) SelftestTarget() (repo, branch string) {
+	repo, branch = c.SelftestRepo, c.SelftestBranch
+	if repo == "" {
+		repo = c.GetRepoFullName()
+	} else if !strings.Contains(repo, "/") {
+		repo = fmt.Sprintf("%s/%s", c.GitHubOrganization, repo)
+	}
+	if branch == "" {
+		branch = c.GitHubBranch
+	}
+	return repo, branch
+}
+
+// PathPrefixFor returns the path prefix for documents of the given intent repo
+func (c *Config) PathPrefixFor(repo string) string {
+	if prefix, ok := c.PathPrefixes[repo]; ok {
+		return prefix
+	}
+	return c.PathPrefix
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -119,6 +705,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -126,4 +721,43 @@ func getEnvBool(key string, defaultValue bool) bool {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getEnvMap parses a comma-separated list of key=value pairs
+func getEnvMap(key string) (map[string]string, error) {
+	result := make(map[string]string)
+	value := os.Getenv(key)
+	if value == "" {
+		return result, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("%s: invalid entry %q, expected key=value", key, pair)
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+
+	return result, nil
+}
+
+// getEnvList splits a separated list, dropping empty entries
+func getEnvList(key, sep string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setRequiredEnv sets the variables Validate requires
+func setRequiredEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("GITHUB_TOKEN", "token")
+	t.Setenv("GITHUB_REPO", "tekfly/site")
+}
+
+func writeIdentityMap(t *testing.T, content string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "identities.json")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write identity map: %v", err)
+	}
+	return file
+}
+
+func TestIdentityForSelectsFirstMatchingBranch(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("IDENTITY_MAP", writeIdentityMap(t, `[
+		{"branch": "team-a/*", "name": "Team A Bot", "email": "team-a@tekfly.io"},
+		{"branch": "team-*", "name": "Teams Bot", "email": "teams@tekfly.io"}
+	]`))
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	tests := []struct {
+		branch, name, email string
+	}{
+		{"team-a/feature", "Team A Bot", "team-a@tekfly.io"},
+		{"team-b", "Teams Bot", "teams@tekfly.io"},
+		{"main", "Virtual DOM Bot", "bot@tekfly.io"},
+	}
+	for _, tt := range tests {
+		name, email := cfg.IdentityFor(tt.branch)
+		if name != tt.name || email != tt.email {
+			t.Errorf("IdentityFor(%q) = %s <%s>, want %s <%s>", tt.branch, name, email, tt.name, tt.email)
+		}
+	}
+}
+
+func TestIdentityMapValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"invalid pattern", `[{"branch": "team-[", "name": "A", "email": "a@tekfly.io"}]`, "invalid IDENTITY_MAP pattern"},
+		{"missing email", `[{"branch": "team-a/*", "name": "A"}]`, "need a branch, name and email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv("IDENTITY_MAP", writeIdentityMap(t, tt.content))
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsMalformedIdentityMap(t *testing.T) {
+	t.Setenv("IDENTITY_MAP", writeIdentityMap(t, `{"branch": "team-a/*"}`))
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "IDENTITY_MAP") {
+		t.Errorf("expected an IDENTITY_MAP error, got %v", err)
+	}
+}
+
+func TestAllowedBranchesValidation(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("ALLOWED_BRANCHES", "main,release-[")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid ALLOWED_BRANCHES pattern") {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v58/github"
+	"github.com/sirupsen/logrus"
+)
+
+// Allowlist is the set of repository names intents may target. It is empty
+// and not ready until the first successful refresh.
+type Allowlist struct {
+	mu    sync.RWMutex
+	repos map[string]struct{}
+	ready bool
+}
+
+// Ready reports whether the allowlist has been loaded at least once
+func (a *Allowlist) Ready() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.ready
+}
+
+// Allowed reports whether repo is in the allowlist. Names compare
+// case-insensitively, as GitHub treats them.
+func (a *Allowlist) Allowed(repo string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	_, ok := a.repos[strings.ToLower(repo)]
+	return ok
+}
+
+// Repos returns the allowed repository names in order
+func (a *Allowlist) Repos() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	repos := make([]string, 0, len(a.repos))
+	for name := range a.repos {
+		repos = append(repos, name)
+	}
+	sort.Strings(repos)
+	return repos
+}
+
+// replace swaps in a freshly discovered set of repositories
+func (a *Allowlist) replace(names []string) {
+	repos := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		repos[strings.ToLower(name)] = struct{}{}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.repos = repos
+	a.ready = true
+}
+
+// Options configures repository discovery
+type Options struct {
+	// Organization whose repositories are listed
+	Organization string
+
+	// Pattern is a path.Match glob repository names must match
+	Pattern string
+
+	// Token authenticates API requests
+	Token string
+
+	// Interval between refreshes
+	Interval time.Duration
+
+	// BaseURL overrides the GitHub API endpoint, e.g. for GitHub Enterprise
+	BaseURL string
+
+	// HTTPClient sends API requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Discoverer keeps an Allowlist in sync with the repositories of an
+// organization whose names match a pattern
+type Discoverer struct {
+	client    *github.Client
+	org       string
+	pattern   string
+	interval  time.Duration
+	allowlist *Allowlist
+	logger    *logrus.Logger
+}
+
+// New creates a Discoverer with an empty allowlist
+func New(opts Options, logger *logrus.Logger) (*Discoverer, error) {
+	if _, err := path.Match(opts.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid repository pattern %q: %w", opts.Pattern, err)
+	}
+
+	client := github.NewClient(opts.HTTPClient)
+	if opts.Token != "" {
+		client = client.WithAuthToken(opts.Token)
+	}
+	if opts.BaseURL != "" {
+		base, err := url.Parse(strings.TrimSuffix(opts.BaseURL, "/") + "/")
+		if err != nil {
+			return nil, fmt.Errorf("invalid GitHub API URL: %w", err)
+		}
+		client.BaseURL = base
+	}
+
+	return &Discoverer{
+		client:    client,
+		org:       opts.Organization,
+		pattern:   opts.Pattern,
+		interval:  opts.Interval,
+		allowlist: &Allowlist{},
+		logger:    logger,
+	}, nil
+}
+
+// Allowlist returns the allowlist kept up to date by Run
+func (d *Discoverer) Allowlist() *Allowlist {
+	return d.allowlist
+}
+
+// Refresh lists every page of the organization's repositories and replaces
+// the allowlist with those matching the pattern. On failure the previous
+// allowlist is kept.
+func (d *Discoverer) Refresh(ctx context.Context) error {
+	opts := &github.RepositoryListByOrgOptions{
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	var matched []string
+	for {
+		repos, resp, err := d.client.Repositories.ListByOrg(ctx, d.org, opts)
+		if err != nil {
+			return fmt.Errorf("failed to list repositories of %s: %w", d.org, err)
+		}
+
+		for _, repo := range repos {
+			if ok, _ := path.Match(d.pattern, repo.GetName()); ok {
+				matched = append(matched, repo.GetName())
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	d.allowlist.replace(matched)
+	d.logger.WithFields(logrus.Fields{
+		"organization": d.org,
+		"repos":        len(matched),
+	}).Debug("Refreshed repository allowlist")
+	return nil
+}
+
+// Run refreshes the allowlist immediately and then on every interval until
+// ctx is done. When GitHub rate limits the listing, the next attempt waits
+// for the limit to reset instead of the usual interval.
+func (d *Discoverer) Run(ctx context.Context) {
+	for {
+		wait := d.interval
+
+		if err := d.Refresh(ctx); err != nil {
+			if reset, limited := rateLimitReset(err); limited && time.Until(reset) > wait {
+				wait = time.Until(reset)
+			}
+			d.logger.WithError(err).WithField("retry_in", wait).Warn("Repository discovery failed, keeping previous allowlist")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// rateLimitReset reports when a rate limited request may be retried
+func rateLimitReset(err error) (time.Time, bool) {
+	var rateErr *github.RateLimitError
+	if errors.As(err, &rateErr) {
+		return rateErr.Rate.Reset.Time, true
+	}
+
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseErr) {
+		return time.Now().Add(abuseErr.GetRetryAfter()), true
+	}
+
+	return time.Time{}, false
+}
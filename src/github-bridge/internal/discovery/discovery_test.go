@@ -0,0 +1,166 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeOrgAPI serves an organization's repositories two per page
+type fakeOrgAPI struct {
+	mu          sync.Mutex
+	repos       []string
+	rateLimited bool
+	requests    int
+}
+
+func (f *fakeOrgAPI) setRepos(repos ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.repos = repos
+}
+
+func (f *fakeOrgAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if r.URL.Path != "/orgs/tekfly/repos" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if f.rateLimited {
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"message": "API rate limit exceeded"}`)
+		return
+	}
+
+	const perPage = 2
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	start := (page - 1) * perPage
+	end := start + perPage
+	if end > len(f.repos) {
+		end = len(f.repos)
+	}
+	if end < len(f.repos) {
+		next := fmt.Sprintf("http://%s%s?page=%d", r.Host, r.URL.Path, page+1)
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next))
+	}
+
+	var body []map[string]string
+	for _, name := range f.repos[start:end] {
+		body = append(body, map[string]string{"name": name})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestDiscoverer(t *testing.T, api *fakeOrgAPI) *Discoverer {
+	t.Helper()
+
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	d, err := New(Options{
+		Organization: "tekfly",
+		Pattern:      "svc-*",
+		Token:        "test-token",
+		Interval:     time.Minute,
+		BaseURL:      srv.URL,
+	}, logger)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return d
+}
+
+func TestRefreshFollowsPaginationAndUpdatesAllowlist(t *testing.T) {
+	api := &fakeOrgAPI{}
+	api.setRepos("svc-billing", "website", "svc-auth", "docs", "svc-search")
+	d := newTestDiscoverer(t, api)
+
+	allowlist := d.Allowlist()
+	if allowlist.Ready() {
+		t.Fatal("expected the allowlist not to be ready before the first refresh")
+	}
+
+	if err := d.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	if api.requests != 3 {
+		t.Errorf("expected 3 page requests, got %d", api.requests)
+	}
+	want := []string{"svc-auth", "svc-billing", "svc-search"}
+	if got := allowlist.Repos(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected allowlist %v, got %v", want, got)
+	}
+	if !allowlist.Ready() || !allowlist.Allowed("SVC-Auth") || allowlist.Allowed("website") {
+		t.Errorf("unexpected allowlist membership: %v", allowlist.Repos())
+	}
+
+	// A newly created repository becomes eligible on the next refresh
+	api.setRepos("svc-billing", "svc-auth", "svc-payments")
+	if err := d.Refresh(context.Background()); err != nil {
+		t.Fatalf("second Refresh failed: %v", err)
+	}
+
+	want = []string{"svc-auth", "svc-billing", "svc-payments"}
+	if got := allowlist.Repos(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected refreshed allowlist %v, got %v", want, got)
+	}
+}
+
+func TestRateLimitedRefreshKeepsAllowlist(t *testing.T) {
+	api := &fakeOrgAPI{}
+	api.setRepos("svc-auth")
+	d := newTestDiscoverer(t, api)
+
+	if err := d.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	api.mu.Lock()
+	api.rateLimited = true
+	api.mu.Unlock()
+
+	err := d.Refresh(context.Background())
+	if err == nil {
+		t.Fatal("expected a rate limited refresh to fail")
+	}
+
+	reset, limited := rateLimitReset(err)
+	if !limited {
+		t.Fatalf("expected a rate limit error, got %v", err)
+	}
+	if time.Until(reset) < 30*time.Minute {
+		t.Errorf("expected the reset time from the response, got %v", reset)
+	}
+
+	if !d.Allowlist().Allowed("svc-auth") {
+		t.Error("expected the previous allowlist to be kept")
+	}
+}
@@ -0,0 +1,145 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotAmendable is returned when HEAD cannot be amended: it was not
+// committed by the bot, or it is a root or merge commit
+var ErrNotAmendable = errors.New("HEAD commit cannot be amended")
+
+// CanAmend reports whether HEAD is a commit the bot may replace: committed
+// by author's committer, with a single parent. The commit's author may be
+// anyone the bot attributed it to; commits made by anyone else are never
+// rewritten.
+func (r *Repository) CanAmend(author CommitAuthor) (bool, error) {
+	head, err := r.headCommit()
+	if err != nil {
+		return false, err
+	}
+	return amendable(head, author), nil
+}
+
+// AmendCommit commits whatever has been applied to the worktree as a
+// replacement for HEAD: the new commit has HEAD's parent rather than HEAD,
+// so repeated amends keep a single bot commit on the branch. It returns an
+// empty hash when the worktree is clean. Push the result with
+// ForcePushWithLease, leased on the commit that was replaced.
+func (r *Repository) AmendCommit(message string, author CommitAuthor) (string, error) {
+	prev, err := r.headCommit()
+	if err != nil {
+		return "", err
+	}
+	if !amendable(prev, author) {
+		return "", fmt.Errorf("%w: %s", ErrNotAmendable, prev.Hash)
+	}
+
+	// Commit on top of HEAD as usual, then rewrite that commit's parent
+	stacked, err := r.CommitChanges(message, author)
+	if err != nil || stacked == "" {
+		return stacked, err
+	}
+	commit, err := r.repo.CommitObject(plumbing.NewHash(stacked))
+	if err != nil {
+		return "", fmt.Errorf("failed to load commit: %w", err)
+	}
+
+	amended := &object.Commit{
+		Author:       commit.Author,
+		Committer:    commit.Committer,
+		Message:      commit.Message,
+		TreeHash:     commit.TreeHash,
+		ParentHashes: prev.ParentHashes,
+	}
+	if r.signKey != nil {
+		if amended.PGPSignature, err = signCommit(amended, r.signKey); err != nil {
+			return "", err
+		}
+	}
+
+	hash, err := r.storeObject(amended)
+	if err != nil {
+		return "", fmt.Errorf("failed to store commit: %w", err)
+	}
+
+	head, err := r.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), hash)); err != nil {
+		return "", fmt.Errorf("failed to update %s: %w", head.Name(), err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"hash":     hash.String(),
+		"replaced": prev.Hash.String(),
+	}).Info("Amended commit")
+	return hash.String(), nil
+}
+
+// ForcePushWithLease pushes the branch at HEAD over a rewritten history,
+// provided the remote branch is still at lease. If someone pushed in the
+// meantime the push is rejected rather than discarding their commits.
+func (r *Repository) ForcePushWithLease(ctx context.Context, lease string) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	pushOpts := &git.PushOptions{
+		RemoteName: r.remoteName,
+		Auth:       r.auth,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", head.Name(), head.Name()))},
+		ForceWithLease: &git.ForceWithLease{
+			RefName: head.Name(),
+			Hash:    plumbing.NewHash(lease),
+		},
+	}
+
+	r.logger.WithField("lease", lease).Info("Force pushing to remote with lease")
+
+	err = withRetry(ctx, r.logger, "push", r.netRetries, func() error {
+		return r.repo.PushContext(ctx, pushOpts)
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+
+	return nil
+}
+
+// HeadHash returns the hash of the commit at HEAD
+func (r *Repository) HeadHash() (string, error) {
+	head, err := r.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return head.Hash().String(), nil
+}
+
+// headCommit loads the commit at HEAD
+func (r *Repository) headCommit() (*object.Commit, error) {
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %w", err)
+	}
+	return commit, nil
+}
+
+// amendable reports whether the bot committing as author may replace commit
+func amendable(commit *object.Commit, author CommitAuthor) bool {
+	_, email := author.Committer()
+	return len(commit.ParentHashes) == 1 && commit.Committer.Email == email
+}
@@ -0,0 +1,164 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAmendCommitReplacesBotTip(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/index.md": "v1"})
+
+	// The initial commit is a human's and is never amended
+	if ok, err := r.CanAmend(testAuthor); err != nil || ok {
+		t.Fatalf("expected a human tip not to be amendable, got %v, %v", ok, err)
+	}
+	if _, err := r.AmendCommit("Publish", testAuthor); !errors.Is(err, ErrNotAmendable) {
+		t.Fatalf("expected ErrNotAmendable, got %v", err)
+	}
+
+	if err := r.WriteFile("docs/index.md", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	first, err := r.CommitChanges("Publish v2", testAuthor)
+	if err != nil {
+		t.Fatalf("CommitChanges failed: %v", err)
+	}
+	firstCommit, _ := r.headCommit()
+
+	if ok, err := r.CanAmend(testAuthor); err != nil || !ok {
+		t.Fatalf("expected the bot's tip to be amendable, got %v, %v", ok, err)
+	}
+	if err := r.WriteFile("docs/about.md", []byte("about")); err != nil {
+		t.Fatal(err)
+	}
+	amended, err := r.AmendCommit("Publish v3", testAuthor)
+	if err != nil || amended == "" || amended == first {
+		t.Fatalf("AmendCommit failed: %q, %v", amended, err)
+	}
+
+	head, err := r.headCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Hash.String() != amended || head.Message != "Publish v3" {
+		t.Errorf("expected HEAD at the amended commit, got %s %q", head.Hash, head.Message)
+	}
+	if len(head.ParentHashes) != 1 || head.ParentHashes[0] != firstCommit.ParentHashes[0] {
+		t.Errorf("expected the amended commit to replace %s, got parents %v", first, head.ParentHashes)
+	}
+	if got := commitCount(t, r); got != 2 {
+		t.Errorf("expected 2 commits after amending, got %d", got)
+	}
+	for _, path := range []string{"docs/index.md", "docs/about.md"} {
+		if _, err := head.File(path); err != nil {
+			t.Errorf("expected %s in the amended commit: %v", path, err)
+		}
+	}
+
+	// Nothing applied leaves the tip alone
+	if hash, err := r.AmendCommit("Publish v4", testAuthor); err != nil || hash != "" {
+		t.Errorf("expected no amend for a clean worktree, got %q, %v", hash, err)
+	}
+}
+
+func TestForcePushWithLeaseRejectsMovedRemote(t *testing.T) {
+	url := newTestRemote(t, map[string]string{"docs/index.md": "v1"})
+
+	// The bot pushes a commit, then amends it
+	r := cloneTestRemote(t, url)
+	if err := r.WriteFile("docs/index.md", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CommitChanges("Publish v2", testAuthor); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Push(context.Background()); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	lease, _ := r.HeadHash()
+	if err := r.WriteFile("docs/index.md", []byte("v3")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.AmendCommit("Publish v3", testAuthor); err != nil {
+		t.Fatalf("AmendCommit failed: %v", err)
+	}
+
+	// Someone else pushes on top of the bot's commit first
+	other := cloneTestRemote(t, url)
+	if err := other.WriteFile("docs/other.md", []byte("theirs")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.CommitChanges("Their change", CommitAuthor{Name: "Alice", Email: "alice@tekfly.io"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Push(context.Background()); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	if err := r.ForcePushWithLease(context.Background(), lease); err == nil {
+		t.Fatal("expected the lease to reject overwriting the newer remote commit")
+	}
+
+	// With the lease still valid the rewrite goes through
+	fresh := cloneTestRemote(t, url)
+	if err := fresh.WriteFile("docs/index.md", []byte("v4")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fresh.CommitChanges("Publish v4", testAuthor); err != nil {
+		t.Fatal(err)
+	}
+	lease, _ = fresh.HeadHash()
+	if err := fresh.Push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := fresh.WriteFile("docs/index.md", []byte("v5")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fresh.AmendCommit("Publish v5", testAuthor); err != nil {
+		t.Fatalf("AmendCommit failed: %v", err)
+	}
+	if err := fresh.ForcePushWithLease(context.Background(), lease); err != nil {
+		t.Errorf("expected the amended commit to be force pushed, got %v", err)
+	}
+}
+
+func TestAmendChecksCommitterNotAuthor(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/index.md": "v1"})
+	attributed := testAuthor
+	attributed.Name, attributed.Email = "Alice", "alice@tekfly.io"
+	attributed.CommitterName, attributed.CommitterEmail = testAuthor.Name, testAuthor.Email
+
+	// The bot committed on Alice's behalf, so its commit may be replaced
+	if err := r.WriteFile("docs/index.md", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.CommitChanges("Publish v2", attributed); err != nil {
+		t.Fatalf("CommitChanges failed: %v", err)
+	}
+	head, err := r.headCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Author.Email != "alice@tekfly.io" || head.Committer.Email != testAuthor.Email {
+		t.Fatalf("expected Alice's commit by the bot, got author %s committer %s", head.Author.Email, head.Committer.Email)
+	}
+	if ok, err := r.CanAmend(testAuthor); err != nil || !ok {
+		t.Errorf("expected the bot's commit to be amendable whoever authored it, got %v, %v", ok, err)
+	}
+
+	// A commit Alice made herself is never rewritten, even if the bot is
+	// named as its author
+	if err := r.WriteFile("docs/index.md", []byte("v3")); err != nil {
+		t.Fatal(err)
+	}
+	human := testAuthor
+	human.CommitterName, human.CommitterEmail = "Alice", "alice@tekfly.io"
+	if _, err := r.CommitChanges("Publish v3", human); err != nil {
+		t.Fatalf("CommitChanges failed: %v", err)
+	}
+	if ok, err := r.CanAmend(testAuthor); err != nil || ok {
+		t.Errorf("expected a commit made by someone else not to be amendable, got %v, %v", ok, err)
+	}
+}
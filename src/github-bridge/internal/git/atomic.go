@@ -0,0 +1,68 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// writeFileAtomic writes content to a temporary file in path's directory and
+// renames it over path, so a crash leaves either the old or the new content
+// and never a truncated file. With sync the file, and then its directory, are
+// flushed to disk so the rename survives a power loss as well. As with
+// os.WriteFile, perm only applies to new files; existing files keep their mode.
+func writeFileAtomic(path string, content []byte, perm os.FileMode, sync bool) (err error) {
+	dir := filepath.Dir(path)
+
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if sync {
+		if err = tmp.Sync(); err != nil {
+			return fmt.Errorf("failed to sync %s: %w", tmp.Name(), err)
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if sync {
+		return syncDir(dir)
+	}
+	return nil
+}
+
+// syncDir flushes a directory so entries renamed into it are durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
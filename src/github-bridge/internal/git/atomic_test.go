@@ -0,0 +1,76 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// assertNoTempFiles fails when a temporary file is left in dir
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp-") {
+			t.Errorf("temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+func TestWriteFileReplacesAtomically(t *testing.T) {
+	for _, sync := range []bool{false, true} {
+		r := newTestRepository(t, map[string]string{"docs/index.md": "old content that is longer"})
+		r.syncWrites = sync
+
+		if err := r.WriteFile("docs/index.md", []byte("new")); err != nil {
+			t.Fatalf("WriteFile (sync=%v) failed: %v", sync, err)
+		}
+
+		got, err := os.ReadFile(r.fullPath("docs/index.md"))
+		if err != nil {
+			t.Fatalf("failed to read written file: %v", err)
+		}
+		if string(got) != "new" {
+			t.Errorf("expected file content %q, got %q", "new", got)
+		}
+		assertNoTempFiles(t, r.fullPath("docs"))
+	}
+}
+
+func TestWriteFileAtomicKeepsExistingMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to seed file: %v", err)
+	}
+
+	if err := writeFileAtomic(path, []byte("#!/bin/sh\necho hi\n"), 0644, false); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("expected mode 0755 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestWriteFileAtomicCleansUpOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "occupied")
+	// Renaming a file over a non-empty directory fails
+	if err := os.MkdirAll(filepath.Join(target, "child"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+
+	if err := writeFileAtomic(target, []byte("x"), 0644, false); err == nil {
+		t.Fatal("expected writing over a directory to fail")
+	}
+	assertNoTempFiles(t, dir)
+}
@@ -0,0 +1,22 @@
+package git
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Backend clones working copies of remote repositories. Push and Pull on the
+// returned Repository go back through the same remote, so swapping the
+// backend swaps the whole transport.
+type Backend interface {
+	Clone(ctx context.Context, opts CloneOptions, logger *logrus.Logger) (*Repository, error)
+}
+
+// NetworkBackend clones over the network using the remote URL as given
+type NetworkBackend struct{}
+
+// Clone implements Backend
+func (NetworkBackend) Clone(ctx context.Context, opts CloneOptions, logger *logrus.Logger) (*Repository, error) {
+	return Clone(ctx, opts, logger)
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// ErrBinaryContent is returned for documents with binary content when
+// binaries are rejected
+var ErrBinaryContent = errors.New("document content is binary")
+
+// binarySniffLen is how much of a blob IsBinary inspects, as git does
+const binarySniffLen = 8000
+
+// IsBinary reports whether content looks like binary rather than text: its
+// first 8000 bytes contain a NUL byte or are not valid UTF-8. A rune cut off
+// by the end of that window does not count as invalid.
+func IsBinary(content []byte) bool {
+	sample := content
+	if len(sample) > binarySniffLen {
+		sample = sample[:binarySniffLen]
+	}
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return true
+	}
+
+	for len(sample) > 0 {
+		r, size := utf8.DecodeRune(sample)
+		if r == utf8.RuneError && size == 1 {
+			truncated := len(sample) < utf8.UTFMax && len(content) > binarySniffLen && !utf8.FullRune(sample)
+			return !truncated
+		}
+		sample = sample[size:]
+	}
+	return false
+}
+
+// checkBinary reports whether doc's content is binary, counting it, and
+// fails it when binaries are rejected
+func (r *Repository) checkBinary(doc Document) (bool, error) {
+	if !IsBinary(doc.Content) {
+		return false, nil
+	}
+
+	r.metrics.DocumentsBinary.Inc()
+	if r.rejectBinary {
+		return true, fmt.Errorf("%w: %s", ErrBinaryContent, doc.Path)
+	}
+	return true, nil
+}
@@ -0,0 +1,93 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/transform"
+)
+
+// pngBlob is the start of a PNG file: its signature and IHDR chunk
+var pngBlob = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x10\x00\x00\x00\x10\x08\x06\x00\x00\x00")
+
+func TestIsBinary(t *testing.T) {
+	// A multi-byte rune straddling the sniffed window is still text
+	straddling := append(bytes.Repeat([]byte("a"), binarySniffLen-1), "é and more"...)
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    bool
+	}{
+		{"empty", nil, false},
+		{"text", []byte("# Config\nkey: value\r\n"), false},
+		{"utf8 text", []byte("café ☕ 日本語"), false},
+		{"png", pngBlob, true},
+		{"nul byte", []byte("text\x00more"), true},
+		{"invalid utf8", []byte("caf\xe9 latin-1"), true},
+		{"truncated rune at end", []byte("caf\xc3"), true},
+		{"rune across sniff window", straddling, false},
+		{"nul after sniff window", append(bytes.Repeat([]byte("a"), binarySniffLen), 0), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBinary(tt.content); got != tt.want {
+				t.Errorf("IsBinary = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDocumentsPassesBinaryThroughTransformers(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.transformer = transform.Func(func(path string, content []byte) ([]byte, error) {
+		return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), nil
+	})
+
+	if err := r.ApplyDocuments([]Document{
+		{Path: "assets/logo.png", Content: pngBlob, Operation: "create"},
+		{Path: "config.yaml", Content: []byte("key: value\r\n"), Operation: "create"},
+	}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	png, err := os.ReadFile(r.fullPath("assets/logo.png"))
+	if err != nil || !bytes.Equal(png, pngBlob) {
+		t.Errorf("expected the PNG written unchanged, got %q, %v", png, err)
+	}
+	text, err := os.ReadFile(r.fullPath("config.yaml"))
+	if err != nil || string(text) != "key: value\n" {
+		t.Errorf("expected the text document transformed, got %q, %v", text, err)
+	}
+
+	if got := testutil.ToFloat64(r.metrics.DocumentsBinary); got != 1 {
+		t.Errorf("expected 1 binary document counted, got %v", got)
+	}
+}
+
+func TestApplyDocumentsRejectsBinary(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.rejectBinary = true
+
+	if err := r.ApplyDocuments([]Document{{Path: "config.yaml", Content: []byte("key: value\n"), Operation: "create"}}); err != nil {
+		t.Fatalf("expected text to be accepted, got %v", err)
+	}
+
+	err := r.ApplyDocuments([]Document{{Path: "assets/logo.png", Content: pngBlob, Operation: "create"}})
+	if !errors.Is(err, ErrBinaryContent) || !strings.Contains(err.Error(), "assets/logo.png") {
+		t.Errorf("expected ErrBinaryContent naming the path, got %v", err)
+	}
+	if _, err := os.Stat(r.fullPath("assets/logo.png")); !os.IsNotExist(err) {
+		t.Errorf("rejected document should not be written, got %v", err)
+	}
+
+	// Deletes carry no content and are never rejected
+	if err := r.ApplyDocuments([]Document{{Path: "config.yaml", Operation: "delete"}}); err != nil {
+		t.Errorf("expected delete to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+)
+
+// FileChange is a path the staged changes create, update or delete, with
+// its unified diff against HEAD
+type FileChange struct {
+	Path      string
+	Operation string
+	Diff      string
+}
+
+// DiffStaged returns the changes staged by StageDocuments against HEAD,
+// sorted by path, without committing them. The staged tree is written to
+// the object store but no commit or ref points at it.
+func (r *Repository) DiffStaged() ([]FileChange, error) {
+	stage := r.stage
+	if stage == nil {
+		return nil, nil
+	}
+
+	treeHash, _, err := r.writeTree(stage.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write tree: %w", err)
+	}
+	staged, err := object.GetTree(r.repo.Storer, treeHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load staged tree: %w", err)
+	}
+	parent, err := stage.parent.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD tree: %w", err)
+	}
+
+	changes, err := object.DiffTree(parent, staged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff staged tree: %w", err)
+	}
+
+	result := make([]FileChange, 0, len(changes))
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			return nil, err
+		}
+		patch, err := change.Patch()
+		if err != nil {
+			return nil, fmt.Errorf("failed to diff %s: %w", change, err)
+		}
+
+		fc := FileChange{Path: change.To.Name, Diff: patch.String()}
+		switch action {
+		case merkletrie.Insert:
+			fc.Operation = "create"
+		case merkletrie.Modify:
+			fc.Operation = "update"
+		case merkletrie.Delete:
+			fc.Path, fc.Operation = change.From.Name, "delete"
+		}
+		result = append(result, fc)
+	}
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffStagedListsChangesWithoutCommitting(t *testing.T) {
+	r := newTestRepository(t, map[string]string{
+		"docs/index.md": "# Hello\n",
+		"old.txt":       "old\n",
+	})
+	head, err := r.HeadHash()
+	if err != nil {
+		t.Fatalf("HeadHash failed: %v", err)
+	}
+
+	if err := r.StageDocuments([]Document{
+		{Path: "docs/index.md", Content: []byte("# Hello, world\n"), Operation: "update"},
+		{Path: "new.txt", Content: []byte("new\n"), Operation: "create"},
+		{Path: "old.txt", Operation: "delete"},
+	}); err != nil {
+		t.Fatalf("StageDocuments failed: %v", err)
+	}
+
+	changes, err := r.DiffStaged()
+	if err != nil {
+		t.Fatalf("DiffStaged failed: %v", err)
+	}
+
+	want := []struct{ path, operation, line string }{
+		{"docs/index.md", "update", "+# Hello, world"},
+		{"new.txt", "create", "+new"},
+		{"old.txt", "delete", "-old"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d changes, got %+v", len(want), changes)
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Path != w.path || c.Operation != w.operation {
+			t.Errorf("change %d: expected %s %s, got %s %s", i, w.operation, w.path, c.Operation, c.Path)
+		}
+		if !strings.Contains(c.Diff, w.line) {
+			t.Errorf("expected the diff of %s to contain %q, got:\n%s", w.path, w.line, c.Diff)
+		}
+	}
+
+	if after, _ := r.HeadHash(); after != head || commitCount(t, r) != 1 {
+		t.Errorf("expected HEAD to stay at %s without new commits, got %s", head, after)
+	}
+}
@@ -0,0 +1,39 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+// ErrInsufficientDisk is returned when the work directory does not have
+// enough free space to start a clone
+var ErrInsufficientDisk = errors.New("insufficient free disk space")
+
+var errDiskCheckUnsupported = errors.New("free disk space check not supported")
+
+// freeDiskBytes reports free space under a directory; tests replace it
+var freeDiskBytes = statFreeBytes
+
+// checkFreeDisk refuses to proceed when dir has less than minFree bytes
+// available. A zero minFree disables the check.
+func checkFreeDisk(dir string, minFree uint64, m *metrics.Metrics, logger *logrus.Logger) error {
+	free, err := freeDiskBytes(dir)
+	if errors.Is(err, errDiskCheckUnsupported) {
+		return nil
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Failed to measure free disk space")
+		return nil
+	}
+
+	m.WorkdirFreeBytes.Set(float64(free))
+
+	if minFree > 0 && free < minFree {
+		return fmt.Errorf("%w: %d bytes free in %s, need %d", ErrInsufficientDisk, free, dir, minFree)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package git
+
+// statFreeBytes is not supported on this platform; the free space check is
+// skipped
+func statFreeBytes(path string) (uint64, error) {
+	return 0, errDiskCheckUnsupported
+}
@@ -0,0 +1,71 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+func stubFreeDisk(t *testing.T, free uint64) {
+	t.Helper()
+	orig := freeDiskBytes
+	freeDiskBytes = func(string) (uint64, error) { return free, nil }
+	t.Cleanup(func() { freeDiskBytes = orig })
+}
+
+func TestCloneRefusedWhenDiskLow(t *testing.T) {
+	stubFreeDisk(t, 1024)
+	url := newTestRemote(t, map[string]string{})
+	workDir := t.TempDir()
+	m := metrics.Init(prometheus.NewRegistry())
+
+	_, err := Clone(context.Background(), CloneOptions{
+		URL:              url,
+		Branch:           "master",
+		TempDir:          workDir,
+		RemoteName:       "origin",
+		FullHistory:      true,
+		MinFreeDiskBytes: 1 << 20,
+		Metrics:          m,
+	}, logrus.New())
+	if !errors.Is(err, ErrInsufficientDisk) {
+		t.Fatalf("expected ErrInsufficientDisk, got %v", err)
+	}
+
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		t.Fatalf("failed to read work dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no clone directory to be created, found %d entries", len(entries))
+	}
+
+	if got := testutil.ToFloat64(m.WorkdirFreeBytes); got != 1024 {
+		t.Errorf("expected free bytes gauge of 1024, got %v", got)
+	}
+}
+
+func TestCloneAllowedWithEnoughDisk(t *testing.T) {
+	stubFreeDisk(t, 1<<30)
+	url := newTestRemote(t, map[string]string{})
+
+	r, err := Clone(context.Background(), CloneOptions{
+		URL:              url,
+		Branch:           "master",
+		TempDir:          t.TempDir(),
+		RemoteName:       "origin",
+		FullHistory:      true,
+		MinFreeDiskBytes: 1 << 20,
+		Metrics:          metrics.Init(prometheus.NewRegistry()),
+	}, logrus.New())
+	if err != nil {
+		t.Fatalf("expected clone to succeed, got %v", err)
+	}
+	r.Cleanup()
+}
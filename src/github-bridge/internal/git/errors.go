@@ -0,0 +1,76 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// ErrPermanent marks failures that will not succeed on retry, such as
+// authentication errors, a missing branch or rejected non-fast-forward
+// updates. withRetry wraps the errors it gives up on straight away with it.
+var ErrPermanent = errors.New("permanent git error")
+
+// isPermanent reports whether err is a failure retrying cannot fix
+func isPermanent(err error) bool {
+	return errors.Is(err, transport.ErrAuthenticationRequired) ||
+		errors.Is(err, transport.ErrAuthorizationFailed) ||
+		errors.Is(err, transport.ErrRepositoryNotFound) ||
+		errors.Is(err, git.NoMatchingRefSpecError{}) ||
+		errors.Is(err, git.ErrNonFastForwardUpdate)
+}
+
+// permanent wraps err with ErrPermanent when retrying cannot fix it
+func permanent(err error) error {
+	if err == nil || errors.Is(err, ErrPermanent) || !isPermanent(err) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrPermanent, err)
+}
+
+// IsTransient reports whether err is a network failure worth retrying,
+// including a secondary rate limit
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, ErrPermanent) || isPermanent(err) {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var limited *ErrSecondaryRateLimit
+	if errors.As(err, &limited) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	// go-git flattens many transport errors into strings
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{"connection reset", "unexpected eof", "timeout", "broken pipe", "connection refused"} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+
+	return false
+}
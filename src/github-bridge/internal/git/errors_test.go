@@ -0,0 +1,114 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/sirupsen/logrus"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"eof", io.EOF, true},
+		{"connection reset", fmt.Errorf("push: %w", syscall.ECONNRESET), true},
+		{"flattened timeout", errors.New("read tcp 10.0.0.1:443: i/o timeout"), true},
+		{"auth required", transport.ErrAuthenticationRequired, false},
+		{"missing branch", git.NoMatchingRefSpecError{}, false},
+		{"non-fast-forward", git.ErrNonFastForwardUpdate, false},
+		{"canceled", context.Canceled, false},
+		{"permanent", fmt.Errorf("%w: connection reset", ErrPermanent), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithRetryRecoversFromTransientErrors(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	// A transport that resets the connection twice before succeeding
+	calls := 0
+	err := withRetry(context.Background(), logrus.New(), "push", 3, func() error {
+		calls++
+		if calls <= 2 {
+			return fmt.Errorf("send-pack: %w", syscall.ECONNRESET)
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestWithRetryStopsOnPermanentError(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	calls := 0
+	err := withRetry(context.Background(), logrus.New(), "push", 3, func() error {
+		calls++
+		return transport.ErrAuthorizationFailed
+	})
+
+	if !errors.Is(err, transport.ErrAuthorizationFailed) || !errors.Is(err, ErrPermanent) {
+		t.Fatalf("expected a permanent authorization error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestWithRetryMarksMissingBranchPermanent(t *testing.T) {
+	calls := 0
+	err := withRetry(context.Background(), logrus.New(), "clone", 3, func() error {
+		calls++
+		return git.NoMatchingRefSpecError{}
+	})
+
+	if !errors.Is(err, ErrPermanent) || !errors.Is(err, git.NoMatchingRefSpecError{}) {
+		t.Fatalf("expected a permanent missing branch error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestWithRetryGivesUpAfterRetries(t *testing.T) {
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Millisecond
+
+	calls := 0
+	err := withRetry(context.Background(), logrus.New(), "pull", 2, func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, ErrPermanent) {
+		t.Fatalf("expected last transient error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls)
+	}
+}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/sirupsen/logrus"
+)
+
+// LooseObjects returns how many loose objects the repository holds
+func (r *Repository) LooseObjects() (int, error) {
+	hashes, err := r.looseObjects()
+	return len(hashes), err
+}
+
+// looseObjects returns the hashes of the repository's loose objects, or nil
+// when its storage does not keep any
+func (r *Repository) looseObjects() ([]plumbing.Hash, error) {
+	los, ok := r.repo.Storer.(storer.LooseObjectStorer)
+	if !ok {
+		return nil, nil
+	}
+
+	var hashes []plumbing.Hash
+	err := los.ForEachObjectHash(func(hash plumbing.Hash) error {
+		hashes = append(hashes, hash)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loose objects: %w", err)
+	}
+	return hashes, nil
+}
+
+// GC repacks the objects reachable from any ref into a single pack and
+// deletes the loose objects, like git gc --prune=now, when the repository
+// holds more than threshold loose objects. It reports whether it ran. Staged
+// but uncommitted blobs are lost, so callers run it between intents while
+// holding the repo lock.
+func (r *Repository) GC(threshold int) (bool, error) {
+	loose, err := r.looseObjects()
+	if err != nil {
+		return false, err
+	}
+	if len(loose) <= threshold {
+		return false, nil
+	}
+
+	if err := r.repo.RepackObjects(&git.RepackConfig{}); err != nil {
+		return false, fmt.Errorf("failed to repack objects: %w", err)
+	}
+
+	// The storage caches pack indexes, which still list the packs the repack
+	// replaced
+	if reindexer, ok := r.repo.Storer.(interface{ Reindex() }); ok {
+		reindexer.Reindex()
+	}
+
+	// The storage may already have dropped loose objects it found packed
+	remaining, err := r.looseObjects()
+	if err != nil {
+		return true, err
+	}
+	los := r.repo.Storer.(storer.LooseObjectStorer)
+	for _, hash := range remaining {
+		if err := los.DeleteLooseObject(hash); err != nil {
+			return true, fmt.Errorf("failed to delete loose object %s: %w", hash, err)
+		}
+	}
+
+	r.metrics.RepoGC.Inc()
+	r.logger.WithFields(logrus.Fields{
+		"path":          r.tempDir,
+		"loose_objects": len(loose),
+	}).Info("Repacked repository")
+	return true, nil
+}
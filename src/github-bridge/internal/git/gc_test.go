@@ -0,0 +1,61 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGCRepacksPastLooseObjectThreshold(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/index.md": "# Hello\n"})
+
+	// A blob no ref reaches is pruned
+	dangling := r.repo.Storer.NewEncodedObject()
+	dangling.SetType(plumbing.BlobObject)
+	if _, err := dangling.Writer(); err != nil {
+		t.Fatalf("failed to write dangling blob: %v", err)
+	}
+	if _, err := r.repo.Storer.SetEncodedObject(dangling); err != nil {
+		t.Fatalf("failed to store dangling blob: %v", err)
+	}
+
+	loose, err := r.looseObjects()
+	if err != nil {
+		t.Fatalf("looseObjects failed: %v", err)
+	}
+	if len(loose) == 0 {
+		t.Fatal("expected the test commit to leave loose objects")
+	}
+
+	ran, err := r.GC(len(loose))
+	if err != nil || ran {
+		t.Fatalf("expected no gc at the threshold, got ran=%v err=%v", ran, err)
+	}
+
+	before := testutil.ToFloat64(r.metrics.RepoGC)
+	ran, err = r.GC(len(loose) - 1)
+	if err != nil || !ran {
+		t.Fatalf("expected gc past the threshold, got ran=%v err=%v", ran, err)
+	}
+	if got := testutil.ToFloat64(r.metrics.RepoGC); got != before+1 {
+		t.Errorf("expected repo_gc_total to grow by 1, got %v -> %v", before, got)
+	}
+
+	if loose, err = r.looseObjects(); err != nil || len(loose) != 0 {
+		t.Errorf("expected no loose objects after gc, got %d (err=%v)", len(loose), err)
+	}
+
+	// Every object is still readable from the pack
+	head, err := r.headCommit()
+	if err != nil {
+		t.Fatalf("failed to read HEAD after gc: %v", err)
+	}
+	file, err := head.File("docs/index.md")
+	if err != nil {
+		t.Fatalf("expected docs/index.md after gc: %v", err)
+	}
+	if content, _ := file.Contents(); content != "# Hello\n" {
+		t.Errorf("unexpected content after gc %q", content)
+	}
+}
@@ -0,0 +1,210 @@
+// Package gittest provides an in-memory git backend for exercising push
+// flows without a network remote
+package gittest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/git"
+)
+
+// scheme is the URL scheme served from memory
+const scheme = "memory"
+
+var (
+	installOnce sync.Once
+
+	backendsMu sync.Mutex
+	backends   = make(map[string]*MemoryBackend)
+	nextID     int
+)
+
+// registry resolves memory:// endpoints to the backend named by their host
+type registry struct{}
+
+func (registry) Load(ep *transport.Endpoint) (storer.Storer, error) {
+	backendsMu.Lock()
+	m, ok := backends[ep.Host]
+	backendsMu.Unlock()
+	if !ok {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return m.storer(ep.Path)
+}
+
+// MemoryBackend is a git.Backend whose remotes live in memory. Repositories
+// are keyed by their full name, so clone URLs such as
+// https://github.com/org/repo.git resolve to the repository "org/repo".
+type MemoryBackend struct {
+	id string
+
+	mu    sync.Mutex
+	repos map[string]*gogit.Repository
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	installOnce.Do(func() {
+		client.InstallProtocol(scheme, server.NewClient(registry{}))
+	})
+
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	nextID++
+	m := &MemoryBackend{
+		id:    fmt.Sprintf("backend%d", nextID),
+		repos: make(map[string]*gogit.Repository),
+	}
+	backends[m.id] = m
+	return m
+}
+
+// CreateRepository creates a remote repository with a single commit on
+// branch containing the given files
+func (m *MemoryBackend) CreateRepository(name, branch string, files map[string]string) error {
+	fs := memfs.New()
+	repo, err := gogit.InitWithOptions(memory.NewStorage(), fs, gogit.InitOptions{
+		DefaultBranch: plumbing.NewBranchReferenceName(branch),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to init %s: %w", name, err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	for path, content := range files {
+		f, err := fs.Create(path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, content); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if _, err := worktree.Add(path); err != nil {
+			return err
+		}
+	}
+
+	if _, err := worktree.Commit("initial commit", &gogit.CommitOptions{
+		Author:            &object.Signature{Name: "gittest", Email: "gittest@tekfly.io", When: time.Now()},
+		AllowEmptyCommits: true,
+	}); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.repos[name] = repo
+	return nil
+}
+
+// Clone implements git.Backend, cloning the repository named by opts.URL
+// from memory
+func (m *MemoryBackend) Clone(ctx context.Context, opts git.CloneOptions, logger *logrus.Logger) (*git.Repository, error) {
+	name, err := repoName(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	// The in-process server does not support shallow clones
+	opts.URL = fmt.Sprintf("%s://%s/%s", scheme, m.id, name)
+	opts.FullHistory = true
+
+	return git.Clone(ctx, opts, logger)
+}
+
+// Commits returns the commits on a remote branch, newest first
+func (m *MemoryBackend) Commits(name, branch string) ([]*object.Commit, error) {
+	repo, err := m.repository(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s@%s: %w", name, branch, err)
+	}
+
+	iter, err := repo.Log(&gogit.LogOptions{From: ref.Hash()})
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*object.Commit
+	err = iter.ForEach(func(c *object.Commit) error {
+		commits = append(commits, c)
+		return nil
+	})
+	return commits, err
+}
+
+// File returns the content of path at the tip of a remote branch
+func (m *MemoryBackend) File(name, branch, path string) (string, error) {
+	commits, err := m.Commits(name, branch)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := commits[0].File(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return file.Contents()
+}
+
+func (m *MemoryBackend) repository(name string) (*gogit.Repository, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	repo, ok := m.repos[name]
+	if !ok {
+		return nil, fmt.Errorf("repository %s not found", name)
+	}
+	return repo, nil
+}
+
+func (m *MemoryBackend) storer(path string) (storer.Storer, error) {
+	repo, err := m.repository(strings.Trim(path, "/"))
+	if err != nil {
+		return nil, transport.ErrRepositoryNotFound
+	}
+	return repo.Storer, nil
+}
+
+// repoName extracts "org/repo" from a clone URL
+func repoName(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid clone URL %q: %w", rawURL, err)
+	}
+
+	name := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if name == "" {
+		return "", fmt.Errorf("clone URL %q does not name a repository", rawURL)
+	}
+	return name, nil
+}
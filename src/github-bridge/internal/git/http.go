@@ -0,0 +1,94 @@
+package git
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+// HTTPOptions tunes the shared HTTP transport used for git over HTTPS
+type HTTPOptions struct {
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	KeepAlive           time.Duration
+	EnableHTTP2         bool
+
+	// TLSConfig trusts a private CA or presents a client certificate
+	TLSConfig *tls.Config
+
+	// Metrics counts the connections dialed
+	Metrics *metrics.Metrics
+}
+
+// NewHTTPClient builds a pooled HTTP client whose connections and TLS
+// sessions are reused across clones, fetches and pushes. Every new connection
+// increments the GitHTTPConnections metric, so a rate close to the operation
+// rate means connections are not being reused.
+func NewHTTPClient(opts HTTPOptions) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: opts.KeepAlive,
+	}
+
+	tlsConfig := &tls.Config{}
+	if opts.TLSConfig != nil {
+		tlsConfig = opts.TLSConfig.Clone()
+	}
+	if tlsConfig.ClientSessionCache == nil {
+		// Resume TLS sessions when a pooled connection has been closed
+		tlsConfig.ClientSessionCache = tls.NewLRUClientSessionCache(64)
+	}
+
+	// Kept a plain *http.Transport so its settings can be inspected;
+	// InstallHTTPClient adds rate limit handling on top
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err == nil {
+				opts.Metrics.GitHTTPConnections.Inc()
+			}
+			return conn, err
+		},
+		ForceAttemptHTTP2:     opts.EnableHTTP2,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       opts.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+	if !opts.EnableHTTP2 {
+		// A non-nil empty map is how net/http is told not to negotiate h2
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+// InstallHTTPClient makes go-git use c for every http and https remote,
+// turning GitHub secondary rate limit responses into ErrSecondaryRateLimit
+// counted in m. go-git keeps transports in a process-wide registry, so this
+// affects all repositories cloned afterwards.
+//
+// go-git needs a plain *http.Transport only for endpoints carrying their own
+// CA bundle or proxy, which the bridge never sets: CAs come from the TLS
+// config of the transport and proxies from the environment.
+func InstallHTTPClient(c *http.Client, m *metrics.Metrics) {
+	base := c.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *c
+	wrapped.Transport = &rateLimitTransport{base: base, metrics: m}
+
+	transport := githttp.NewClient(&wrapped)
+	client.InstallProtocol("https", transport)
+	client.InstallProtocol("http", transport)
+}
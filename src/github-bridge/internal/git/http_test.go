@@ -0,0 +1,100 @@
+package git
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+func TestInstalledHTTPClientCarriesGitTraffic(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	m := metrics.Init(prometheus.NewRegistry())
+	httpClient := NewHTTPClient(HTTPOptions{
+		MaxIdleConnsPerHost: 4,
+		IdleConnTimeout:     time.Minute,
+		KeepAlive:           time.Minute,
+		Metrics:             m,
+	})
+	InstallHTTPClient(httpClient, m)
+	t.Cleanup(func() {
+		client.InstallProtocol("https", githttp.DefaultClient)
+		client.InstallProtocol("http", githttp.DefaultClient)
+	})
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{srv.URL + "/org/repo.git"},
+	})
+
+	before := testutil.ToFloat64(m.GitHTTPConnections)
+	for i := 0; i < 2; i++ {
+		if _, err := remote.List(&git.ListOptions{}); err == nil {
+			t.Fatal("expected listing a missing repository to fail")
+		}
+	}
+
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Fatalf("expected 2 requests to reach the server, got %d", got)
+	}
+
+	// Only the tuned transport counts dials, and keep-alive means the second
+	// listing reuses the first connection
+	if got := testutil.ToFloat64(m.GitHTTPConnections) - before; got != 1 {
+		t.Errorf("expected 1 connection dialed by the installed transport, got %v", got)
+	}
+}
+
+func TestHTTPClientTrustsConfiguredCA(t *testing.T) {
+	var requests int32
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	// The test server's certificate stands in for a private CA
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	httpClient := NewHTTPClient(HTTPOptions{
+		MaxIdleConnsPerHost: 1,
+		IdleConnTimeout:     time.Minute,
+		KeepAlive:           time.Minute,
+		TLSConfig:           &tls.Config{RootCAs: roots},
+		Metrics:             metrics.Init(prometheus.NewRegistry()),
+	})
+
+	transport := httpClient.Transport.(*http.Transport)
+	if transport.TLSClientConfig.RootCAs != roots {
+		t.Fatal("expected the configured CA pool on the git transport")
+	}
+
+	resp, err := httpClient.Get(srv.URL + "/org/repo.git/info/refs")
+	if err != nil {
+		t.Fatalf("expected the private CA to be trusted: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("expected 1 request to reach the server, got %d", got)
+	}
+}
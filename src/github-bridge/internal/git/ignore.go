@@ -0,0 +1,43 @@
+package git
+
+import (
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// newIgnoreMatcher compiles gitignore-style patterns, returning nil when
+// there are none
+func newIgnoreMatcher(patterns []string) gitignore.Matcher {
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	parsed := make([]gitignore.Pattern, 0, len(patterns))
+	for _, p := range patterns {
+		parsed = append(parsed, gitignore.ParsePattern(p, nil))
+	}
+	return gitignore.NewMatcher(parsed)
+}
+
+// Ignored reports whether a document path matches the configured ignore
+// patterns. As in git, a file is ignored when any of its parent directories
+// is, regardless of later negations.
+func (r *Repository) Ignored(docPath string) bool {
+	if r.ignore == nil {
+		return false
+	}
+
+	cleaned, err := r.normalizePath(docPath)
+	if err != nil {
+		return false
+	}
+
+	parts := strings.Split(cleaned, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.ignore.Match(parts[:i], true) {
+			return true
+		}
+	}
+	return r.ignore.Match(parts, false)
+}
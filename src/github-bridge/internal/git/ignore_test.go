@@ -0,0 +1,71 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestIgnoredPaths(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.ignore = newIgnoreMatcher([]string{"*.tmp", ".internal/", "build/*.log", "!keep.tmp"})
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"scratch.tmp", true},
+		{"deep/dir/scratch.tmp", true},
+		{"keep.tmp", false},
+		{".internal/state.json", true},
+		{".internal/nested/state.json", true},
+		{"docs/.internal/notes.md", true},
+		{"build/output.log", true},
+		{"build/nested/output.log", false},
+		{"docs/index.md", false},
+		{"internal/config.yaml", false},
+		{"tmp.md", false},
+	}
+
+	for _, tt := range tests {
+		if got := r.Ignored(tt.path); got != tt.want {
+			t.Errorf("Ignored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoredWithoutPatterns(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+
+	if r.Ignored("scratch.tmp") {
+		t.Error("expected nothing to be ignored without patterns")
+	}
+}
+
+func TestSkipDocumentSkipsIgnoredPaths(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"existing.tmp": "keep me"})
+	r.ignore = newIgnoreMatcher([]string{"*.tmp"})
+
+	before := testutil.ToFloat64(r.metrics.DocumentsSkipped)
+
+	for _, tt := range []struct {
+		doc  Document
+		want bool
+	}{
+		{Document{Path: "scratch.tmp", Content: []byte("x"), Operation: "create"}, true},
+		{Document{Path: "existing.tmp", Operation: "delete"}, true},
+		{Document{Path: "docs/index.md", Content: []byte("# Docs"), Operation: "create"}, false},
+	} {
+		skip, err := r.SkipDocument(tt.doc)
+		if err != nil {
+			t.Fatalf("SkipDocument(%s) failed: %v", tt.doc.Path, err)
+		}
+		if skip != tt.want {
+			t.Errorf("SkipDocument(%s) = %v, want %v", tt.doc.Path, skip, tt.want)
+		}
+	}
+
+	if got := testutil.ToFloat64(r.metrics.DocumentsSkipped); got != before+2 {
+		t.Errorf("expected skipped counter to increase by 2, got %v -> %v", before, got)
+	}
+}
@@ -0,0 +1,84 @@
+package git
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// keeperFile is written into directories that would otherwise be empty so
+// that git keeps tracking them
+const keeperFile = ".gitkeep"
+
+// reconcileKeepers adds keeper files to directories emptied by removed paths
+// and drops keepers from directories that gained real files
+func (r *Repository) reconcileKeepers(written, removed []string) error {
+	for _, docPath := range removed {
+		resolved, err := r.ResolvePath(docPath)
+		if err != nil {
+			return err
+		}
+
+		// Only the deepest emptied directory needs a keeper; its parents
+		// then contain it and are no longer empty
+		dir := path.Dir(resolved)
+		if dir == "." {
+			continue
+		}
+
+		empty, err := r.isEmptyDir(dir)
+		if err != nil {
+			return err
+		}
+		if empty {
+			r.logger.WithField("dir", dir).Debug("Keeping empty directory")
+			if err := r.writePath(path.Join(dir, keeperFile), nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, docPath := range written {
+		resolved, err := r.ResolvePath(docPath)
+		if err != nil {
+			return err
+		}
+		if path.Base(resolved) == keeperFile {
+			continue
+		}
+
+		for dir := path.Dir(resolved); dir != "."; dir = path.Dir(dir) {
+			keeper := path.Join(dir, keeperFile)
+			if _, err := os.Stat(r.fullPath(keeper)); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+
+			r.logger.WithField("dir", dir).Debug("Directory no longer empty, removing keeper")
+			if err := r.removePath(keeper); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isEmptyDir reports whether a repository directory exists and has no entries
+func (r *Repository) isEmptyDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(r.fullPath(dir))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// fullPath returns the on-disk location of a resolved repository path
+func (r *Repository) fullPath(repoPath string) string {
+	return filepath.Join(r.tempDir, filepath.FromSlash(repoPath))
+}
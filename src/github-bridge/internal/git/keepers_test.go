@@ -0,0 +1,68 @@
+package git
+
+import (
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+func TestKeeperAddedWhenDirectoryEmptied(t *testing.T) {
+	r := newTestRepository(t, map[string]string{
+		"assets/icons/logo.svg": "<svg/>",
+		"assets/readme.txt":     "assets",
+	})
+	r.keepEmptyDirs = true
+
+	if err := r.ApplyDocuments([]Document{{Path: "assets/icons/logo.svg", Operation: "delete"}}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+
+	if got := status.File("assets/icons/.gitkeep").Staging; got != git.Added {
+		t.Errorf("expected keeper to be staged, got %q", got)
+	}
+	if got := status.File("assets/icons/logo.svg").Staging; got != git.Deleted {
+		t.Errorf("expected deletion to be staged, got %q", got)
+	}
+	if _, ok := status["assets/.gitkeep"]; ok {
+		t.Error("non-empty parent directory should not get a keeper")
+	}
+}
+
+func TestKeeperRemovedWhenDirectoryGainsFiles(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"assets/icons/.gitkeep": ""})
+	r.keepEmptyDirs = true
+
+	if err := r.ApplyDocuments([]Document{{Path: "assets/icons/logo.svg", Content: []byte("<svg/>"), Operation: "create"}}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+
+	if got := status.File("assets/icons/.gitkeep").Staging; got != git.Deleted {
+		t.Errorf("expected keeper removal to be staged, got %q", got)
+	}
+	if _, err := os.Stat(r.fullPath("assets/icons/.gitkeep")); !os.IsNotExist(err) {
+		t.Errorf("expected keeper to be removed from disk, got %v", err)
+	}
+}
+
+func TestKeepersDisabledByDefault(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"assets/logo.svg": "<svg/>"})
+
+	if err := r.ApplyDocuments([]Document{{Path: "assets/logo.svg", Operation: "delete"}}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	if _, err := os.Stat(r.fullPath("assets/.gitkeep")); !os.IsNotExist(err) {
+		t.Errorf("expected no keeper without KeepEmptyDirs, got %v", err)
+	}
+}
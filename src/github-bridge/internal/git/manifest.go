@@ -0,0 +1,78 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// manifestSections lists the manifest groups in the order they are written
+var manifestSections = []struct {
+	title string
+	code  git.StatusCode
+}{
+	{"Added", git.Added},
+	{"Modified", git.Modified},
+	{"Deleted", git.Deleted},
+}
+
+// formatManifest renders the staged changes as a commit body section grouped
+// by operation. At most limit paths are listed when limit is positive.
+func formatManifest(status git.Status, limit int) string {
+	groups := make(map[git.StatusCode][]string)
+	total := 0
+	for path, fileStatus := range status {
+		code := fileStatus.Staging
+		switch code {
+		case git.Unmodified, git.Untracked:
+			continue
+		case git.Added, git.Deleted:
+		default:
+			code = git.Modified
+		}
+		groups[code] = append(groups[code], path)
+		total++
+	}
+
+	if total == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	listed := 0
+	for _, section := range manifestSections {
+		paths := groups[section.code]
+		if len(paths) == 0 || (limit > 0 && listed >= limit) {
+			continue
+		}
+		sort.Strings(paths)
+
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(section.title + ":\n")
+		for _, path := range paths {
+			if limit > 0 && listed >= limit {
+				break
+			}
+			b.WriteString("  - " + path + "\n")
+			listed++
+		}
+	}
+
+	if remaining := total - listed; remaining > 0 {
+		fmt.Fprintf(&b, "\n... and %d more files\n", remaining)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// appendManifest appends a manifest section to a commit message body
+func appendManifest(message, manifest string) string {
+	if manifest == "" {
+		return message
+	}
+	return strings.TrimRight(message, "\n") + "\n\n" + manifest + "\n"
+}
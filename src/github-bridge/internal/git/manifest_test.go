@@ -0,0 +1,83 @@
+package git
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestFormatManifestGroupsByOperation(t *testing.T) {
+	status := git.Status{
+		"b.txt":        {Staging: git.Added},
+		"a.txt":        {Staging: git.Added},
+		"conf/app.yml": {Staging: git.Modified},
+		"old.txt":      {Staging: git.Deleted},
+		"scratch.tmp":  {Staging: git.Untracked, Worktree: git.Untracked},
+	}
+
+	want := strings.Join([]string{
+		"Added:",
+		"  - a.txt",
+		"  - b.txt",
+		"",
+		"Modified:",
+		"  - conf/app.yml",
+		"",
+		"Deleted:",
+		"  - old.txt",
+	}, "\n")
+
+	if got := formatManifest(status, 0); got != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatManifestTruncates(t *testing.T) {
+	status := git.Status{
+		"a.txt": {Staging: git.Added},
+		"b.txt": {Staging: git.Added},
+		"c.txt": {Staging: git.Modified},
+		"d.txt": {Staging: git.Deleted},
+		"e.txt": {Staging: git.Deleted},
+	}
+
+	want := strings.Join([]string{
+		"Added:",
+		"  - a.txt",
+		"  - b.txt",
+		"",
+		"Modified:",
+		"  - c.txt",
+		"",
+		"... and 2 more files",
+	}, "\n")
+
+	if got := formatManifest(status, 3); got != want {
+		t.Errorf("unexpected manifest:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCommitMessageIncludesManifest(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"old.txt": "old"})
+	r.fileManifest = true
+
+	hashes, err := r.CommitDocuments([]Document{
+		{Path: "new.txt", Content: []byte("new"), Operation: "create"},
+		{Path: "old.txt", Operation: "delete"},
+	}, "Sync documents", testAuthor, false)
+	if err != nil {
+		t.Fatalf("CommitDocuments failed: %v", err)
+	}
+
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hashes[0]))
+	if err != nil {
+		t.Fatalf("failed to load commit: %v", err)
+	}
+
+	want := "Sync documents\n\nAdded:\n  - new.txt\n\nDeleted:\n  - old.txt\n"
+	if commit.Message != want {
+		t.Errorf("unexpected commit message %q, want %q", commit.Message, want)
+	}
+}
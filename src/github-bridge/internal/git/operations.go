@@ -2,28 +2,65 @@ package git
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/transform"
 )
 
 // Repository manages Git operations
 type Repository struct {
-	repo      *git.Repository
-	worktree  *git.Worktree
-	auth      transport.AuthMethod
+	repo       *git.Repository
+	worktree   *git.Worktree
+	auth       transport.AuthMethod
 	remoteName string
-	logger    *logrus.Logger
-	tempDir   string
+	logger     *logrus.Logger
+	tempDir    string
+	netRetries int
+	pathPrefix string
+
+	keepEmptyDirs    bool
+	strictOperations bool
+	lowercasePaths   bool
+	ignore           gitignore.Matcher
+	transformer      transform.Transformer
+
+	fileManifest  bool
+	manifestLimit int
+
+	location *time.Location
+
+	signKey      *openpgp.Entity
+	verifySigner bool
+
+	// casePaths maps lowercased document paths to the path first written
+	casePaths map[string]string
+
+	// submodules holds the submodule directories once read from .gitmodules
+	submodules           []string
+	rejectSubmodulePaths bool
+
+	syncWrites bool
+
+	rejectBinary bool
+
+	// stage holds changes staged in memory by StageDocuments
+	stage *treeStage
+
+	metrics *metrics.Metrics
 }
 
 // CloneOptions contains options for cloning a repository
@@ -33,10 +70,113 @@ type CloneOptions struct {
 	Token      string
 	TempDir    string
 	RemoteName string
+	NetRetries int    // retries for transient network errors
+	PathPrefix string // directory prepended to every document path
+
+	// KeepEmptyDirs writes a .gitkeep into directories emptied by deletes
+	KeepEmptyDirs bool
+
+	// StrictOperations rejects creates of existing paths and updates or
+	// deletes of missing ones
+	StrictOperations bool
+
+	// RejectSubmodulePaths fails documents inside a submodule instead of
+	// skipping them
+	RejectSubmodulePaths bool
+
+	// SyncWrites fsyncs every written file before it is renamed into place,
+	// for work directories that outlive the process
+	SyncWrites bool
+
+	// RejectBinary fails documents whose content is detected as binary
+	RejectBinary bool
+
+	// LowercasePaths lowercases document paths before ignore patterns and
+	// the path prefix are applied
+	LowercasePaths bool
+
+	// IgnorePaths are gitignore-style patterns for document paths that are
+	// silently skipped
+	IgnorePaths []string
+
+	// Transformer rewrites document content before it is written
+	Transformer transform.Transformer
+
+	// FullHistory disables the default shallow clone
+	FullHistory bool
+
+	// Location is the time zone for commit signatures, UTC when nil
+	Location *time.Location
+
+	// MinFreeDiskBytes refuses to clone when TempDir has less free space
+	MinFreeDiskBytes uint64
+
+	// FileManifest appends the changed paths to commit messages, listing at
+	// most ManifestLimit paths when it is positive
+	FileManifest  bool
+	ManifestLimit int
+
+	// SignKey signs commits and tags when set. With VerifySigner every
+	// commit is refused unless the key has an identity for the author email.
+	SignKey      *openpgp.Entity
+	VerifySigner bool
+
+	// Metrics receives the repository's skipped documents, strict mode
+	// mismatches and work directory free space
+	Metrics *metrics.Metrics
+}
+
+// retryBaseDelay is the initial backoff between network retries
+var retryBaseDelay = 500 * time.Millisecond
+
+// withRetry runs fn, retrying up to retries times with exponential backoff
+// while it fails with a transient network error. A secondary rate limit is
+// retried after exactly the wait GitHub asked for instead. Failures retrying
+// cannot fix are returned wrapped with ErrPermanent.
+func withRetry(ctx context.Context, logger *logrus.Logger, operation string, retries int, fn func() error) error {
+	delay := retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || !IsTransient(err) {
+			return permanent(err)
+		}
+
+		wait := delay
+		var limited *ErrSecondaryRateLimit
+		if errors.As(err, &limited) {
+			wait = limited.RetryAfter
+		} else {
+			delay *= 2
+		}
+
+		logger.WithError(err).WithFields(logrus.Fields{
+			"operation": operation,
+			"attempt":   attempt + 1,
+			"delay":     wait,
+		}).Warn("Transient git network error, retrying")
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return err
+		}
+	}
 }
 
 // Clone creates a new Repository by cloning from remote
 func Clone(ctx context.Context, opts CloneOptions, logger *logrus.Logger) (*Repository, error) {
+	prefix := ""
+	if opts.PathPrefix != "" {
+		var err error
+		if prefix, err = cleanRelativePath(opts.PathPrefix); err != nil {
+			return nil, fmt.Errorf("invalid path prefix: %w", err)
+		}
+	}
+
+	if err := checkFreeDisk(opts.TempDir, opts.MinFreeDiskBytes, opts.Metrics, logger); err != nil {
+		return nil, err
+	}
+
 	// Create temporary directory
 	tempDir := filepath.Join(opts.TempDir, fmt.Sprintf("repo-%d", time.Now().UnixNano()))
 	if err := os.MkdirAll(tempDir, 0755); err != nil {
@@ -57,6 +197,12 @@ func Clone(ctx context.Context, opts CloneOptions, logger *logrus.Logger) (*Repo
 		ReferenceName: plumbing.NewBranchReferenceName(opts.Branch),
 		SingleBranch:  true,
 		Depth:         1, // Shallow clone for performance
+
+		// Submodules are left uninitialized; documents never write into them
+		RecurseSubmodules: git.NoRecurseSubmodules,
+	}
+	if opts.FullHistory {
+		cloneOpts.Depth = 0
 	}
 
 	logger.WithFields(logrus.Fields{
@@ -64,7 +210,16 @@ func Clone(ctx context.Context, opts CloneOptions, logger *logrus.Logger) (*Repo
 		"branch": opts.Branch,
 	}).Info("Cloning repository")
 
-	repo, err := git.PlainCloneContext(ctx, tempDir, false, cloneOpts)
+	var repo *git.Repository
+	err := withRetry(ctx, logger, "clone", opts.NetRetries, func() error {
+		// A failed clone can leave a partial repository behind
+		if err := os.RemoveAll(tempDir); err != nil {
+			return err
+		}
+		var cloneErr error
+		repo, cloneErr = git.PlainCloneContext(ctx, tempDir, false, cloneOpts)
+		return cloneErr
+	})
 	if err != nil {
 		os.RemoveAll(tempDir)
 		return nil, fmt.Errorf("failed to clone repository: %w", err)
@@ -83,13 +238,41 @@ func Clone(ctx context.Context, opts CloneOptions, logger *logrus.Logger) (*Repo
 		remoteName: opts.RemoteName,
 		logger:     logger,
 		tempDir:    tempDir,
+		netRetries: opts.NetRetries,
+		pathPrefix: prefix,
+
+		keepEmptyDirs:    opts.KeepEmptyDirs,
+		strictOperations: opts.StrictOperations,
+		lowercasePaths:   opts.LowercasePaths,
+		ignore:           newIgnoreMatcher(opts.IgnorePaths),
+		transformer:      opts.Transformer,
+		fileManifest:     opts.FileManifest,
+		manifestLimit:    opts.ManifestLimit,
+		location:         opts.Location,
+		signKey:          opts.SignKey,
+		verifySigner:     opts.VerifySigner,
+		metrics:          opts.Metrics,
+
+		rejectSubmodulePaths: opts.RejectSubmodulePaths,
+		syncWrites:           opts.SyncWrites,
+
+		rejectBinary: opts.RejectBinary,
 	}, nil
 }
 
 // WriteFile writes content to a file in the repository
-func (r *Repository) WriteFile(path string, content []byte) error {
-	fullPath := filepath.Join(r.tempDir, path)
-	
+func (r *Repository) WriteFile(docPath string, content []byte) error {
+	path, err := r.ResolvePath(docPath)
+	if err != nil {
+		return err
+	}
+	return r.writePath(path, content)
+}
+
+// writePath writes and stages a file at a resolved repository path
+func (r *Repository) writePath(path string, content []byte) error {
+	fullPath := r.fullPath(path)
+
 	// Create directory if needed
 	dir := filepath.Dir(fullPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -97,7 +280,7 @@ func (r *Repository) WriteFile(path string, content []byte) error {
 	}
 
 	// Write file
-	if err := os.WriteFile(fullPath, content, 0644); err != nil {
+	if err := writeFileAtomic(fullPath, content, 0644, r.syncWrites); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -110,16 +293,30 @@ func (r *Repository) WriteFile(path string, content []byte) error {
 }
 
 // RemoveFile removes a file from the repository
-func (r *Repository) RemoveFile(path string) error {
-	fullPath := filepath.Join(r.tempDir, path)
-	
+func (r *Repository) RemoveFile(docPath string) error {
+	path, err := r.ResolvePath(docPath)
+	if err != nil {
+		return err
+	}
+	return r.removePath(path)
+}
+
+// removePath deletes and unstages a file at a resolved repository path
+func (r *Repository) removePath(path string) error {
+	fullPath := r.fullPath(path)
+
 	// Remove file
 	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove file: %w", err)
 	}
 
-	// Remove from git
+	// Remove from git; a path that is not tracked is already deleted as far
+	// as the commit is concerned, which keeps deletes idempotent
 	if _, err := r.worktree.Remove(path); err != nil {
+		if errors.Is(err, index.ErrEntryNotFound) {
+			r.logger.WithField("path", path).Debug("File not tracked, nothing to remove")
+			return nil
+		}
 		return fmt.Errorf("failed to remove file from git: %w", err)
 	}
 
@@ -139,12 +336,19 @@ func (r *Repository) Commit(message string, author CommitAuthor) (string, error)
 	}
 
 	// Create commit
+	authorSig, committerSig := r.signatures(author)
 	commitOpts := &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  author.Name,
-			Email: author.Email,
-			When:  time.Now(),
-		},
+		Author:    &authorSig,
+		Committer: &committerSig,
+	}
+
+	if r.signKey != nil {
+		if r.verifySigner {
+			if err := VerifySignerIdentity(r.signKey, committerSig.Email); err != nil {
+				return "", err
+			}
+		}
+		commitOpts.SignKey = r.signKey
 	}
 
 	hash, err := r.worktree.Commit(message, commitOpts)
@@ -165,8 +369,10 @@ func (r *Repository) Push(ctx context.Context) error {
 	}
 
 	r.logger.Info("Pushing to remote")
-	
-	err := r.repo.PushContext(ctx, pushOpts)
+
+	err := withRetry(ctx, r.logger, "push", r.netRetries, func() error {
+		return r.repo.PushContext(ctx, pushOpts)
+	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		return fmt.Errorf("failed to push: %w", err)
 	}
@@ -182,7 +388,9 @@ func (r *Repository) Pull(ctx context.Context) error {
 		Progress:   nil,
 	}
 
-	err := r.worktree.PullContext(ctx, pullOpts)
+	err := withRetry(ctx, r.logger, "pull", r.netRetries, func() error {
+		return r.worktree.PullContext(ctx, pullOpts)
+	})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		return fmt.Errorf("failed to pull: %w", err)
 	}
@@ -190,6 +398,44 @@ func (r *Repository) Pull(ctx context.Context) error {
 	return nil
 }
 
+// Refresh readies a clone kept from an earlier intent for the next one: it
+// fetches the branch, hard resets the worktree to the remote tip, removes
+// untracked files and forgets the paths and staged changes of the last run
+func (r *Repository) Refresh(ctx context.Context) error {
+	head, err := r.repo.Head()
+	if err != nil {
+		return fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	fetchOpts := &git.FetchOptions{
+		RemoteName: r.remoteName,
+		Auth:       r.auth,
+		Force:      true,
+	}
+	err = withRetry(ctx, r.logger, "fetch", r.netRetries, func() error {
+		return r.repo.FetchContext(ctx, fetchOpts)
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	remote, err := r.repo.Reference(plumbing.NewRemoteReferenceName(r.remoteName, head.Name().Short()), true)
+	if err != nil {
+		return fmt.Errorf("failed to read remote branch: %w", err)
+	}
+	if err := r.worktree.Reset(&git.ResetOptions{Commit: remote.Hash(), Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to reset worktree: %w", err)
+	}
+	if err := r.worktree.Clean(&git.CleanOptions{Dir: true}); err != nil {
+		return fmt.Errorf("failed to clean worktree: %w", err)
+	}
+
+	r.casePaths = nil
+	r.submodules = nil
+	r.stage = nil
+	return nil
+}
+
 // GetStatus returns the current repository status
 func (r *Repository) GetStatus() (git.Status, error) {
 	return r.worktree.Status()
@@ -204,34 +450,220 @@ func (r *Repository) Cleanup() error {
 	return nil
 }
 
-// CommitAuthor represents commit author information
+// CommitAuthor represents commit author information. The committer, who
+// signs the commit, is CommitterName and CommitterEmail, or the author when
+// they are empty.
 type CommitAuthor struct {
 	Name  string
 	Email string
+	When  time.Time // zero means now
+
+	CommitterName  string
+	CommitterEmail string
+}
+
+// Committer returns the name and email the commit is committed with
+func (a CommitAuthor) Committer() (name, email string) {
+	if a.CommitterEmail == "" {
+		return a.Name, a.Email
+	}
+	return a.CommitterName, a.CommitterEmail
+}
+
+// signatures returns the author and committer signatures for a commit
+func (r *Repository) signatures(a CommitAuthor) (author, committer object.Signature) {
+	when := r.signatureTime(a)
+	name, email := a.Committer()
+	return object.Signature{Name: a.Name, Email: a.Email, When: when},
+		object.Signature{Name: name, Email: email, When: when}
+}
+
+// signatureTime returns the signature time for author in the configured
+// commit time zone
+func (r *Repository) signatureTime(author CommitAuthor) time.Time {
+	when := author.When
+	if when.IsZero() {
+		when = time.Now()
+	}
+
+	loc := r.location
+	if loc == nil {
+		loc = time.UTC
+	}
+	return when.In(loc)
+}
+
+// CommitDocuments applies documents and commits the result. When perDocument
+// is set every document is applied and committed on its own, using its Message
+// when present. It returns the hashes of the commits created, which is empty
+// when the documents produced no changes.
+func (r *Repository) CommitDocuments(documents []Document, message string, author CommitAuthor, perDocument bool) ([]string, error) {
+	if !perDocument {
+		hash, err := r.applyAndCommit(documents, message, author)
+		if err != nil || hash == "" {
+			return nil, err
+		}
+		return []string{hash}, nil
+	}
+
+	var hashes []string
+	for _, doc := range documents {
+		docMessage := message
+		if doc.Message != "" {
+			docMessage = doc.Message
+		}
+
+		hash, err := r.applyAndCommit([]Document{doc}, docMessage, author)
+		if err != nil {
+			return hashes, fmt.Errorf("failed to commit %s: %w", doc.Path, err)
+		}
+		if hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes, nil
+}
+
+// applyAndCommit applies documents and commits them as a single commit,
+// returning an empty hash when the worktree is left clean
+func (r *Repository) applyAndCommit(documents []Document, message string, author CommitAuthor) (string, error) {
+	if err := r.ApplyDocuments(documents); err != nil {
+		return "", fmt.Errorf("failed to apply documents: %w", err)
+	}
+
+	return r.CommitChanges(message, author)
+}
+
+// CommitChanges commits whatever has been applied to the worktree, returning
+// an empty hash when there is nothing to commit
+func (r *Repository) CommitChanges(message string, author CommitAuthor) (string, error) {
+	status, err := r.worktree.Status()
+	if err != nil {
+		return "", fmt.Errorf("failed to get status: %w", err)
+	}
+
+	if status.IsClean() {
+		return "", nil
+	}
+
+	if r.fileManifest {
+		message = appendManifest(message, formatManifest(status, r.manifestLimit))
+	}
+
+	return r.Commit(message, author)
 }
 
-// ApplyDocuments applies a set of document changes to the repository
+// ApplyDocuments applies a set of document changes to the repository. The
+// documents are expected to have passed SkipDocument.
 func (r *Repository) ApplyDocuments(documents []Document) error {
+	var written, removed []string
 	for _, doc := range documents {
-		switch doc.Operation {
+		if r.strictOperations {
+			if err := r.checkOperation(doc); err != nil {
+				return err
+			}
+		}
+
+		switch doc.EffectiveOperation() {
 		case "create", "update":
-			if err := r.WriteFile(doc.Path, doc.Content); err != nil {
+			content, err := r.transform(doc)
+			if err != nil {
+				return err
+			}
+			renamed, err := r.removeRenamed(doc)
+			if err != nil {
+				return fmt.Errorf("failed to remove previous path of %s: %w", doc.Path, err)
+			}
+			if renamed {
+				removed = append(removed, doc.PreviousPath)
+			}
+			if err := r.WriteFile(doc.Path, content); err != nil {
 				return fmt.Errorf("failed to write %s: %w", doc.Path, err)
 			}
+			written = append(written, doc.Path)
 		case "delete":
 			if err := r.RemoveFile(doc.Path); err != nil {
 				return fmt.Errorf("failed to remove %s: %w", doc.Path, err)
 			}
+			removed = append(removed, doc.Path)
 		default:
 			r.logger.WithField("operation", doc.Operation).Warn("Unknown operation")
 		}
 	}
+
+	if r.keepEmptyDirs {
+		if err := r.reconcileKeepers(written, removed); err != nil {
+			return fmt.Errorf("failed to update %s files: %w", keeperFile, err)
+		}
+	}
+
 	return nil
 }
 
+// SkipDocument reports whether doc is skipped for an ignored path or one
+// inside a submodule, and fails on case collisions and rejected submodule
+// paths. ApplyDocuments, StageDocuments and CommitDocuments trust their
+// input, so callers run it once on every document before handing it over.
+func (r *Repository) SkipDocument(doc Document) (bool, error) {
+	if r.Ignored(doc.Path) {
+		r.logger.WithField("path", doc.Path).Debug("Skipping ignored document path")
+		r.metrics.DocumentsSkipped.Inc()
+		return true, nil
+	}
+
+	inSubmodule, err := r.CheckSubmodule(doc.Path)
+	if err != nil {
+		return false, err
+	}
+	if inSubmodule {
+		r.logger.WithField("path", doc.Path).Warn("Skipping document inside a submodule")
+		r.metrics.DocumentsSkipped.Inc()
+		return true, nil
+	}
+
+	return false, r.CheckCaseCollision(doc.Path)
+}
+
+// transform returns doc's content as rewritten by the configured transformer.
+// Transformers rewrite text, so binary content is passed through unchanged.
+func (r *Repository) transform(doc Document) ([]byte, error) {
+	binary, err := r.checkBinary(doc)
+	if err != nil {
+		return nil, err
+	}
+	if binary || r.transformer == nil {
+		return doc.Content, nil
+	}
+
+	content, err := r.transformer.Transform(doc.Path, doc.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform %s: %w", doc.Path, err)
+	}
+	return content, nil
+}
+
 // Document represents a document to be applied to the repository
 type Document struct {
 	Path      string
 	Content   []byte
 	Operation string // create, update, delete
-}
\ No newline at end of file
+	Message   string // optional per-document commit message
+
+	// Tombstone marks a soft-deleted document: its path is removed whatever
+	// the operation, and its content is never written
+	Tombstone bool
+
+	// PreviousPath is where the document was written before its path
+	// changed; a create or update removes it in the same commit
+	PreviousPath string
+}
+
+// EffectiveOperation returns the operation applied for the document, which
+// is always a delete for tombstones
+func (d Document) EffectiveOperation() string {
+	if d.Tombstone {
+		return "delete"
+	}
+	return d.Operation
+}
@@ -0,0 +1,364 @@
+package git
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/transform"
+)
+
+var testAuthor = CommitAuthor{Name: "Virtual DOM Bot", Email: "bot@tekfly.io"}
+
+// newTestRepository initialises a local repository with a single commit
+// containing the given files
+func newTestRepository(t testing.TB, files map[string]string) *Repository {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatalf("failed to init repository: %v", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+
+	files["README.md"] = "test repository\n"
+	for path, content := range files {
+		fullPath := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if _, err := worktree.Add(path); err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+	}
+
+	if _, err := worktree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@tekfly.io", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("failed to create initial commit: %v", err)
+	}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	return &Repository{
+		repo:       repo,
+		worktree:   worktree,
+		remoteName: "origin",
+		logger:     logger,
+		tempDir:    dir,
+		metrics:    metrics.Init(prometheus.NewRegistry()),
+	}
+}
+
+// commitCount returns the number of commits reachable from HEAD
+func commitCount(t *testing.T, r *Repository) int {
+	t.Helper()
+
+	head, err := r.repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+
+	iter, err := r.repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		t.Fatalf("failed to get log: %v", err)
+	}
+
+	count := 0
+	iter.ForEach(func(*object.Commit) error {
+		count++
+		return nil
+	})
+	return count
+}
+
+func TestCommitDocumentsPerDocument(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"old.txt": "old"})
+
+	docs := []Document{
+		{Path: "a.txt", Content: []byte("a"), Operation: "create", Message: "Add a"},
+		{Path: "dir/b.txt", Content: []byte("b"), Operation: "create"},
+		{Path: "old.txt", Operation: "delete", Message: "Remove old"},
+	}
+
+	hashes, err := r.CommitDocuments(docs, "intent message", testAuthor, true)
+	if err != nil {
+		t.Fatalf("CommitDocuments failed: %v", err)
+	}
+
+	if len(hashes) != len(docs) {
+		t.Fatalf("expected %d commits, got %d", len(docs), len(hashes))
+	}
+
+	if got := commitCount(t, r); got != len(docs)+1 {
+		t.Errorf("expected %d commits in history, got %d", len(docs)+1, got)
+	}
+
+	wantMessages := []string{"Add a", "intent message", "Remove old"}
+	for i, hash := range hashes {
+		commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+		if err != nil {
+			t.Fatalf("failed to load commit %s: %v", hash, err)
+		}
+		if commit.Message != wantMessages[i] {
+			t.Errorf("commit %d: expected message %q, got %q", i, wantMessages[i], commit.Message)
+		}
+	}
+}
+
+func TestCommitDocumentsPerIntent(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+
+	docs := []Document{
+		{Path: "a.txt", Content: []byte("a"), Operation: "create"},
+		{Path: "b.txt", Content: []byte("b"), Operation: "create"},
+	}
+
+	hashes, err := r.CommitDocuments(docs, "intent message", testAuthor, false)
+	if err != nil {
+		t.Fatalf("CommitDocuments failed: %v", err)
+	}
+
+	if len(hashes) != 1 {
+		t.Fatalf("expected 1 commit, got %d", len(hashes))
+	}
+
+	if got := commitCount(t, r); got != 2 {
+		t.Errorf("expected 2 commits in history, got %d", got)
+	}
+}
+
+func TestCommitDocumentsNoChanges(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"a.txt": "a"})
+
+	docs := []Document{{Path: "a.txt", Content: []byte("a"), Operation: "update"}}
+
+	for _, perDocument := range []bool{false, true} {
+		hashes, err := r.CommitDocuments(docs, "intent message", testAuthor, perDocument)
+		if err != nil {
+			t.Fatalf("CommitDocuments failed: %v", err)
+		}
+		if len(hashes) != 0 {
+			t.Errorf("expected no commits, got %d", len(hashes))
+		}
+	}
+}
+
+func TestApplyDocumentsWithPathPrefix(t *testing.T) {
+	r := newTestRepository(t, map[string]string{
+		"foo/existing.txt": "v1",
+		"foo/stale.txt":    "stale",
+		"stale.txt":        "outside prefix",
+	})
+	r.pathPrefix = "foo"
+
+	docs := []Document{
+		{Path: "new.txt", Content: []byte("created"), Operation: "create"},
+		{Path: "existing.txt", Content: []byte("v2"), Operation: "update"},
+		{Path: "stale.txt", Operation: "delete"},
+	}
+
+	if err := r.ApplyDocuments(docs); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+
+	want := map[string]git.StatusCode{
+		"foo/new.txt":      git.Added,
+		"foo/existing.txt": git.Modified,
+		"foo/stale.txt":    git.Deleted,
+	}
+	for path, code := range want {
+		if got := status.File(path).Staging; got != code {
+			t.Errorf("%s: expected staging status %q, got %q", path, code, got)
+		}
+	}
+
+	if len(status) != len(want) {
+		t.Errorf("expected %d changed paths, got %d: %v", len(want), len(status), status)
+	}
+
+	if _, err := os.Stat(filepath.Join(r.tempDir, "stale.txt")); err != nil {
+		t.Errorf("file outside the prefix should be untouched: %v", err)
+	}
+}
+
+func TestWriteFileRejectsEscapingPaths(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.pathPrefix = "foo"
+
+	for _, path := range []string{"../outside.txt", "a/../../outside.txt", "/etc/passwd", "", ".git/config"} {
+		if err := r.WriteFile(path, []byte("x")); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("WriteFile(%q): expected ErrInvalidPath, got %v", path, err)
+		}
+	}
+}
+
+func TestRemoveFileAlreadyAbsent(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+
+	if err := r.RemoveFile("missing/file.txt"); err != nil {
+		t.Fatalf("expected removing an absent file to be a no-op, got %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if !status.IsClean() {
+		t.Errorf("expected clean worktree, got %v", status)
+	}
+}
+
+func TestRemoveFileTracked(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"dir/tracked.txt": "content"})
+
+	if err := r.RemoveFile("dir/tracked.txt"); err != nil {
+		t.Fatalf("RemoveFile failed: %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if got := status.File("dir/tracked.txt").Staging; got != git.Deleted {
+		t.Errorf("expected staged deletion, got %q", got)
+	}
+
+	if _, err := os.Stat(filepath.Join(r.tempDir, "dir", "tracked.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed from disk, got %v", err)
+	}
+}
+
+func TestApplyDocumentsTombstoneRemovesFile(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/old.md": "old"})
+
+	// The tombstone's operation and payload are ignored
+	if err := r.ApplyDocuments([]Document{{
+		Path:      "docs/old.md",
+		Content:   []byte(`{"deleted":true}`),
+		Operation: "update",
+		Tombstone: true,
+	}}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if got := status.File("docs/old.md").Staging; got != git.Deleted {
+		t.Errorf("expected staged deletion, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(r.tempDir, "docs", "old.md")); !os.IsNotExist(err) {
+		t.Errorf("expected file to be removed from disk, got %v", err)
+	}
+}
+
+func TestApplyDocumentsTombstoneAlreadyAbsent(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/index.md": "index"})
+	r.strictOperations = true
+
+	if err := r.ApplyDocuments([]Document{{
+		Path:      "docs/gone.md",
+		Content:   []byte(`{"deleted":true}`),
+		Operation: "create",
+		Tombstone: true,
+	}}); err != nil {
+		t.Fatalf("expected a tombstone for an absent file to be a no-op, got %v", err)
+	}
+
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus failed: %v", err)
+	}
+	if !status.IsClean() {
+		t.Errorf("expected clean worktree, got %v", status)
+	}
+}
+
+func TestApplyDocumentsTransformerRejectsDocument(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.transformer = transform.Func(func(path string, content []byte) ([]byte, error) {
+		if path == "secret.txt" {
+			return nil, errors.New("contains secret")
+		}
+		return append([]byte("// generated\n"), content...), nil
+	})
+
+	err := r.ApplyDocuments([]Document{
+		{Path: "ok.txt", Content: []byte("fine"), Operation: "create"},
+		{Path: "secret.txt", Content: []byte("key"), Operation: "create"},
+	})
+	if err == nil {
+		t.Fatal("expected transformer error to reject the document")
+	}
+
+	content, err := os.ReadFile(r.fullPath("ok.txt"))
+	if err != nil {
+		t.Fatalf("failed to read transformed file: %v", err)
+	}
+	if string(content) != "// generated\nfine" {
+		t.Errorf("unexpected transformed content %q", content)
+	}
+	if _, err := os.Stat(r.fullPath("secret.txt")); !os.IsNotExist(err) {
+		t.Errorf("rejected document should not be written, got %v", err)
+	}
+}
+
+func TestCommitSignatureUsesConfiguredTimezone(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.location = time.FixedZone("UTC+05:30", 5*3600+1800)
+
+	hash, err := r.applyAndCommit([]Document{{Path: "a.txt", Content: []byte("a"), Operation: "create"}}, "msg", testAuthor)
+	if err != nil {
+		t.Fatalf("applyAndCommit failed: %v", err)
+	}
+
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		t.Fatalf("failed to load commit: %v", err)
+	}
+
+	for name, sig := range map[string]object.Signature{"author": commit.Author, "committer": commit.Committer} {
+		if _, offset := sig.When.Zone(); offset != 5*3600+1800 {
+			t.Errorf("%s: expected +05:30 offset, got %ds", name, offset)
+		}
+	}
+}
+
+func TestSignatureTimeConvertsSuppliedTime(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+
+	supplied := time.Date(2024, 3, 1, 12, 0, 0, 0, time.FixedZone("PST", -8*3600))
+	got := r.signatureTime(CommitAuthor{When: supplied})
+
+	if got.Location() != time.UTC {
+		t.Errorf("expected UTC by default, got %v", got.Location())
+	}
+	if !got.Equal(supplied) || got.Hour() != 20 {
+		t.Errorf("expected supplied instant in UTC, got %v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCaseCollision is returned when two documents of one intent have paths
+// differing only in case, which clobber each other on case-insensitive
+// filesystems
+var ErrCaseCollision = errors.New("document paths differ only in case")
+
+// normalizePath cleans a document path and applies the configured case
+// normalization. Ignore patterns and the path prefix see the result.
+func (r *Repository) normalizePath(docPath string) (string, error) {
+	cleaned, err := cleanRelativePath(docPath)
+	if err != nil {
+		return "", err
+	}
+
+	if r.lowercasePaths {
+		cleaned = strings.ToLower(cleaned)
+	}
+	return cleaned, nil
+}
+
+// CheckCaseCollision records docPath as written by this repository's intent
+// and fails when an earlier document's path differs from it only in case.
+// Paths that lowercasing folds together collide as well.
+func (r *Repository) CheckCaseCollision(docPath string) error {
+	cleaned, err := cleanRelativePath(docPath)
+	if err != nil {
+		return err
+	}
+
+	if r.casePaths == nil {
+		r.casePaths = make(map[string]string)
+	}
+
+	folded := strings.ToLower(cleaned)
+	if prev, ok := r.casePaths[folded]; ok && prev != cleaned {
+		return fmt.Errorf("%w: %s and %s", ErrCaseCollision, prev, cleaned)
+	}
+	r.casePaths[folded] = cleaned
+	return nil
+}
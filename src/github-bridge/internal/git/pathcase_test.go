@@ -0,0 +1,57 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLowercasePathsNormalizeBeforePrefixAndIgnore(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.lowercasePaths = true
+	r.pathPrefix = "Docs"
+	r.ignore = newIgnoreMatcher([]string{"drafts/"})
+
+	got, err := r.ResolvePath("Guides/Config.YAML")
+	if err != nil {
+		t.Fatalf("ResolvePath failed: %v", err)
+	}
+	if got != "Docs/guides/config.yaml" {
+		t.Errorf("expected the document path lowercased under the prefix, got %q", got)
+	}
+
+	if !r.Ignored("Drafts/Plan.md") {
+		t.Error("expected ignore patterns to match the lowercased path")
+	}
+
+	if err := r.ApplyDocuments([]Document{
+		{Path: "Guides/Config.YAML", Content: []byte("a: 1\n"), Operation: "update"},
+	}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.tempDir, "Docs", "guides", "config.yaml")); err != nil {
+		t.Errorf("expected the lowercased file to be written: %v", err)
+	}
+}
+
+func TestCaseCollisionsWithinIntent(t *testing.T) {
+	for _, lower := range []bool{false, true} {
+		r := newTestRepository(t, map[string]string{})
+		r.lowercasePaths = lower
+
+		var err error
+		for _, doc := range []Document{
+			{Path: "config.yaml", Content: []byte("a: 1\n"), Operation: "update"},
+			{Path: "./config.yaml", Content: []byte("a: 2\n"), Operation: "update"},
+			{Path: "Config.yaml", Content: []byte("a: 3\n"), Operation: "update"},
+		} {
+			if _, err = r.SkipDocument(doc); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, ErrCaseCollision) {
+			t.Errorf("lowercase=%v: expected a case collision, got %v", lower, err)
+		}
+	}
+}
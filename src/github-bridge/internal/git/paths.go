@@ -0,0 +1,60 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidPath is returned for document paths that would escape the
+// repository or its configured path prefix
+var ErrInvalidPath = errors.New("invalid document path")
+
+// ResolvePath maps a document path to a slash-separated path relative to the
+// repository root, normalizing its case and placing it under the configured
+// path prefix
+func (r *Repository) ResolvePath(docPath string) (string, error) {
+	cleaned, err := r.normalizePath(docPath)
+	if err != nil {
+		return "", err
+	}
+
+	if r.pathPrefix == "" {
+		return cleaned, nil
+	}
+
+	return path.Join(r.pathPrefix, cleaned), nil
+}
+
+// ValidatePath checks that a document path is safe to write into a repository
+func ValidatePath(p string) error {
+	_, err := cleanRelativePath(p)
+	return err
+}
+
+// cleanRelativePath normalises p and rejects empty, absolute and escaping
+// paths as well as paths into the .git directory
+func cleanRelativePath(p string) (string, error) {
+	slashed := filepath.ToSlash(p)
+	if strings.TrimSpace(slashed) == "" {
+		return "", fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+
+	if path.IsAbs(slashed) || filepath.IsAbs(p) || filepath.VolumeName(p) != "" {
+		return "", fmt.Errorf("%w: %s is absolute", ErrInvalidPath, p)
+	}
+
+	cleaned := path.Clean(slashed)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %s escapes the repository", ErrInvalidPath, p)
+	}
+
+	first := strings.SplitN(cleaned, "/", 2)[0]
+	if strings.EqualFold(first, ".git") {
+		return "", fmt.Errorf("%w: %s targets the .git directory", ErrInvalidPath, p)
+	}
+
+	return cleaned, nil
+}
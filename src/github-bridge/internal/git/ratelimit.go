@@ -0,0 +1,73 @@
+package git
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+// ErrSecondaryRateLimit is returned when GitHub refuses a git request under
+// a secondary rate limit. Retrying before RetryAfter has passed only extends
+// the limit, so withRetry waits exactly that long.
+type ErrSecondaryRateLimit struct {
+	RetryAfter time.Duration
+}
+
+func (e *ErrSecondaryRateLimit) Error() string {
+	return fmt.Sprintf("github secondary rate limit, retry after %s", e.RetryAfter)
+}
+
+// rateLimitTransport detects secondary rate limits: a 403 or 429 carrying
+// Retry-After. go-git reports a bare 403 as an authorization failure, losing
+// the header, so the response is replaced by an error before it gets there.
+// A 403 without Retry-After is left alone.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	metrics *metrics.Metrics
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || (resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests) {
+		return resp, err
+	}
+
+	wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if !ok {
+		return resp, nil
+	}
+
+	resp.Body.Close()
+	t.metrics.SecondaryRateLimits.Inc()
+	return nil, &ErrSecondaryRateLimit{RetryAfter: wait}
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date, which is measured from now. A date already past means no wait.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := at.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
@@ -0,0 +1,151 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// forbidden builds a 403 response, with Retry-After when retryAfter is set
+func forbidden(req *http.Request, retryAfter string) *http.Response {
+	resp := &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("You have exceeded a secondary rate limit")),
+		Request:    req,
+	}
+	if retryAfter != "" {
+		resp.Header.Set("Retry-After", retryAfter)
+	}
+	return resp
+}
+
+func TestSecondaryRateLimitSurfacesThroughGoGit(t *testing.T) {
+	var limited atomic.Bool
+	limited.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if limited.Load() {
+			w.Header().Set("Retry-After", "7")
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	m := metrics.Init(prometheus.NewRegistry())
+	InstallHTTPClient(&http.Client{}, m)
+	t.Cleanup(func() {
+		client.InstallProtocol("https", githttp.DefaultClient)
+		client.InstallProtocol("http", githttp.DefaultClient)
+	})
+
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{srv.URL + "/org/repo.git"},
+	})
+
+	_, err := remote.List(&git.ListOptions{})
+	var rateLimit *ErrSecondaryRateLimit
+	if !errors.As(err, &rateLimit) || rateLimit.RetryAfter != 7*time.Second {
+		t.Fatalf("expected a secondary rate limit of 7s, got %v", err)
+	}
+	if !IsTransient(err) {
+		t.Error("expected a secondary rate limit to be retried")
+	}
+	if got := testutil.ToFloat64(m.SecondaryRateLimits); got != 1 {
+		t.Errorf("expected 1 secondary rate limit counted, got %v", got)
+	}
+
+	// Without Retry-After a 403 is still an authorization failure
+	limited.Store(false)
+	if _, err := remote.List(&git.ListOptions{}); !errors.Is(err, transport.ErrAuthorizationFailed) {
+		t.Errorf("expected an authorization failure, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"30", 30 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"Wed, 01 May 2024 12:01:30 GMT", 90 * time.Second, true},
+		{"Wed, 01 May 2024 11:59:00 GMT", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestWithRetryHonorsRetryAfter(t *testing.T) {
+	// Generic backoff would wait an hour; only Retry-After lets this finish
+	defer func(d time.Duration) { retryBaseDelay = d }(retryBaseDelay)
+	retryBaseDelay = time.Hour
+
+	calls := 0
+	httpClient := &http.Client{Transport: &rateLimitTransport{
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return forbidden(req, "1"), nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+		metrics: metrics.Init(prometheus.NewRegistry()),
+	}}
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	start := time.Now()
+	err := withRetry(context.Background(), logger, "push", 3, func() error {
+		resp, err := httpClient.Get("https://github.com/org/repo.git/info/refs")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	})
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("expected success after the rate limit, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 attempts, got %d", calls)
+	}
+	if elapsed < time.Second || elapsed > 5*time.Second {
+		t.Errorf("expected to wait the 1s Retry-After, waited %v", elapsed)
+	}
+}
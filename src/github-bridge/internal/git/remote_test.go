@@ -0,0 +1,112 @@
+package git
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport/client"
+	"github.com/go-git/go-git/v5/plumbing/transport/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
+)
+
+func TestMain(m *testing.M) {
+	// Serve file:// remotes in-process so tests do not need a git binary
+	client.InstallProtocol("file", server.DefaultServer)
+	os.Exit(m.Run())
+}
+
+// newTestRemote creates a bare repository seeded with the given files and
+// returns its file:// URL
+func newTestRemote(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	seed := newTestRepository(t, files)
+	bare := filepath.Join(t.TempDir(), "remote.git")
+	if _, err := git.PlainClone(bare, true, &git.CloneOptions{
+		URL: "file://" + filepath.Join(seed.tempDir, ".git"),
+	}); err != nil {
+		t.Fatalf("failed to create remote: %v", err)
+	}
+
+	return "file://" + bare
+}
+
+// cloneTestRemote clones the master branch of a test remote
+func cloneTestRemote(t *testing.T, url string) *Repository {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	r, err := Clone(context.Background(), CloneOptions{
+		URL:         url,
+		Branch:      "master",
+		TempDir:     t.TempDir(),
+		RemoteName:  "origin",
+		FullHistory: true,
+		Metrics:     metrics.Init(prometheus.NewRegistry()),
+	}, logger)
+	if err != nil {
+		t.Fatalf("failed to clone test remote: %v", err)
+	}
+	t.Cleanup(func() { r.Cleanup() })
+
+	return r
+}
+
+func TestRefreshResetsCloneToRemoteTip(t *testing.T) {
+	url := newTestRemote(t, map[string]string{"docs/index.md": "v1"})
+	r := cloneTestRemote(t, url)
+
+	// Leftovers of an intent that never pushed
+	if err := r.WriteFile("docs/index.md", []byte("unpushed")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(r.tempDir, "stray.md"), []byte("stray"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CheckCaseCollision("docs/Other.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	other := cloneTestRemote(t, url)
+	if err := other.WriteFile("docs/index.md", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.CommitChanges("Publish v2", testAuthor); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Push(context.Background()); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	if err := r.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	want, _ := other.HeadHash()
+	if got, _ := r.HeadHash(); got != want {
+		t.Errorf("expected HEAD at the remote tip %s, got %s", want, got)
+	}
+	content, err := os.ReadFile(filepath.Join(r.tempDir, "docs", "index.md"))
+	if err != nil || string(content) != "v2" {
+		t.Errorf("expected docs/index.md reset to v2, got %q (err=%v)", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(r.tempDir, "stray.md")); !os.IsNotExist(err) {
+		t.Errorf("expected untracked files removed, got %v", err)
+	}
+	if status, _ := r.GetStatus(); !status.IsClean() {
+		t.Errorf("expected a clean worktree, got %v", status)
+	}
+
+	// Paths written by the last intent no longer collide
+	if err := r.CheckCaseCollision("docs/other.md"); err != nil {
+		t.Errorf("expected case paths forgotten, got %v", err)
+	}
+}
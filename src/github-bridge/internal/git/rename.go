@@ -0,0 +1,57 @@
+package git
+
+// RenamedFrom returns the resolved path a document was written at before its
+// path changed, when the document carries a PreviousPath that resolves to a
+// different path. Previous paths that are ignored or inside a submodule were
+// never written by the bridge and are left alone.
+func (r *Repository) RenamedFrom(doc Document) (string, bool, error) {
+	if doc.PreviousPath == "" || doc.EffectiveOperation() == "delete" {
+		return "", false, nil
+	}
+
+	path, err := r.ResolvePath(doc.Path)
+	if err != nil {
+		return "", false, err
+	}
+	previous, err := r.ResolvePath(doc.PreviousPath)
+	if err != nil {
+		return "", false, err
+	}
+	if previous == path || r.Ignored(doc.PreviousPath) {
+		return "", false, nil
+	}
+
+	inSubmodule, err := r.CheckSubmodule(doc.PreviousPath)
+	if err != nil || inSubmodule {
+		return "", false, err
+	}
+	return previous, true, nil
+}
+
+// removeRenamed deletes the previous path of a renamed document from the
+// worktree, so the rename lands in the same commit as the new path. It
+// reports whether the document was renamed.
+func (r *Repository) removeRenamed(doc Document) (bool, error) {
+	previous, renamed, err := r.RenamedFrom(doc)
+	if err != nil || !renamed {
+		return false, err
+	}
+	if err := r.removePath(previous); err != nil {
+		return false, err
+	}
+	r.logger.WithField("from", previous).WithField("to", doc.Path).Debug("Removed previous path of renamed document")
+	return true, nil
+}
+
+// stageRenamed stages the removal of a renamed document's previous path
+func (r *Repository) stageRenamed(stage *treeStage, doc Document) error {
+	previous, renamed, err := r.RenamedFrom(doc)
+	if err != nil || !renamed {
+		return err
+	}
+	if err := r.stageRemoval(stage, previous); err != nil {
+		return err
+	}
+	stage.track(previous)
+	return nil
+}
@@ -0,0 +1,131 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// headChanges returns the changes HEAD makes to its parent, with renames
+// detected as git log would show them
+func headChanges(t *testing.T, r *Repository) object.Changes {
+	t.Helper()
+
+	head, err := r.headCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent, err := head.Parent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := parent.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := head.Tree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := object.DiffTreeWithOptions(context.Background(), from, to, &object.DiffTreeOptions{DetectRenames: true})
+	if err != nil {
+		t.Fatalf("failed to diff HEAD: %v", err)
+	}
+	return changes
+}
+
+func TestRenamedDocumentMovesFileInOneCommit(t *testing.T) {
+	rename := []Document{{
+		Path:         "docs/guide/setup.md",
+		PreviousPath: "docs/setup.md",
+		Content:      []byte("# Setup\n\nInstall the bridge.\n"),
+		Operation:    "update",
+	}}
+
+	for _, mode := range []string{"worktree", "tree"} {
+		t.Run(mode, func(t *testing.T) {
+			r := newTestRepository(t, map[string]string{
+				"docs/setup.md": "# Setup\n\nInstall the bridge.\n",
+				"docs/index.md": "# Docs\n",
+			})
+
+			var err error
+			if mode == "tree" {
+				if err = r.StageDocuments(rename); err == nil {
+					_, err = r.CommitStaged("Move setup guide", testAuthor)
+				}
+			} else {
+				if err = r.ApplyDocuments(rename); err == nil {
+					_, err = r.CommitChanges("Move setup guide", testAuthor)
+				}
+			}
+			if err != nil {
+				t.Fatalf("failed to commit rename: %v", err)
+			}
+
+			if got := commitCount(t, r); got != 2 {
+				t.Errorf("expected the rename in a single commit, got %d commits", got)
+			}
+			changes := headChanges(t, r)
+			if len(changes) != 1 || changes[0].From.Name != "docs/setup.md" || changes[0].To.Name != "docs/guide/setup.md" {
+				t.Errorf("expected docs/setup.md renamed to docs/guide/setup.md, got %v", changes)
+			}
+		})
+	}
+}
+
+func TestDocumentWithoutPreviousPathKeepsOtherFiles(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/setup.md": "old"})
+
+	if err := r.ApplyDocuments([]Document{{Path: "docs/guide/setup.md", Content: []byte("new"), Operation: "create"}}); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+
+	if _, err := os.Stat(r.fullPath("docs/setup.md")); err != nil {
+		t.Errorf("expected docs/setup.md to be kept, got %v", err)
+	}
+	status, err := r.GetStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 {
+		t.Errorf("expected only the new file to change, got %v", status)
+	}
+}
+
+func TestRenamedFrom(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.pathPrefix = "site"
+
+	tests := []struct {
+		name    string
+		doc     Document
+		want    string
+		renamed bool
+	}{
+		{"no previous path", Document{Path: "a.md", Operation: "update"}, "", false},
+		{"moved", Document{Path: "b.md", PreviousPath: "a.md", Operation: "update"}, "site/a.md", true},
+		{"same path", Document{Path: "a.md", PreviousPath: "./a.md", Operation: "update"}, "", false},
+		{"delete", Document{Path: "b.md", PreviousPath: "a.md", Operation: "delete"}, "", false},
+		{"tombstone", Document{Path: "b.md", PreviousPath: "a.md", Operation: "update", Tombstone: true}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, renamed, err := r.RenamedFrom(tt.doc)
+			if err != nil {
+				t.Fatalf("RenamedFrom failed: %v", err)
+			}
+			if got != tt.want || renamed != tt.renamed {
+				t.Errorf("expected %q, %v, got %q, %v", tt.want, tt.renamed, got, renamed)
+			}
+		})
+	}
+
+	if _, _, err := r.RenamedFrom(Document{Path: "b.md", PreviousPath: "../escape.md", Operation: "update"}); err == nil {
+		t.Error("expected a previous path escaping the repository to be rejected")
+	}
+}
@@ -0,0 +1,58 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// ErrSignerIdentityMismatch is returned when a signing key has no identity
+// matching the committer email, which GitHub would show as "Unverified"
+var ErrSignerIdentityMismatch = errors.New("signing key identity does not match committer email")
+
+// LoadSigningKey reads the first entity from an ASCII-armored OpenPGP private
+// key file
+func LoadSigningKey(path string) (*openpgp.Entity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open signing key: %w", err)
+	}
+	defer f.Close()
+
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", path)
+	}
+
+	key := entities[0]
+	if key.PrivateKey == nil {
+		return nil, fmt.Errorf("%s does not contain a private key", path)
+	}
+	if key.PrivateKey.Encrypted {
+		return nil, fmt.Errorf("signing key in %s is passphrase protected", path)
+	}
+
+	return key, nil
+}
+
+// VerifySignerIdentity checks that one of the key's user IDs carries email
+func VerifySignerIdentity(key *openpgp.Entity, email string) error {
+	var emails []string
+	for _, identity := range key.Identities {
+		if identity.UserId == nil {
+			continue
+		}
+		if strings.EqualFold(identity.UserId.Email, email) {
+			return nil
+		}
+		emails = append(emails, identity.UserId.Email)
+	}
+
+	return fmt.Errorf("%w: committer %q, key identities %v", ErrSignerIdentityMismatch, email, emails)
+}
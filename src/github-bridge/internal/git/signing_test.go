@@ -0,0 +1,97 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// writeTestKey generates an unprotected key for email and writes it armored
+// to a temporary file
+func writeTestKey(t *testing.T, email string) (string, *openpgp.Entity) {
+	t.Helper()
+
+	key, err := openpgp.NewEntity("Virtual DOM Bot", "", email, nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.asc")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create key file: %v", err)
+	}
+	defer f.Close()
+
+	w, err := armor.Encode(f, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to armor key: %v", err)
+	}
+	if err := key.SerializePrivate(w, nil); err != nil {
+		t.Fatalf("failed to serialize key: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close armor: %v", err)
+	}
+
+	return path, key
+}
+
+func TestLoadSigningKeyMatchingIdentity(t *testing.T) {
+	path, _ := writeTestKey(t, "bot@tekfly.io")
+
+	key, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("LoadSigningKey failed: %v", err)
+	}
+
+	if err := VerifySignerIdentity(key, "BOT@tekfly.io"); err != nil {
+		t.Errorf("expected matching identity, got %v", err)
+	}
+}
+
+func TestVerifySignerIdentityMismatch(t *testing.T) {
+	path, _ := writeTestKey(t, "someone-else@example.com")
+
+	key, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("LoadSigningKey failed: %v", err)
+	}
+
+	err = VerifySignerIdentity(key, "bot@tekfly.io")
+	if !errors.Is(err, ErrSignerIdentityMismatch) {
+		t.Fatalf("expected ErrSignerIdentityMismatch, got %v", err)
+	}
+}
+
+func TestCommitIsSignedAndCheckedPerCommit(t *testing.T) {
+	_, key := writeTestKey(t, "bot@tekfly.io")
+
+	r := newTestRepository(t, map[string]string{})
+	r.signKey = key
+	r.verifySigner = true
+
+	hash, err := r.applyAndCommit([]Document{{Path: "a.txt", Content: []byte("a"), Operation: "create"}}, "signed", testAuthor)
+	if err != nil {
+		t.Fatalf("applyAndCommit failed: %v", err)
+	}
+
+	commit, err := r.repo.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		t.Fatalf("failed to load commit: %v", err)
+	}
+	if commit.PGPSignature == "" {
+		t.Error("expected commit to be signed")
+	}
+
+	_, err = r.applyAndCommit([]Document{{Path: "b.txt", Content: []byte("b"), Operation: "create"}}, "unsigned",
+		CommitAuthor{Name: "Other", Email: "other@example.com"})
+	if !errors.Is(err, ErrSignerIdentityMismatch) {
+		t.Errorf("expected per-commit identity check to fail, got %v", err)
+	}
+}
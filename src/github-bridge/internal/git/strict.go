@@ -0,0 +1,57 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Errors returned in strict operations mode when a document's operation does
+// not match the state of the worktree
+var (
+	ErrCreateExists  = errors.New("create on a path that already exists")
+	ErrUpdateMissing = errors.New("update on a path that does not exist")
+	ErrDeleteMissing = errors.New("delete on a path that does not exist")
+)
+
+// checkOperation verifies that doc's operation agrees with whether its path
+// currently exists in the worktree
+func (r *Repository) checkOperation(doc Document) error {
+	path, err := r.ResolvePath(doc.Path)
+	if err != nil {
+		return err
+	}
+
+	exists := true
+	if _, err := os.Lstat(r.fullPath(path)); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		exists = false
+	}
+
+	return r.operationMismatch(doc, path, exists)
+}
+
+// operationMismatch fails when doc's operation disagrees with whether its
+// resolved path exists. Tombstones may repeat a delete, so a missing path is
+// never a mismatch for them.
+func (r *Repository) operationMismatch(doc Document, path string, exists bool) error {
+	var mismatch error
+	var kind string
+	switch {
+	case doc.Tombstone:
+		return nil
+	case doc.Operation == "create" && exists:
+		mismatch, kind = ErrCreateExists, "create_exists"
+	case doc.Operation == "update" && !exists:
+		mismatch, kind = ErrUpdateMissing, "update_missing"
+	case doc.Operation == "delete" && !exists:
+		mismatch, kind = ErrDeleteMissing, "delete_missing"
+	default:
+		return nil
+	}
+
+	r.metrics.OperationMismatches.WithLabelValues(kind).Inc()
+	return fmt.Errorf("%w: %s", mismatch, path)
+}
@@ -0,0 +1,74 @@
+package git
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestStrictOperationsRejectMismatches(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  Document
+		want error
+		kind string
+	}{
+		{"create existing", Document{Path: "existing.txt", Content: []byte("x"), Operation: "create"}, ErrCreateExists, "create_exists"},
+		{"update missing", Document{Path: "missing.txt", Content: []byte("x"), Operation: "update"}, ErrUpdateMissing, "update_missing"},
+		{"delete missing", Document{Path: "missing.txt", Operation: "delete"}, ErrDeleteMissing, "delete_missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepository(t, map[string]string{"existing.txt": "v1"})
+			r.strictOperations = true
+
+			before := testutil.ToFloat64(r.metrics.OperationMismatches.WithLabelValues(tt.kind))
+
+			err := r.ApplyDocuments([]Document{tt.doc})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+
+			if got := testutil.ToFloat64(r.metrics.OperationMismatches.WithLabelValues(tt.kind)); got != before+1 {
+				t.Errorf("expected %s counter to increase by 1, got %v -> %v", tt.kind, before, got)
+			}
+
+			status, err := r.GetStatus()
+			if err != nil {
+				t.Fatalf("GetStatus failed: %v", err)
+			}
+			if !status.IsClean() {
+				t.Errorf("expected rejected document to leave the worktree clean, got %v", status)
+			}
+		})
+	}
+}
+
+func TestStrictOperationsAcceptMatchingState(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"existing.txt": "v1", "old.txt": "old"})
+	r.strictOperations = true
+
+	err := r.ApplyDocuments([]Document{
+		{Path: "new.txt", Content: []byte("new"), Operation: "create"},
+		{Path: "existing.txt", Content: []byte("v2"), Operation: "update"},
+		{Path: "old.txt", Operation: "delete"},
+	})
+	if err != nil {
+		t.Fatalf("expected matching operations to apply, got %v", err)
+	}
+}
+
+func TestLenientOperationsByDefault(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"existing.txt": "v1"})
+
+	err := r.ApplyDocuments([]Document{
+		{Path: "existing.txt", Content: []byte("v2"), Operation: "create"},
+		{Path: "missing.txt", Content: []byte("x"), Operation: "update"},
+		{Path: "gone.txt", Operation: "delete"},
+	})
+	if err != nil {
+		t.Fatalf("expected lenient mode to accept mismatches, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSubmodulePath is returned for documents whose path falls inside a
+// submodule, which is never checked out and is not part of this repository's
+// tree
+var ErrSubmodulePath = errors.New("document path is inside a submodule")
+
+// submodulePaths returns the submodule directories declared in .gitmodules,
+// read once after the clone and pull have settled the worktree
+func (r *Repository) submodulePaths() ([]string, error) {
+	if r.submodules != nil {
+		return r.submodules, nil
+	}
+
+	subs, err := r.worktree.Submodules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitmodules: %w", err)
+	}
+
+	paths := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		cleaned, err := cleanRelativePath(sub.Config().Path)
+		if err != nil {
+			r.logger.WithError(err).WithField("submodule", sub.Config().Name).Warn("Ignoring submodule with invalid path")
+			continue
+		}
+		paths = append(paths, cleaned)
+	}
+
+	r.submodules = paths
+	return paths, nil
+}
+
+// CheckSubmodule reports whether docPath resolves to a path inside one of
+// the repository's submodules. When submodule paths are rejected it also
+// returns an error wrapping ErrSubmodulePath; otherwise callers skip the
+// document.
+func (r *Repository) CheckSubmodule(docPath string) (bool, error) {
+	paths, err := r.submodulePaths()
+	if err != nil || len(paths) == 0 {
+		return false, err
+	}
+
+	path, err := r.ResolvePath(docPath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, sub := range paths {
+		if path != sub && !strings.HasPrefix(path, sub+"/") {
+			continue
+		}
+		if r.rejectSubmodulePaths {
+			return true, fmt.Errorf("%w: %s is inside %s", ErrSubmodulePath, path, sub)
+		}
+		return true, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,105 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newSubmoduleRepository creates a repository with an uninitialized
+// submodule at vendor/lib, as a non-recursive clone leaves it
+func newSubmoduleRepository(t *testing.T) *Repository {
+	t.Helper()
+
+	r := newTestRepository(t, map[string]string{
+		".gitmodules": "[submodule \"lib\"]\n\tpath = vendor/lib\n\turl = https://github.com/tekfly/lib.git\n",
+	})
+
+	idx, err := r.repo.Storer.Index()
+	if err != nil {
+		t.Fatalf("failed to read index: %v", err)
+	}
+	idx.Entries = append(idx.Entries, &index.Entry{
+		Name: "vendor/lib",
+		Mode: filemode.Submodule,
+		Hash: plumbing.NewHash("8ab686eafeb1f44702738c8b0f24f2567c36da6d"),
+	})
+	if err := r.repo.Storer.SetIndex(idx); err != nil {
+		t.Fatalf("failed to write index: %v", err)
+	}
+	if _, err := r.worktree.Commit("add submodule", &git.CommitOptions{
+		Author: &object.Signature{Name: "Test", Email: "test@tekfly.io", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("failed to commit submodule: %v", err)
+	}
+
+	if err := os.MkdirAll(r.fullPath("vendor/lib"), 0755); err != nil {
+		t.Fatalf("failed to create submodule directory: %v", err)
+	}
+	return r
+}
+
+func TestCheckSubmodule(t *testing.T) {
+	r := newSubmoduleRepository(t)
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"vendor/lib", true},
+		{"vendor/lib/README.md", true},
+		{"vendor/library.md", false},
+		{"vendor/other/file.txt", false},
+	}
+
+	for _, tt := range tests {
+		got, err := r.CheckSubmodule(tt.path)
+		if err != nil {
+			t.Fatalf("CheckSubmodule(%q) failed: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("CheckSubmodule(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSkipDocumentSkipsSubmodulePaths(t *testing.T) {
+	r := newSubmoduleRepository(t)
+
+	skip, err := r.SkipDocument(Document{Path: "vendor/lib/README.md", Content: []byte("# Lib"), Operation: "create"})
+	if err != nil || !skip {
+		t.Errorf("expected the document inside the submodule to be skipped, got %v, %v", skip, err)
+	}
+
+	skip, err = r.SkipDocument(Document{Path: "docs/index.md", Content: []byte("# Docs"), Operation: "create"})
+	if err != nil || skip {
+		t.Errorf("expected other documents to be kept, got %v, %v", skip, err)
+	}
+}
+
+func TestSkipDocumentRejectsSubmodulePaths(t *testing.T) {
+	r := newSubmoduleRepository(t)
+	r.rejectSubmodulePaths = true
+
+	_, err := r.SkipDocument(Document{Path: "vendor/lib/README.md", Content: []byte("# Lib"), Operation: "create"})
+	if !errors.Is(err, ErrSubmodulePath) {
+		t.Fatalf("expected ErrSubmodulePath, got %v", err)
+	}
+}
+
+func TestCheckSubmoduleWithoutGitmodules(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"vendor/lib/README.md": "# Lib"})
+	r.rejectSubmodulePaths = true
+
+	inside, err := r.CheckSubmodule("vendor/lib/README.md")
+	if err != nil || inside {
+		t.Errorf("expected no submodules without .gitmodules, got %v, %v", inside, err)
+	}
+}
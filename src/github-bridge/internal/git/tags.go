@@ -0,0 +1,93 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrTagExists is returned when creating a tag that already exists locally
+// or on the remote
+var ErrTagExists = errors.New("tag already exists")
+
+// CreateTag creates an annotated tag pointing at commitHash
+func (r *Repository) CreateTag(name, commitHash, message string, tagger CommitAuthor) error {
+	_, err := r.repo.CreateTag(name, plumbing.NewHash(commitHash), &git.CreateTagOptions{
+		Tagger: &object.Signature{
+			Name:  tagger.Name,
+			Email: tagger.Email,
+			When:  r.signatureTime(tagger),
+		},
+		Message: message,
+		SignKey: r.signKey,
+	})
+	if errors.Is(err, git.ErrTagExists) {
+		return fmt.Errorf("%w: %s", ErrTagExists, name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"tag":    name,
+		"commit": commitHash,
+	}).Info("Created tag")
+	return nil
+}
+
+// TagExists reports whether a tag exists locally or on the remote
+func (r *Repository) TagExists(ctx context.Context, name string) (bool, error) {
+	ref := plumbing.NewTagReferenceName(name)
+	if _, err := r.repo.Reference(ref, false); err == nil {
+		return true, nil
+	}
+
+	remote, err := r.repo.Remote(r.remoteName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get remote: %w", err)
+	}
+
+	var refs []*plumbing.Reference
+	err = withRetry(ctx, r.logger, "ls-remote", r.netRetries, func() error {
+		var listErr error
+		refs, listErr = remote.ListContext(ctx, &git.ListOptions{Auth: r.auth})
+		return listErr
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list remote refs: %w", err)
+	}
+
+	for _, remoteRef := range refs {
+		if remoteRef.Name() == ref {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PushTag pushes a single tag to the remote
+func (r *Repository) PushTag(ctx context.Context, name string) error {
+	ref := plumbing.NewTagReferenceName(name)
+	pushOpts := &git.PushOptions{
+		RemoteName: r.remoteName,
+		Auth:       r.auth,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("%s:%s", ref, ref))},
+	}
+
+	r.logger.WithField("tag", name).Info("Pushing tag to remote")
+
+	err := withRetry(ctx, r.logger, "push tag", r.netRetries, func() error {
+		return r.repo.PushContext(ctx, pushOpts)
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return fmt.Errorf("failed to push tag %s: %w", name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+func TestCreateAndPushTag(t *testing.T) {
+	url := newTestRemote(t, map[string]string{})
+	r := cloneTestRemote(t, url)
+
+	if err := r.WriteFile("release.txt", []byte("v1")); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	hash, err := r.Commit("Release v1.0.0", testAuthor)
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err := r.Push(context.Background()); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+
+	if err := r.CreateTag("v1.0.0", hash, "Version 1.0.0", testAuthor); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+	if err := r.PushTag(context.Background(), "v1.0.0"); err != nil {
+		t.Fatalf("PushTag failed: %v", err)
+	}
+
+	// A fresh clone sees the annotated tag pointing at the pushed commit
+	other := cloneTestRemote(t, url)
+	ref, err := other.repo.Tag("v1.0.0")
+	if err != nil {
+		t.Fatalf("tag not found on remote: %v", err)
+	}
+
+	tag, err := other.repo.TagObject(ref.Hash())
+	if err != nil {
+		t.Fatalf("expected an annotated tag: %v", err)
+	}
+	if tag.Target != plumbing.NewHash(hash) {
+		t.Errorf("expected tag to reference %s, got %s", hash, tag.Target)
+	}
+	if tag.Message != "Version 1.0.0\n" {
+		t.Errorf("unexpected tag message %q", tag.Message)
+	}
+	if tag.Tagger.Email != testAuthor.Email {
+		t.Errorf("unexpected tagger %q", tag.Tagger.Email)
+	}
+}
+
+func TestTagExists(t *testing.T) {
+	url := newTestRemote(t, map[string]string{})
+	r := cloneTestRemote(t, url)
+
+	head, err := r.repo.Head()
+	if err != nil {
+		t.Fatalf("failed to get HEAD: %v", err)
+	}
+
+	exists, err := r.TagExists(context.Background(), "v1.0.0")
+	if err != nil || exists {
+		t.Fatalf("expected tag to be absent, got exists=%v err=%v", exists, err)
+	}
+
+	if err := r.CreateTag("v1.0.0", head.Hash().String(), "first", testAuthor); err != nil {
+		t.Fatalf("CreateTag failed: %v", err)
+	}
+	if err := r.PushTag(context.Background(), "v1.0.0"); err != nil {
+		t.Fatalf("PushTag failed: %v", err)
+	}
+
+	// The remote tag is visible to another clone that never fetched it locally
+	other := cloneTestRemote(t, url)
+	other.repo.DeleteTag("v1.0.0")
+	exists, err = other.TagExists(context.Background(), "v1.0.0")
+	if err != nil || !exists {
+		t.Fatalf("expected remote tag to exist, got exists=%v err=%v", exists, err)
+	}
+
+	if err := r.CreateTag("v1.0.0", head.Hash().String(), "again", testAuthor); !errors.Is(err, ErrTagExists) {
+		t.Errorf("expected ErrTagExists, got %v", err)
+	}
+}
@@ -0,0 +1,418 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// treeNode is a directory of the commit being built: the entries of its tree
+// in the parent commit, overlaid with staged changes. Subdirectories are only
+// loaded once a change reaches into them.
+type treeNode struct {
+	entries  map[string]object.TreeEntry
+	children map[string]*treeNode
+}
+
+// treeStage holds document changes staged in memory against HEAD
+type treeStage struct {
+	parent *object.Commit
+	root   *treeNode
+
+	// paths lists every staged path, in the order first staged
+	paths  []string
+	staged map[string]bool
+}
+
+// SupportsInMemoryApply reports whether StageDocuments and CommitStaged can
+// be used instead of the worktree. Keeping empty directories needs the
+// worktree, as does a branch without commits.
+func (r *Repository) SupportsInMemoryApply() bool {
+	if r.keepEmptyDirs {
+		return false
+	}
+	_, err := r.repo.Head()
+	return err == nil
+}
+
+// StageDocuments hashes the documents' content into the object store and
+// records their changes against HEAD without touching the worktree. Only
+// the path and blob hash of each change are kept, so documents can still be
+// streamed. CommitStaged turns the staged changes into a single commit. The
+// documents are expected to have passed SkipDocument.
+func (r *Repository) StageDocuments(documents []Document) error {
+	for _, doc := range documents {
+		path, err := r.ResolvePath(doc.Path)
+		if err != nil {
+			return err
+		}
+
+		stage, err := r.treeStage()
+		if err != nil {
+			return err
+		}
+
+		if r.strictOperations {
+			_, exists, err := r.lookupStaged(stage, path)
+			if err != nil {
+				return err
+			}
+			if err := r.operationMismatch(doc, path, exists); err != nil {
+				return err
+			}
+		}
+
+		switch doc.EffectiveOperation() {
+		case "create", "update":
+			content, err := r.transform(doc)
+			if err != nil {
+				return err
+			}
+			if err := r.stageRenamed(stage, doc); err != nil {
+				return fmt.Errorf("failed to stage removal of previous path of %s: %w", doc.Path, err)
+			}
+			if err := r.stageBlob(stage, path, content); err != nil {
+				return fmt.Errorf("failed to stage %s: %w", doc.Path, err)
+			}
+		case "delete":
+			if err := r.stageRemoval(stage, path); err != nil {
+				return fmt.Errorf("failed to stage removal of %s: %w", doc.Path, err)
+			}
+		default:
+			r.logger.WithField("operation", doc.Operation).Warn("Unknown operation")
+			continue
+		}
+
+		stage.track(path)
+	}
+
+	return nil
+}
+
+// track records path as staged, for the status of the commit
+func (s *treeStage) track(path string) {
+	if !s.staged[path] {
+		s.staged[path] = true
+		s.paths = append(s.paths, path)
+	}
+}
+
+// CommitStaged commits the changes staged by StageDocuments on top of HEAD,
+// returning an empty hash when they leave the tree unchanged. The branch is
+// advanced without updating the worktree or index, which stay at the parent
+// commit.
+func (r *Repository) CommitStaged(message string, author CommitAuthor) (string, error) {
+	stage := r.stage
+	r.stage = nil
+	if stage == nil {
+		return "", nil
+	}
+
+	status, err := r.stagedStatus(stage)
+	if err != nil {
+		return "", err
+	}
+	if len(status) == 0 {
+		return "", nil
+	}
+
+	if r.fileManifest {
+		message = appendManifest(message, formatManifest(status, r.manifestLimit))
+	}
+
+	treeHash, _, err := r.writeTree(stage.root)
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %w", err)
+	}
+
+	authorSig, committerSig := r.signatures(author)
+	commit := &object.Commit{
+		Author:       authorSig,
+		Committer:    committerSig,
+		Message:      message,
+		TreeHash:     treeHash,
+		ParentHashes: []plumbing.Hash{stage.parent.Hash},
+	}
+
+	if r.signKey != nil {
+		if r.verifySigner {
+			if err := VerifySignerIdentity(r.signKey, committerSig.Email); err != nil {
+				return "", err
+			}
+		}
+		if commit.PGPSignature, err = signCommit(commit, r.signKey); err != nil {
+			return "", err
+		}
+	}
+
+	hash, err := r.storeObject(commit)
+	if err != nil {
+		return "", fmt.Errorf("failed to store commit: %w", err)
+	}
+
+	head, err := r.repo.Head()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if err := r.repo.Storer.SetReference(plumbing.NewHashReference(head.Name(), hash)); err != nil {
+		return "", fmt.Errorf("failed to update %s: %w", head.Name(), err)
+	}
+
+	r.logger.WithField("hash", hash.String()).Info("Created commit")
+	return hash.String(), nil
+}
+
+// treeStage returns the stage, starting one from HEAD on first use
+func (r *Repository) treeStage() (*treeStage, error) {
+	if r.stage != nil {
+		return r.stage, nil
+	}
+
+	head, err := r.repo.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	parent, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD commit: %w", err)
+	}
+	root, err := r.loadTreeNode(parent.TreeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	r.stage = &treeStage{parent: parent, root: root, staged: make(map[string]bool)}
+	return r.stage, nil
+}
+
+// loadTreeNode reads a tree of the parent commit into a node
+func (r *Repository) loadTreeNode(hash plumbing.Hash) (*treeNode, error) {
+	node := &treeNode{
+		entries:  make(map[string]object.TreeEntry),
+		children: make(map[string]*treeNode),
+	}
+	if hash.IsZero() {
+		return node, nil
+	}
+
+	tree, err := object.GetTree(r.repo.Storer, hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tree %s: %w", hash, err)
+	}
+	for _, entry := range tree.Entries {
+		node.entries[entry.Name] = entry
+	}
+	return node, nil
+}
+
+// dir walks to the directory of the given path components. With create,
+// missing directories are added; otherwise a missing one returns nil.
+func (r *Repository) dir(stage *treeStage, parts []string, create bool) (*treeNode, error) {
+	node := stage.root
+	for i, name := range parts {
+		child, ok := node.children[name]
+		if !ok {
+			entry, exists := node.entries[name]
+			switch {
+			case exists && entry.Mode == filemode.Dir:
+				var err error
+				if child, err = r.loadTreeNode(entry.Hash); err != nil {
+					return nil, err
+				}
+			case !create:
+				return nil, nil
+			case exists:
+				return nil, fmt.Errorf("%s is not a directory", strings.Join(parts[:i+1], "/"))
+			default:
+				child = &treeNode{
+					entries:  make(map[string]object.TreeEntry),
+					children: make(map[string]*treeNode),
+				}
+			}
+			node.children[name] = child
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// lookupStaged returns the entry at path with the staged changes applied
+func (r *Repository) lookupStaged(stage *treeStage, path string) (object.TreeEntry, bool, error) {
+	parts := strings.Split(path, "/")
+	node, err := r.dir(stage, parts[:len(parts)-1], false)
+	if err != nil || node == nil {
+		return object.TreeEntry{}, false, err
+	}
+
+	name := parts[len(parts)-1]
+	if _, isDir := node.children[name]; isDir {
+		return object.TreeEntry{}, true, nil
+	}
+	entry, ok := node.entries[name]
+	return entry, ok, nil
+}
+
+// stageBlob stores content as a blob and points path at it, keeping the
+// mode of an existing executable file
+func (r *Repository) stageBlob(stage *treeStage, path string, content []byte) error {
+	obj := r.repo.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	hash, err := r.repo.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(path, "/")
+	node, err := r.dir(stage, parts[:len(parts)-1], true)
+	if err != nil {
+		return err
+	}
+
+	name := parts[len(parts)-1]
+	prev, exists := node.entries[name]
+	if _, isDir := node.children[name]; isDir || (exists && prev.Mode == filemode.Dir) {
+		return fmt.Errorf("%s is a directory", path)
+	}
+
+	mode := filemode.Regular
+	if exists && prev.Mode == filemode.Executable {
+		mode = filemode.Executable
+	}
+	node.entries[name] = object.TreeEntry{Name: name, Mode: mode, Hash: hash}
+	return nil
+}
+
+// stageRemoval removes the file at path. Like a worktree delete, removing a
+// path that does not exist is not an error.
+func (r *Repository) stageRemoval(stage *treeStage, path string) error {
+	parts := strings.Split(path, "/")
+	node, err := r.dir(stage, parts[:len(parts)-1], false)
+	if err != nil || node == nil {
+		return err
+	}
+
+	name := parts[len(parts)-1]
+	if entry, ok := node.entries[name]; ok && entry.Mode != filemode.Dir {
+		delete(node.entries, name)
+	}
+	return nil
+}
+
+// stagedStatus compares the staged paths against the parent commit, in the
+// form the file manifest is built from
+func (r *Repository) stagedStatus(stage *treeStage) (git.Status, error) {
+	parentTree, err := stage.parent.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load HEAD tree: %w", err)
+	}
+
+	status := make(git.Status)
+	for _, path := range stage.paths {
+		cur, curOK, err := r.lookupStaged(stage, path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Any lookup failure means the path did not exist as a file
+		old, err := parentTree.FindEntry(path)
+		oldOK := err == nil && old.Mode != filemode.Dir
+
+		var code git.StatusCode
+		switch {
+		case curOK && !oldOK:
+			code = git.Added
+		case curOK && (cur.Hash != old.Hash || cur.Mode != old.Mode):
+			code = git.Modified
+		case !curOK && oldOK:
+			code = git.Deleted
+		default:
+			continue
+		}
+		status[path] = &git.FileStatus{Staging: code, Worktree: git.Unmodified}
+	}
+	return status, nil
+}
+
+// writeTree stores node and its changed subdirectories, reporting empty
+// directories so their parent drops them as git does
+func (r *Repository) writeTree(node *treeNode) (plumbing.Hash, bool, error) {
+	for name, child := range node.children {
+		hash, empty, err := r.writeTree(child)
+		if err != nil {
+			return plumbing.ZeroHash, false, err
+		}
+		if empty {
+			delete(node.entries, name)
+			continue
+		}
+		node.entries[name] = object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash}
+	}
+
+	tree := &object.Tree{Entries: make([]object.TreeEntry, 0, len(node.entries))}
+	for _, entry := range node.entries {
+		tree.Entries = append(tree.Entries, entry)
+	}
+	sort.Slice(tree.Entries, func(i, j int) bool {
+		return treeSortKey(tree.Entries[i]) < treeSortKey(tree.Entries[j])
+	})
+
+	hash, err := r.storeObject(tree)
+	return hash, len(tree.Entries) == 0, err
+}
+
+// treeSortKey orders tree entries as git does, comparing directories as if
+// their names ended in a slash
+func treeSortKey(entry object.TreeEntry) string {
+	if entry.Mode == filemode.Dir {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
+
+// storeObject encodes a tree or commit into the object store
+func (r *Repository) storeObject(obj interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	encoded := r.repo.Storer.NewEncodedObject()
+	if err := obj.Encode(encoded); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return r.repo.Storer.SetEncodedObject(encoded)
+}
+
+// signCommit returns the armored detached signature of commit, as the
+// worktree path produces it
+func signCommit(commit *object.Commit, key *openpgp.Entity) (string, error) {
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.Encode(encoded); err != nil {
+		return "", err
+	}
+	reader, err := encoded.Reader()
+	if err != nil {
+		return "", err
+	}
+
+	var sig bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&sig, key, reader, nil); err != nil {
+		return "", fmt.Errorf("failed to sign commit: %w", err)
+	}
+	return sig.String(), nil
+}
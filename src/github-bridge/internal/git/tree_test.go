@@ -0,0 +1,180 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// headTree returns the tree hash and message of HEAD
+func headTree(t testing.TB, r *Repository) (string, string) {
+	t.Helper()
+
+	head, err := r.repo.Head()
+	if err != nil {
+		t.Fatalf("failed to resolve HEAD: %v", err)
+	}
+	commit, err := r.repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to load HEAD commit: %v", err)
+	}
+	return commit.TreeHash.String(), commit.Message
+}
+
+func TestCommitStagedMatchesWorktreeCommit(t *testing.T) {
+	files := func() map[string]string {
+		return map[string]string{
+			"docs/index.md":        "# Docs",
+			"docs/guide/intro.md":  "intro",
+			"docs/guide/setup.md":  "setup",
+			"assets/logo.svg":      "<svg/>",
+			"assets/old/unused.md": "unused",
+		}
+	}
+	docs := []Document{
+		{Path: "docs/index.md", Content: []byte("# Docs v2"), Operation: "update"},
+		{Path: "docs/guide/setup.md", Operation: "delete"},
+		{Path: "docs/guide/advanced/tuning.md", Content: []byte("tuning"), Operation: "create"},
+		{Path: "assets/old/unused.md", Operation: "delete"},
+		{Path: "assets/logo.svg", Content: []byte("<svg/>"), Operation: "update"},
+		{Path: "CHANGELOG.md", Content: []byte("v2"), Operation: "create"},
+		{Path: "missing.md", Operation: "delete"},
+	}
+
+	onDisk := newTestRepository(t, files())
+	onDisk.fileManifest = true
+	if err := onDisk.ApplyDocuments(docs); err != nil {
+		t.Fatalf("ApplyDocuments failed: %v", err)
+	}
+	if _, err := onDisk.CommitChanges("Publish docs", testAuthor); err != nil {
+		t.Fatalf("CommitChanges failed: %v", err)
+	}
+
+	inMemory := newTestRepository(t, files())
+	inMemory.fileManifest = true
+	if !inMemory.SupportsInMemoryApply() {
+		t.Fatal("expected in-memory apply to be supported")
+	}
+	if err := inMemory.StageDocuments(docs); err != nil {
+		t.Fatalf("StageDocuments failed: %v", err)
+	}
+	hash, err := inMemory.CommitStaged("Publish docs", testAuthor)
+	if err != nil || hash == "" {
+		t.Fatalf("CommitStaged failed: %q, %v", hash, err)
+	}
+
+	wantTree, wantMessage := headTree(t, onDisk)
+	gotTree, gotMessage := headTree(t, inMemory)
+	if gotTree != wantTree {
+		t.Errorf("expected tree %s from the worktree path, got %s", wantTree, gotTree)
+	}
+	if gotMessage != wantMessage {
+		t.Errorf("expected message %q, got %q", wantMessage, gotMessage)
+	}
+
+	// The worktree is left untouched
+	if _, err := os.Stat(inMemory.fullPath("CHANGELOG.md")); !os.IsNotExist(err) {
+		t.Errorf("expected no file written to the worktree, got %v", err)
+	}
+	if got := commitCount(t, inMemory); got != 2 {
+		t.Errorf("expected 2 commits, got %d", got)
+	}
+}
+
+func TestCommitStagedWithoutChanges(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"docs/index.md": "# Docs"})
+
+	if err := r.StageDocuments([]Document{
+		{Path: "docs/index.md", Content: []byte("# Docs"), Operation: "update"},
+		{Path: "missing.md", Operation: "delete"},
+	}); err != nil {
+		t.Fatalf("StageDocuments failed: %v", err)
+	}
+
+	hash, err := r.CommitStaged("No-op", testAuthor)
+	if err != nil || hash != "" {
+		t.Errorf("expected no commit, got %q, %v", hash, err)
+	}
+	if got := commitCount(t, r); got != 1 {
+		t.Errorf("expected 1 commit, got %d", got)
+	}
+}
+
+func TestStageDocumentsStrictOperations(t *testing.T) {
+	r := newTestRepository(t, map[string]string{"existing.txt": "v1"})
+	r.strictOperations = true
+
+	// A path created earlier in the same batch exists for later documents
+	if err := r.StageDocuments([]Document{
+		{Path: "new.txt", Content: []byte("x"), Operation: "create"},
+		{Path: "new.txt", Content: []byte("y"), Operation: "update"},
+	}); err != nil {
+		t.Fatalf("StageDocuments failed: %v", err)
+	}
+
+	err := r.StageDocuments([]Document{{Path: "existing.txt", Content: []byte("x"), Operation: "create"}})
+	if !errors.Is(err, ErrCreateExists) {
+		t.Errorf("expected ErrCreateExists, got %v", err)
+	}
+	err = r.StageDocuments([]Document{{Path: "gone.txt", Operation: "delete"}})
+	if !errors.Is(err, ErrDeleteMissing) {
+		t.Errorf("expected ErrDeleteMissing, got %v", err)
+	}
+}
+
+func TestInMemoryApplyUnsupportedWithKeepEmptyDirs(t *testing.T) {
+	r := newTestRepository(t, map[string]string{})
+	r.keepEmptyDirs = true
+
+	if r.SupportsInMemoryApply() {
+		t.Error("expected keeping empty directories to require the worktree")
+	}
+}
+
+// benchmarkDocuments returns n creates spread over 20 directories
+func benchmarkDocuments(n int) []Document {
+	docs := make([]Document, n)
+	for i := range docs {
+		docs[i] = Document{
+			Path:      fmt.Sprintf("pages/section-%d/page-%d.html", i%20, i),
+			Content:   []byte(fmt.Sprintf("<html><body>page %d</body></html>", i)),
+			Operation: "create",
+		}
+	}
+	return docs
+}
+
+func BenchmarkApplyDocuments(b *testing.B) {
+	docs := benchmarkDocuments(200)
+
+	b.Run("worktree", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			r := newTestRepository(b, map[string]string{})
+			b.StartTimer()
+
+			if err := r.ApplyDocuments(docs); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := r.CommitChanges("Publish", testAuthor); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("tree", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			r := newTestRepository(b, map[string]string{})
+			b.StartTimer()
+
+			if err := r.StageDocuments(docs); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := r.CommitStaged("Publish", testAuthor); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,182 @@
+// Package hook runs the command or HTTP endpoint configured to act on each
+// successful push, such as triggering a downstream deploy
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// maxOutput caps how much of a hook's output is kept for the log
+const maxOutput = 4096
+
+// killWait is how long a timed-out command's output pipes are drained after
+// it is killed, in case it left children holding them open
+const killWait = time.Second
+
+// Event describes a successful push. Commands receive it as JSON on stdin
+// and HTTP endpoints as the request body.
+type Event struct {
+	IntentID  string                 `json:"intent_id"`
+	Repo      string                 `json:"repo"`
+	Branch    string                 `json:"branch"`
+	Commit    string                 `json:"commit"`
+	Author    string                 `json:"author,omitempty"`
+	Documents int                    `json:"documents"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// env returns the event as environment variables for a command
+func (e Event) env() []string {
+	return []string{
+		"BRIDGE_INTENT_ID=" + e.IntentID,
+		"BRIDGE_REPO=" + e.Repo,
+		"BRIDGE_BRANCH=" + e.Branch,
+		"BRIDGE_COMMIT=" + e.Commit,
+		"BRIDGE_AUTHOR=" + e.Author,
+		"BRIDGE_DOCUMENTS=" + strconv.Itoa(e.Documents),
+	}
+}
+
+// Options configures a Hook
+type Options struct {
+	// Target is the path of an executable, or an http(s) URL to POST to
+	Target string
+
+	// Timeout bounds a single run
+	Timeout time.Duration
+
+	// HTTPClient sends requests to an HTTP target; http.DefaultClient
+	// when nil
+	HTTPClient *http.Client
+}
+
+// Hook invokes the configured target after each successful push
+type Hook struct {
+	opts   Options
+	logger *logrus.Logger
+}
+
+// New creates a Hook for opts
+func New(opts Options, logger *logrus.Logger) *Hook {
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &Hook{opts: opts, logger: logger}
+}
+
+// Run invokes the hook for event and logs the outcome along with the hook's
+// output. It fails when the command exits non-zero, the endpoint answers
+// outside 2xx, or the run exceeds the timeout.
+func (h *Hook) Run(ctx context.Context, event Event) error {
+	ctx, cancel := context.WithTimeout(ctx, h.opts.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook event: %w", err)
+	}
+
+	start := time.Now()
+	var output []byte
+	if isURL(h.opts.Target) {
+		output, err = h.post(ctx, body)
+	} else {
+		output, err = h.exec(ctx, event, body)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("post-push hook timed out after %s", h.opts.Timeout)
+	}
+
+	logger := h.logger.WithFields(logrus.Fields{
+		"intent_id": event.IntentID,
+		"commit":    event.Commit,
+		"duration":  time.Since(start).String(),
+	})
+	if len(output) > 0 {
+		logger = logger.WithField("output", string(output))
+	}
+	if err != nil {
+		logger.WithError(err).Warn("Post-push hook failed")
+		return err
+	}
+	logger.Info("Ran post-push hook")
+	return nil
+}
+
+// exec runs the command with the event in its environment and on stdin,
+// returning its combined output
+func (h *Hook) exec(ctx context.Context, event Event, body []byte) ([]byte, error) {
+	var output limitedBuffer
+
+	cmd := exec.CommandContext(ctx, h.opts.Target)
+	cmd.Env = append(os.Environ(), event.env()...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = killWait
+
+	if err := cmd.Run(); err != nil {
+		return output.Bytes(), fmt.Errorf("post-push hook %s failed: %w", h.opts.Target, err)
+	}
+	return output.Bytes(), nil
+}
+
+// post sends the event to the endpoint, returning the response body
+func (h *Hook) post(ctx context.Context, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.opts.Target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post-push hook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	output, _ := io.ReadAll(io.LimitReader(resp.Body, maxOutput))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return output, fmt.Errorf("post-push hook returned %s", resp.Status)
+	}
+	return output, nil
+}
+
+// isURL reports whether target names an HTTP endpoint rather than a command
+func isURL(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// limitedBuffer keeps the first maxOutput bytes written to it and discards
+// the rest, so a chatty hook cannot grow the log without bound
+type limitedBuffer struct {
+	buf bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := maxOutput - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
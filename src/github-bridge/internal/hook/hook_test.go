@@ -0,0 +1,133 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var testEvent = Event{
+	IntentID:  "intent-1",
+	Repo:      "site",
+	Branch:    "main",
+	Commit:    "abc123",
+	Documents: 2,
+}
+
+// writeScript writes an executable shell script into a temp dir
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "hook.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0755); err != nil {
+		t.Fatalf("failed to write hook script: %v", err)
+	}
+	return path
+}
+
+func TestRunCommandReceivesEvent(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event")
+	script := writeScript(t, `echo "$BRIDGE_REPO $BRIDGE_BRANCH $BRIDGE_COMMIT" > `+out+`
+cat >> `+out+`
+echo deployed`)
+
+	logger, logs := test.NewNullLogger()
+	h := New(Options{Target: script, Timeout: 5 * time.Second}, logger)
+
+	if err := h.Run(context.Background(), testEvent); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	env, body, _ := strings.Cut(string(data), "\n")
+	if env != "site main abc123" {
+		t.Errorf("expected the event in the environment, got %q", env)
+	}
+	var got Event
+	if err := json.Unmarshal([]byte(body), &got); err != nil || got.IntentID != "intent-1" || got.Documents != 2 {
+		t.Errorf("expected the event as JSON on stdin, got %q (%v)", body, err)
+	}
+
+	// Output is captured into the log
+	entry := logs.LastEntry()
+	if entry == nil || entry.Level != logrus.InfoLevel || entry.Data["output"] != "deployed\n" {
+		t.Errorf("expected the hook output to be logged, got %+v", entry)
+	}
+}
+
+func TestRunCommandTimesOut(t *testing.T) {
+	script := writeScript(t, "echo starting\nsleep 10\n")
+
+	logger, _ := test.NewNullLogger()
+	h := New(Options{Target: script, Timeout: 200 * time.Millisecond}, logger)
+
+	start := time.Now()
+	err := h.Run(context.Background(), testEvent)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the hook to be killed at the timeout, took %s", elapsed)
+	}
+}
+
+func TestRunCommandFailure(t *testing.T) {
+	script := writeScript(t, "echo boom >&2\nexit 3\n")
+
+	logger, logs := test.NewNullLogger()
+	h := New(Options{Target: script, Timeout: 5 * time.Second}, logger)
+
+	if err := h.Run(context.Background(), testEvent); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Errorf("expected the exit status in the error, got %v", err)
+	}
+	entry := logs.LastEntry()
+	if entry == nil || entry.Level != logrus.WarnLevel || entry.Data["output"] != "boom\n" {
+		t.Errorf("expected the failure logged with the hook output, got %+v", entry)
+	}
+}
+
+func TestRunPostsToEndpoint(t *testing.T) {
+	var got Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		if got.Commit == "fail" {
+			http.Error(w, "deploy locked", http.StatusConflict)
+			return
+		}
+		w.Write([]byte("queued"))
+	}))
+	defer server.Close()
+
+	logger, _ := test.NewNullLogger()
+	h := New(Options{Target: server.URL, Timeout: 5 * time.Second}, logger)
+
+	if err := h.Run(context.Background(), testEvent); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got.Repo != "site" || got.Commit != "abc123" {
+		t.Errorf("expected the event as the request body, got %+v", got)
+	}
+
+	failing := testEvent
+	failing.Commit = "fail"
+	if err := h.Run(context.Background(), failing); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("expected a non-2xx response to fail, got %v", err)
+	}
+}
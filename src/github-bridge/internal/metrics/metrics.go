@@ -5,95 +5,203 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var (
+// Metrics holds the bridge's collectors. Each set is registered with its own
+// registry, so several can coexist in one process.
+type Metrics struct {
 	// Push metrics
-	PushAttempts = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "github_bridge_push_attempts_total",
-		Help: "Total number of push attempts",
-	})
-
-	PushSuccesses = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "github_bridge_push_successes_total",
-		Help: "Total number of successful pushes",
-	})
-
-	PushFailures = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "github_bridge_push_failures_total",
-		Help: "Total number of failed pushes",
-	})
+	PushAttempts  prometheus.Counter
+	PushSuccesses prometheus.Counter
+	PushFailures  prometheus.Counter
 
 	// Document metrics
-	DocumentsProcessed = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "github_bridge_documents_processed_total",
-		Help: "Total number of documents processed",
-	})
-
-	DocumentsSkipped = promauto.NewCounter(prometheus.CounterOpts{
-		Name: "github_bridge_documents_skipped_total",
-		Help: "Total number of documents skipped",
-	})
+	DocumentsProcessed prometheus.Counter
+	DocumentsSkipped   prometheus.Counter
+	DocumentsBinary    prometheus.Counter
 
 	// Batch metrics
-	BatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "github_bridge_batch_size",
-		Help:    "Size of document batches processed",
-		Buckets: prometheus.ExponentialBuckets(1, 2, 10),
-	})
-
-	BatchDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "github_bridge_batch_duration_seconds",
-		Help:    "Time taken to process a batch",
-		Buckets: prometheus.DefBuckets,
-	})
+	BatchSize     prometheus.Histogram
+	BatchDuration prometheus.Histogram
 
 	// Git operations
-	GitCloneDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "github_bridge_git_clone_duration_seconds",
-		Help:    "Time taken to clone repository",
-		Buckets: prometheus.DefBuckets,
-	})
-
-	GitPushDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "github_bridge_git_push_duration_seconds",
-		Help:    "Time taken to push changes",
-		Buckets: prometheus.DefBuckets,
-	})
+	GitCloneDuration prometheus.Histogram
+	GitPushDuration  prometheus.Histogram
 
 	// MongoDB operations
-	MongoQueryDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "github_bridge_mongo_query_duration_seconds",
-		Help:    "Time taken for MongoDB queries",
-		Buckets: prometheus.DefBuckets,
-	})
-
-	MongoUpdateDuration = promauto.NewHistogram(prometheus.HistogramOpts{
-		Name:    "github_bridge_mongo_update_duration_seconds",
-		Help:    "Time taken for MongoDB updates",
-		Buckets: prometheus.DefBuckets,
-	})
+	MongoQueryDuration  prometheus.Histogram
+	MongoUpdateDuration prometheus.Histogram
 
 	// Errors by type
-	ErrorsByType = promauto.NewCounterVec(prometheus.CounterOpts{
-		Name: "github_bridge_errors_total",
-		Help: "Total errors by type",
-	}, []string{"type"})
+	ErrorsByType *prometheus.CounterVec
+
+	// Intents rejected without being attempted
+	IntentsRejected *prometheus.CounterVec
+
+	// Document operations that disagree with the worktree in strict mode
+	OperationMismatches *prometheus.CounterVec
+
+	// New connections opened by the git HTTP transport; compare with the
+	// operation rate to see how often pooled connections are reused
+	GitHTTPConnections prometheus.Counter
+
+	// GitHub secondary rate limit responses to git over HTTPS
+	SecondaryRateLimits prometheus.Counter
+
+	// Intents whose documents changed on a re-read after leaving the
+	// worktree clean
+	StaleReads prometheus.Counter
+
+	// Post-push hook runs that failed or timed out
+	PostPushHookFailures prometheus.Counter
+
+	// Cached clones repacked for holding too many loose objects
+	RepoGC prometheus.Counter
+
+	// Worker panics
+	WorkerPanics prometheus.Counter
 
 	// Active workers
-	ActiveWorkers = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "github_bridge_active_workers",
-		Help: "Number of active worker goroutines",
-	})
+	ActiveWorkers prometheus.Gauge
+
+	// Whether processing is paused via the admin API
+	Paused prometheus.Gauge
+
+	// Processed intents waiting for their processed flag to be written
+	PendingMarks prometheus.Gauge
+
+	// Age of the oldest unprocessed push intent
+	OldestPendingIntentAge prometheus.Gauge
+
+	// Free space in the clone work directory
+	WorkdirFreeBytes prometheus.Gauge
 
 	// Queue size
-	QueueSize = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "github_bridge_queue_size",
-		Help: "Number of documents in processing queue",
-	})
-)
+	QueueSize prometheus.Gauge
+}
+
+// Init builds every collector against reg and sets initial values
+func Init(reg prometheus.Registerer) *Metrics {
+	f := promauto.With(reg)
+
+	m := &Metrics{
+		PushAttempts: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_push_attempts_total",
+			Help: "Total number of push attempts",
+		}),
+		PushSuccesses: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_push_successes_total",
+			Help: "Total number of successful pushes",
+		}),
+		PushFailures: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_push_failures_total",
+			Help: "Total number of failed pushes",
+		}),
+		DocumentsProcessed: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_documents_processed_total",
+			Help: "Total number of documents processed",
+		}),
+		DocumentsSkipped: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_documents_skipped_total",
+			Help: "Total number of documents skipped",
+		}),
+		DocumentsBinary: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_documents_binary_total",
+			Help: "Total number of documents detected as binary content",
+		}),
+		BatchSize: f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "github_bridge_batch_size",
+			Help:    "Size of document batches processed",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 10),
+		}),
+		BatchDuration: f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "github_bridge_batch_duration_seconds",
+			Help:    "Time taken to process a batch",
+			Buckets: prometheus.DefBuckets,
+		}),
+		GitCloneDuration: f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "github_bridge_git_clone_duration_seconds",
+			Help:    "Time taken to clone repository",
+			Buckets: prometheus.DefBuckets,
+		}),
+		GitPushDuration: f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "github_bridge_git_push_duration_seconds",
+			Help:    "Time taken to push changes",
+			Buckets: prometheus.DefBuckets,
+		}),
+		MongoQueryDuration: f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "github_bridge_mongo_query_duration_seconds",
+			Help:    "Time taken for MongoDB queries",
+			Buckets: prometheus.DefBuckets,
+		}),
+		MongoUpdateDuration: f.NewHistogram(prometheus.HistogramOpts{
+			Name:    "github_bridge_mongo_update_duration_seconds",
+			Help:    "Time taken for MongoDB updates",
+			Buckets: prometheus.DefBuckets,
+		}),
+		ErrorsByType: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "github_bridge_errors_total",
+			Help: "Total errors by type",
+		}, []string{"type"}),
+		IntentsRejected: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "github_bridge_intents_rejected_total",
+			Help: "Total push intents rejected to the dead-letter collection by reason",
+		}, []string{"reason"}),
+		OperationMismatches: f.NewCounterVec(prometheus.CounterOpts{
+			Name: "github_bridge_operation_mismatches_total",
+			Help: "Total document operations rejected by strict operations mode by kind",
+		}, []string{"kind"}),
+		GitHTTPConnections: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_git_http_connections_total",
+			Help: "Total number of new connections dialed for git over HTTPS",
+		}),
+		SecondaryRateLimits: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_secondary_rate_limit_total",
+			Help: "Total git requests refused by a GitHub secondary rate limit",
+		}),
+		StaleReads: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_stale_reads_total",
+			Help: "Total intents found to have changes only when re-read after a clean worktree",
+		}),
+		PostPushHookFailures: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_post_push_hook_failures_total",
+			Help: "Total post-push hook runs that failed or timed out",
+		}),
+		RepoGC: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_repo_gc_total",
+			Help: "Total cached clones repacked for exceeding the loose object threshold",
+		}),
+		WorkerPanics: f.NewCounter(prometheus.CounterOpts{
+			Name: "github_bridge_worker_panics_total",
+			Help: "Total number of panics recovered in worker goroutines",
+		}),
+		ActiveWorkers: f.NewGauge(prometheus.GaugeOpts{
+			Name: "github_bridge_active_workers",
+			Help: "Number of active worker goroutines",
+		}),
+		Paused: f.NewGauge(prometheus.GaugeOpts{
+			Name: "github_bridge_paused",
+			Help: "Whether intent processing is paused (1) or running (0)",
+		}),
+		PendingMarks: f.NewGauge(prometheus.GaugeOpts{
+			Name: "github_bridge_pending_marks",
+			Help: "Number of processed intents whose mark is waiting to be retried",
+		}),
+		OldestPendingIntentAge: f.NewGauge(prometheus.GaugeOpts{
+			Name: "github_bridge_oldest_pending_intent_age_seconds",
+			Help: "Age of the oldest unprocessed push intent",
+		}),
+		WorkdirFreeBytes: f.NewGauge(prometheus.GaugeOpts{
+			Name: "github_bridge_workdir_free_bytes",
+			Help: "Free disk space available in the clone work directory",
+		}),
+		QueueSize: f.NewGauge(prometheus.GaugeOpts{
+			Name: "github_bridge_queue_size",
+			Help: "Number of documents in processing queue",
+		}),
+	}
 
-// Init initializes the metrics
-func Init() {
 	// Set initial values
-	ActiveWorkers.Set(0)
-	QueueSize.Set(0)
-}
\ No newline at end of file
+	m.ActiveWorkers.Set(0)
+	m.QueueSize.Set(0)
+
+	return m
+}
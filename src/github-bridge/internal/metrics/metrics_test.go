@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestIndependentMetricSets(t *testing.T) {
+	regA := prometheus.NewRegistry()
+	regB := prometheus.NewRegistry()
+
+	a := Init(regA)
+	b := Init(regB)
+
+	a.PushAttempts.Inc()
+	a.ErrorsByType.WithLabelValues("mongodb").Inc()
+
+	if got := testutil.ToFloat64(a.PushAttempts); got != 1 {
+		t.Errorf("expected 1 push attempt in the first set, got %v", got)
+	}
+	if got := testutil.ToFloat64(b.PushAttempts); got != 0 {
+		t.Errorf("expected the second set to be unaffected, got %v", got)
+	}
+
+	count, err := testutil.GatherAndCount(regB, "github_bridge_errors_total")
+	if err != nil {
+		t.Fatalf("failed to gather second registry: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("expected no error series in the second registry, got %d", count)
+	}
+}
+
+func TestInitTwiceOnOneRegistryPanics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	Init(reg)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a second set on the same registry to panic")
+		}
+	}()
+	Init(reg)
+}
@@ -2,13 +2,17 @@ package mongodb
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"golang.org/x/sync/errgroup"
 )
 
 // Document represents a document in the virtual DOM
@@ -27,61 +31,208 @@ type Document struct {
 
 // PushIntent represents a push intent document
 type PushIntent struct {
-	ID         string    `bson:"_id,omitempty"`
-	Repo       string    `bson:"repo"`
-	Branch     string    `bson:"branch"`
-	Author     string    `bson:"author"`
-	Message    string    `bson:"message"`
-	Timestamp  time.Time `bson:"timestamp"`
-	Processed  bool      `bson:"processed"`
+	ID          string     `bson:"_id,omitempty"`
+	Repo        string     `bson:"repo"`
+	Branch      string     `bson:"branch"`
+	Author      string     `bson:"author"`
+	Message     string     `bson:"message"`
+	Timestamp   time.Time  `bson:"timestamp"`
+	Processed   bool       `bson:"processed"`
 	ProcessedAt *time.Time `bson:"processed_at,omitempty"`
+	Error       string     `bson:"error,omitempty"`
+	Documents   []string   `bson:"documents"` // Document IDs
+	Priority    int        `bson:"priority"`  // higher runs ahead of older intents
+
+	Metadata map[string]interface{} `bson:"metadata,omitempty"`
+}
+
+// AuditChange is a single path written by the bridge
+type AuditChange struct {
+	Path      string `bson:"path"`
+	Operation string `bson:"operation"`
+}
+
+// AuditRecord is an immutable record of a successful push
+type AuditRecord struct {
+	ID         string        `bson:"_id,omitempty"`
+	IntentID   string        `bson:"intent_id"`
+	Repo       string        `bson:"repo"`
+	Branch     string        `bson:"branch"`
+	CommitHash string        `bson:"commit_hash"`
+	Changes    []AuditChange `bson:"changes"`
+	Author     string        `bson:"author"`
+	Committer  string        `bson:"committer"`
+	Timestamp  time.Time     `bson:"timestamp"`
+}
+
+// DryRunChange is a path a dry run would write, with its diff against the
+// branch. It is also written to DRY_RUN_OUTPUT directories as JSON.
+type DryRunChange struct {
+	Path      string `bson:"path" json:"path"`
+	Operation string `bson:"operation" json:"operation"`
+	Diff      string `bson:"diff" json:"diff"`
+}
+
+// DryRunResult is the change a dry run proposes for an intent, replaced
+// each time the intent is dry-run again
+type DryRunResult struct {
+	IntentID   string         `bson:"_id" json:"intent_id"`
+	Repo       string         `bson:"repo" json:"repo"`
+	Branch     string         `bson:"branch" json:"branch"`
+	BaseCommit string         `bson:"base_commit" json:"base_commit"`
+	Changes    []DryRunChange `bson:"changes" json:"changes"`
+	CreatedAt  time.Time      `bson:"created_at" json:"created_at"`
+}
+
+// DeadLetter is a push intent that was quarantined instead of processed
+type DeadLetter struct {
+	IntentID      string      `bson:"_id"`
+	Intent        *PushIntent `bson:"intent"`
+	Reason        string      `bson:"reason"`
+	QuarantinedAt time.Time   `bson:"quarantined_at"`
+}
+
+// PendingMark records the outcome of a processed intent whose processed flag
+// could not be written, so it can be retried without pushing again
+type PendingMark struct {
+	IntentID   string    `bson:"_id"`
+	CommitHash string    `bson:"commit_hash,omitempty"`
 	Error      string    `bson:"error,omitempty"`
-	Documents  []string  `bson:"documents"` // Document IDs
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+// BatchSummary rolls up the intents enqueued by one poll or change stream
+// batch once all of them have been processed
+type BatchSummary struct {
+	ID               string    `bson:"_id,omitempty"`
+	StartedAt        time.Time `bson:"started_at"`
+	CompletedAt      time.Time `bson:"completed_at"`
+	DurationSeconds  float64   `bson:"duration_seconds"`
+	Intents          int       `bson:"intents"`
+	Succeeded        int       `bson:"succeeded"`
+	Failed           int       `bson:"failed"`
+	Skipped          int       `bson:"skipped"`
+	DocumentsWritten int       `bson:"documents_written"`
+	Workers          int       `bson:"workers"`
 }
 
+// RepoLockKey identifies the target repository branch a RepoLock guards
+type RepoLockKey struct {
+	Repo   string `bson:"repo"`
+	Branch string `bson:"branch"`
+}
+
+// RepoLock is an advisory lock on a target repository branch, held by one
+// worker across all replicas until it is released or expires
+type RepoLock struct {
+	ID        RepoLockKey `bson:"_id"`
+	Owner     string      `bson:"owner"`
+	ExpiresAt time.Time   `bson:"expires_at"`
+}
+
+// ErrPushIntentNotFound is returned when marking an intent that does not exist
+var ErrPushIntentNotFound = errors.New("push intent not found")
+
 // Client wraps MongoDB operations
 type Client struct {
 	client   *mongo.Client
 	database *mongo.Database
+
+	// fetchChunkSize caps the IDs in a single $in query
+	fetchChunkSize int
 }
 
-// NewClient creates a new MongoDB client
-func NewClient(ctx context.Context, uri, databaseName string) (*Client, error) {
-	clientOptions := options.Client().
-		ApplyURI(uri).
-		SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1))
+// ClientOptions configures the MongoDB connection
+type ClientOptions struct {
+	URI                    string
+	Database               string
+	MaxPoolSize            uint64
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+
+	// TLSConfig overrides the TLS settings from the URI, e.g. for a
+	// private CA or client certificate
+	TLSConfig *tls.Config
+
+	// DocFetchChunkSize caps the IDs in a single $in query when fetching
+	// documents. Zero uses defaultFetchChunkSize.
+	DocFetchChunkSize int
+}
 
-	client, err := mongo.Connect(ctx, clientOptions)
+// NewClient creates a new MongoDB client
+func NewClient(ctx context.Context, opts ClientOptions) (*Client, error) {
+	client, err := mongo.Connect(ctx, clientOptions(opts))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
 
 	// Ping to verify connection
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	pingTimeout := opts.ConnectTimeout
+	if pingTimeout <= 0 {
+		pingTimeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
 	defer cancel()
-	
+
 	if err := client.Ping(ctx, readpref.Primary()); err != nil {
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", err)
 	}
 
+	chunkSize := opts.DocFetchChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultFetchChunkSize
+	}
+
 	return &Client{
-		client:   client,
-		database: client.Database(databaseName),
+		client:         client,
+		database:       client.Database(opts.Database),
+		fetchChunkSize: chunkSize,
 	}, nil
 }
 
+// clientOptions builds the driver options for a connection
+func clientOptions(opts ClientOptions) *options.ClientOptions {
+	clientOpts := options.Client().
+		ApplyURI(opts.URI).
+		SetServerAPIOptions(options.ServerAPI(options.ServerAPIVersion1)).
+		SetRetryWrites(true).
+		SetRetryReads(true)
+
+	if opts.MaxPoolSize > 0 {
+		clientOpts.SetMaxPoolSize(opts.MaxPoolSize)
+	}
+	if opts.ConnectTimeout > 0 {
+		clientOpts.SetConnectTimeout(opts.ConnectTimeout)
+	}
+	if opts.ServerSelectionTimeout > 0 {
+		clientOpts.SetServerSelectionTimeout(opts.ServerSelectionTimeout)
+	}
+	if opts.TLSConfig != nil {
+		clientOpts.SetTLSConfig(opts.TLSConfig)
+	}
+
+	return clientOpts
+}
+
 // Close closes the MongoDB connection
 func (c *Client) Close(ctx context.Context) error {
 	return c.client.Disconnect(ctx)
 }
 
+// pendingIntentOrder selects urgent intents first, then the oldest. Intents
+// written before priorities existed have no priority field and sort last.
+var pendingIntentOrder = bson.D{
+	{Key: "priority", Value: -1},
+	{Key: "timestamp", Value: 1},
+}
+
 // GetPendingPushIntents retrieves unprocessed push intents
 func (c *Client) GetPendingPushIntents(ctx context.Context, limit int) ([]*PushIntent, error) {
 	collection := c.database.Collection("push_intents")
-	
+
 	filter := bson.M{"processed": false}
 	opts := options.Find().
-		SetSort(bson.D{{Key: "timestamp", Value: 1}}).
+		SetSort(pendingIntentOrder).
 		SetLimit(int64(limit))
 
 	cursor, err := collection.Find(ctx, filter, opts)
@@ -98,30 +249,265 @@ func (c *Client) GetPendingPushIntents(ctx context.Context, limit int) ([]*PushI
 	return intents, nil
 }
 
-// GetDocumentsByIDs retrieves documents by their IDs
-func (c *Client) GetDocumentsByIDs(ctx context.Context, ids []string) ([]*Document, error) {
+// CountPendingPushIntents returns the number of unprocessed push intents
+func (c *Client) CountPendingPushIntents(ctx context.Context) (int64, error) {
+	count, err := c.database.Collection("push_intents").CountDocuments(ctx, bson.M{"processed": false})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count push intents: %w", err)
+	}
+	return count, nil
+}
+
+// OldestPendingIntentAge returns how long the oldest unprocessed push intent
+// has been waiting, or zero when nothing is pending
+func (c *Client) OldestPendingIntentAge(ctx context.Context) (time.Duration, error) {
+	collection := c.database.Collection("push_intents")
+
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "timestamp", Value: 1}}).
+		SetProjection(bson.M{"timestamp": 1})
+
+	var oldest struct {
+		Timestamp time.Time `bson:"timestamp"`
+	}
+	err := collection.FindOne(ctx, bson.M{"processed": false}, opts).Decode(&oldest)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find oldest pending push intent: %w", err)
+	}
+
+	return time.Since(oldest.Timestamp), nil
+}
+
+// defaultFetchChunkSize keeps $in queries well below the sizes MongoDB
+// handles poorly
+const defaultFetchChunkSize = 1000
+
+// fetchConcurrency bounds the chunk queries StreamDocumentsByIDs runs at once
+const fetchConcurrency = 4
+
+// chunkReadAhead is how many documents each running chunk query decodes
+// ahead of the stream callback
+const chunkReadAhead = 16
+
+// chunkIDs splits ids into chunks of at most size, dropping repeated IDs
+func chunkIDs(ids []string, size int) [][]string {
+	if size <= 0 {
+		size = defaultFetchChunkSize
+	}
+
+	seen := make(map[string]bool, len(ids))
+	var chunks [][]string
+	var chunk []string
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		chunk = append(chunk, id)
+		if len(chunk) == size {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// StreamProjection limits streamed documents to the fields needed to apply
+// them to a repository, including the version and timestamp that decide
+// between documents writing the same path. Test stores apply it too.
+var StreamProjection = bson.M{
+	"_id":       1,
+	"path":      1,
+	"blob":      1,
+	"type":      1,
+	"metadata":  1,
+	"_v":        1,
+	"timestamp": 1,
+}
+
+// documentCursor is the subset of *mongo.Cursor used to stream documents
+type documentCursor interface {
+	Next(ctx context.Context) bool
+	Decode(val interface{}) error
+	Err() error
+	Close(ctx context.Context) error
+}
+
+// StreamDocumentsByIDs retrieves documents by their IDs and calls fn for each
+// one in turn. The IDs are split into chunks of at most DOC_FETCH_CHUNK_SIZE,
+// each document is delivered once, and up to fetchConcurrency chunks are
+// queried at a time. Besides the document being handled only the few decoded
+// ahead by running chunks are held in memory, so fn should not retain it.
+// Returning an error from fn stops the stream and returns that error.
+func (c *Client) StreamDocumentsByIDs(ctx context.Context, ids []string, fn func(*Document) error) error {
 	collection := c.database.Collection("documents")
-	
-	filter := bson.M{"_id": bson.M{"$in": ids}}
-	
-	cursor, err := collection.Find(ctx, filter)
+
+	opts := options.Find().
+		SetProjection(StreamProjection).
+		SetBatchSize(1)
+
+	find := func(ctx context.Context, chunk []string) (documentCursor, error) {
+		cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": chunk}}, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find documents: %w", err)
+		}
+		return cursor, nil
+	}
+
+	return streamChunks(ctx, chunkIDs(ids, c.fetchChunkSize), fetchConcurrency, find, fn)
+}
+
+// streamChunks queries chunks with at most concurrency cursors open at once
+// and calls fn for their documents in chunk order, so the order fn sees stays
+// predictable. The first failing chunk stops the stream and fails it, naming
+// the chunk.
+func streamChunks(ctx context.Context, chunks [][]string, concurrency int, find func(context.Context, []string) (documentCursor, error), fn func(*Document) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+
+	results := make([]chan *Document, len(chunks))
+	errs := make([]error, len(chunks))
+	for i := range results {
+		results[i] = make(chan *Document, chunkReadAhead)
+	}
+
+	// Go blocks while concurrency chunks are running, and they only finish
+	// as fn drains them, so chunks are started from their own goroutine
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		for i, chunk := range chunks {
+			g.Go(func() error {
+				if err := readChunk(gctx, find, chunk, results[i]); err != nil {
+					errs[i] = fmt.Errorf("chunk %d of %d: %w", i+1, len(chunks), err)
+				}
+				close(results[i])
+				return errs[i]
+			})
+		}
+	}()
+
+	stop := func(err error) error {
+		cancel()
+		<-started
+		if waitErr := g.Wait(); err == nil {
+			err = waitErr
+		}
+		return err
+	}
+
+	for i, documents := range results {
+		for doc := range documents {
+			if err := fn(doc); err != nil {
+				return stop(err)
+			}
+		}
+		// The chunk closed early; report whichever chunk failed first
+		if errs[i] != nil {
+			return stop(nil)
+		}
+	}
+
+	return stop(nil)
+}
+
+// readChunk queries one chunk and sends its documents to out as they are
+// decoded
+func readChunk(ctx context.Context, find func(context.Context, []string) (documentCursor, error), chunk []string, out chan<- *Document) error {
+	cursor, err := find(ctx, chunk)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find documents: %w", err)
+		return err
 	}
 	defer cursor.Close(ctx)
 
-	var documents []*Document
-	if err := cursor.All(ctx, &documents); err != nil {
-		return nil, fmt.Errorf("failed to decode documents: %w", err)
+	return streamCursor(ctx, cursor, func(doc *Document) error {
+		select {
+		case out <- doc:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// streamCursor decodes each cursor entry into a fresh Document and hands it
+// to fn before advancing
+func streamCursor(ctx context.Context, cursor documentCursor, fn func(*Document) error) error {
+	for cursor.Next(ctx) {
+		doc := &Document{}
+		if err := cursor.Decode(doc); err != nil {
+			return fmt.Errorf("failed to decode document: %w", err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to read documents: %w", err)
 	}
 
-	return documents, nil
+	return nil
+}
+
+// CreatePushIntent stores inline documents, upserting by repo, branch and
+// path, and inserts an intent referencing them. It returns the intent ID.
+func (c *Client) CreatePushIntent(ctx context.Context, intent *PushIntent, documents []*Document) (string, error) {
+	documentsCol := c.database.Collection("documents")
+
+	for _, doc := range documents {
+		filter := bson.M{"repo": doc.Repo, "branch": doc.Branch, "path": doc.Path}
+		update := bson.M{
+			"$set": bson.M{
+				"blob":      doc.Blob,
+				"author":    doc.Author,
+				"timestamp": doc.Timestamp,
+				"type":      doc.Type,
+				"metadata":  doc.Metadata,
+			},
+			"$inc":         bson.M{"_v": 1},
+			"$setOnInsert": bson.M{"_id": primitive.NewObjectID().Hex()},
+		}
+		opts := options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.After).
+			SetProjection(bson.M{"_id": 1})
+
+		var stored struct {
+			ID string `bson:"_id"`
+		}
+		if err := documentsCol.FindOneAndUpdate(ctx, filter, update, opts).Decode(&stored); err != nil {
+			return "", fmt.Errorf("failed to store document %s: %w", doc.Path, err)
+		}
+		intent.Documents = append(intent.Documents, stored.ID)
+	}
+
+	if intent.ID == "" {
+		intent.ID = primitive.NewObjectID().Hex()
+	}
+	intent.Processed = false
+
+	if _, err := c.database.Collection("push_intents").InsertOne(ctx, intent); err != nil {
+		return "", fmt.Errorf("failed to insert push intent: %w", err)
+	}
+
+	return intent.ID, nil
 }
 
 // MarkPushIntentProcessed marks a push intent as processed
 func (c *Client) MarkPushIntentProcessed(ctx context.Context, id string, err error) error {
 	collection := c.database.Collection("push_intents")
-	
+
 	now := time.Now()
 	update := bson.M{
 		"$set": bson.M{
@@ -145,32 +531,260 @@ func (c *Client) MarkPushIntentProcessed(ctx context.Context, id string, err err
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("push intent not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrPushIntentNotFound, id)
 	}
 
 	return nil
 }
 
-// WatchPushIntents creates a change stream for push intents
-func (c *Client) WatchPushIntents(ctx context.Context) (*mongo.ChangeStream, error) {
+// RecordPendingMark stores an outcome whose processed flag could not be
+// written. Recording the same intent again replaces the earlier entry.
+func (c *Client) RecordPendingMark(ctx context.Context, mark *PendingMark) error {
+	_, err := c.database.Collection("pending_marks").ReplaceOne(
+		ctx,
+		bson.M{"_id": mark.IntentID},
+		mark,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record pending mark: %w", err)
+	}
+	return nil
+}
+
+// GetPendingMark returns the pending mark for an intent, or nil if there is
+// none
+func (c *Client) GetPendingMark(ctx context.Context, intentID string) (*PendingMark, error) {
+	var mark PendingMark
+	err := c.database.Collection("pending_marks").FindOne(ctx, bson.M{"_id": intentID}).Decode(&mark)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending mark: %w", err)
+	}
+	return &mark, nil
+}
+
+// GetPendingMarks retrieves the oldest pending marks
+func (c *Client) GetPendingMarks(ctx context.Context, limit int) ([]*PendingMark, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := c.database.Collection("pending_marks").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find pending marks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var marks []*PendingMark
+	if err := cursor.All(ctx, &marks); err != nil {
+		return nil, fmt.Errorf("failed to decode pending marks: %w", err)
+	}
+	return marks, nil
+}
+
+// DeletePendingMark removes the pending mark for an intent
+func (c *Client) DeletePendingMark(ctx context.Context, intentID string) error {
+	if _, err := c.database.Collection("pending_marks").DeleteOne(ctx, bson.M{"_id": intentID}); err != nil {
+		return fmt.Errorf("failed to delete pending mark: %w", err)
+	}
+	return nil
+}
+
+// AcquireRepoLock takes the lock on repo and branch for owner until ttl from
+// now, reporting false when another owner holds an unexpired lock. An owner
+// acquiring a lock it already holds extends it.
+func (c *Client) AcquireRepoLock(ctx context.Context, repo, branch, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": RepoLockKey{Repo: repo, Branch: branch},
+		"$or": bson.A{
+			bson.M{"owner": owner},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"owner": owner, "expires_at": now.Add(ttl)}}
+
+	// A lock held by someone else fails the filter, so the upsert collides
+	// with the existing _id
+	_, err := c.database.Collection("repo_locks").UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire repo lock: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseRepoLock releases the lock on repo and branch if owner still holds it
+func (c *Client) ReleaseRepoLock(ctx context.Context, repo, branch, owner string) error {
+	_, err := c.database.Collection("repo_locks").DeleteOne(ctx, bson.M{
+		"_id":   RepoLockKey{Repo: repo, Branch: branch},
+		"owner": owner,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release repo lock: %w", err)
+	}
+	return nil
+}
+
+// QuarantinePushIntent copies an intent to the dead-letter collection and
+// marks it processed with the reason so it is not picked up again
+func (c *Client) QuarantinePushIntent(ctx context.Context, intent *PushIntent, reason string) error {
+	deadLetter := &DeadLetter{
+		IntentID:      intent.ID,
+		Intent:        intent,
+		Reason:        reason,
+		QuarantinedAt: time.Now(),
+	}
+
+	_, err := c.database.Collection("dead_letter_intents").ReplaceOne(
+		ctx,
+		bson.M{"_id": intent.ID},
+		deadLetter,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write dead letter: %w", err)
+	}
+
+	return c.MarkPushIntentProcessed(ctx, intent.ID, fmt.Errorf("quarantined: %s", reason))
+}
+
+// SaveDryRunResult writes a dry run's result to the dry_run_results
+// collection, replacing an earlier result for the same intent
+func (c *Client) SaveDryRunResult(ctx context.Context, result *DryRunResult) error {
+	_, err := c.database.Collection("dry_run_results").ReplaceOne(
+		ctx,
+		bson.M{"_id": result.IntentID},
+		result,
+		options.Replace().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write dry run result: %w", err)
+	}
+
+	return nil
+}
+
+// InsertAuditRecord appends a record to the audit collection
+func (c *Client) InsertAuditRecord(ctx context.Context, record *AuditRecord) error {
+	collection := c.database.Collection("audit")
+
+	if _, err := collection.InsertOne(ctx, record); err != nil {
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+
+	return nil
+}
+
+// InsertBatchSummary appends a summary to the batch_summaries collection
+func (c *Client) InsertBatchSummary(ctx context.Context, summary *BatchSummary) error {
+	collection := c.database.Collection("batch_summaries")
+
+	if _, err := collection.InsertOne(ctx, summary); err != nil {
+		return fmt.Errorf("failed to insert batch summary: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditTrail retrieves the most recent audit records for a repo and branch
+func (c *Client) GetAuditTrail(ctx context.Context, repo, branch string, limit int) ([]*AuditRecord, error) {
+	collection := c.database.Collection("audit")
+
+	filter := bson.M{"repo": repo, "branch": branch}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find audit records: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []*AuditRecord
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode audit records: %w", err)
+	}
+
+	return records, nil
+}
+
+// WatchPushIntents creates a change stream for push intents. A non-zero
+// since starts the stream at that time so earlier events are delivered
+// again before live events.
+func (c *Client) WatchPushIntents(ctx context.Context, since time.Time) (*mongo.ChangeStream, error) {
 	collection := c.database.Collection("push_intents")
-	
-	pipeline := mongo.Pipeline{
+
+	stream, err := collection.Watch(ctx, watchPipeline(), watchOptions(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create change stream: %w", err)
+	}
+
+	return stream, nil
+}
+
+// watchPipeline matches new pending intents and intents requeued by
+// setting processed back to false, whether by an update or a replacement.
+// Updates that leave processed untouched are not delivered again.
+func watchPipeline() mongo.Pipeline {
+	return mongo.Pipeline{
 		{{Key: "$match", Value: bson.D{
-			{Key: "operationType", Value: "insert"},
 			{Key: "fullDocument.processed", Value: false},
+			{Key: "$or", Value: bson.A{
+				bson.D{{Key: "operationType", Value: bson.D{{Key: "$in", Value: bson.A{"insert", "replace"}}}}},
+				bson.D{
+					{Key: "operationType", Value: "update"},
+					{Key: "updateDescription.updatedFields.processed", Value: false},
+				},
+			}},
 		}}},
 	}
+}
 
+// watchOptions builds the change stream options, starting at since when set
+func watchOptions(since time.Time) *options.ChangeStreamOptions {
 	opts := options.ChangeStream().
 		SetFullDocument(options.UpdateLookup)
 
-	stream, err := collection.Watch(ctx, pipeline, opts)
+	if !since.IsZero() {
+		opts.SetStartAtOperationTime(&primitive.Timestamp{T: uint32(since.Unix())})
+	}
+
+	return opts
+}
+
+// UnprocessedPushIntentIDs returns which of the given intent IDs are still
+// waiting to be processed
+func (c *Client) UnprocessedPushIntentIDs(ctx context.Context, ids []string) (map[string]bool, error) {
+	collection := c.database.Collection("push_intents")
+
+	filter := bson.M{"_id": bson.M{"$in": ids}, "processed": false}
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create change stream: %w", err)
+		return nil, fmt.Errorf("failed to find push intents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var found []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to decode push intents: %w", err)
 	}
 
-	return stream, nil
+	pending := make(map[string]bool, len(found))
+	for _, intent := range found {
+		pending[intent.ID] = true
+	}
+	return pending, nil
 }
 
 // CreateIndexes creates necessary indexes
@@ -184,6 +798,13 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 				{Key: "timestamp", Value: 1},
 			},
 		},
+		{
+			Keys: bson.D{
+				{Key: "processed", Value: 1},
+				{Key: "priority", Value: -1},
+				{Key: "timestamp", Value: 1},
+			},
+		},
 		{
 			Keys: bson.D{{Key: "repo", Value: 1}},
 		},
@@ -196,6 +817,48 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 		return fmt.Errorf("failed to create push_intents indexes: %w", err)
 	}
 
+	// Audit indexes
+	auditCol := c.database.Collection("audit")
+	auditIndexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "repo", Value: 1},
+				{Key: "branch", Value: 1},
+				{Key: "timestamp", Value: -1},
+			},
+		},
+	}
+
+	if _, err := auditCol.Indexes().CreateMany(ctx, auditIndexes); err != nil {
+		return fmt.Errorf("failed to create audit indexes: %w", err)
+	}
+
+	// Pending marks are retried oldest first
+	pendingMarksCol := c.database.Collection("pending_marks")
+	if _, err := pendingMarksCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "created_at", Value: 1}},
+	}); err != nil {
+		return fmt.Errorf("failed to create pending_marks indexes: %w", err)
+	}
+
+	// Repo locks left behind by a crashed worker are removed once expired;
+	// acquisition does not wait for the TTL monitor to take over a lock
+	repoLocksCol := c.database.Collection("repo_locks")
+	if _, err := repoLocksCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}); err != nil {
+		return fmt.Errorf("failed to create repo_locks indexes: %w", err)
+	}
+
+	// Batch summary indexes
+	batchSummariesCol := c.database.Collection("batch_summaries")
+	if _, err := batchSummariesCol.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "completed_at", Value: -1}},
+	}); err != nil {
+		return fmt.Errorf("failed to create batch_summaries indexes: %w", err)
+	}
+
 	// Documents indexes (if needed for queries)
 	documentsCol := c.database.Collection("documents")
 	documentsIndexes := []mongo.IndexModel{
@@ -217,4 +880,4 @@ func (c *Client) CreateIndexes(ctx context.Context) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
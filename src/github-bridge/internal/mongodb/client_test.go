@@ -0,0 +1,339 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestClientOptionsReflectConfiguration(t *testing.T) {
+	opts := clientOptions(ClientOptions{
+		URI:                    "mongodb://localhost:27017",
+		Database:               "virtual_dom",
+		MaxPoolSize:            42,
+		ConnectTimeout:         7 * time.Second,
+		ServerSelectionTimeout: 3 * time.Second,
+	})
+
+	if opts.MaxPoolSize == nil || *opts.MaxPoolSize != 42 {
+		t.Errorf("expected max pool size 42, got %v", opts.MaxPoolSize)
+	}
+	if opts.ConnectTimeout == nil || *opts.ConnectTimeout != 7*time.Second {
+		t.Errorf("expected connect timeout 7s, got %v", opts.ConnectTimeout)
+	}
+	if opts.ServerSelectionTimeout == nil || *opts.ServerSelectionTimeout != 3*time.Second {
+		t.Errorf("expected server selection timeout 3s, got %v", opts.ServerSelectionTimeout)
+	}
+	if opts.RetryWrites == nil || !*opts.RetryWrites {
+		t.Error("expected retryable writes to be enabled")
+	}
+	if opts.RetryReads == nil || !*opts.RetryReads {
+		t.Error("expected retryable reads to be enabled")
+	}
+	if err := opts.Validate(); err != nil {
+		t.Errorf("expected valid options, got %v", err)
+	}
+}
+
+func TestClientOptionsUseTLSConfig(t *testing.T) {
+	tlsConfig := &tls.Config{ServerName: "mongo.internal"}
+	opts := clientOptions(ClientOptions{URI: "mongodb://localhost:27017", TLSConfig: tlsConfig})
+
+	if opts.TLSConfig != tlsConfig {
+		t.Errorf("expected the supplied TLS config, got %+v", opts.TLSConfig)
+	}
+}
+
+func TestClientOptionsKeepDriverDefaults(t *testing.T) {
+	opts := clientOptions(ClientOptions{URI: "mongodb://localhost:27017"})
+
+	if opts.MaxPoolSize != nil {
+		t.Errorf("expected driver default pool size, got %v", *opts.MaxPoolSize)
+	}
+	if opts.ConnectTimeout != nil {
+		t.Errorf("expected driver default connect timeout, got %v", *opts.ConnectTimeout)
+	}
+}
+
+// fakeCursor serves documents one at a time and records the order in which
+// it is advanced relative to the stream callback
+type fakeCursor struct {
+	docs   []*Document
+	pos    int
+	events *[]string
+	closed func()
+}
+
+func (c *fakeCursor) Next(ctx context.Context) bool {
+	if c.pos >= len(c.docs) {
+		return false
+	}
+	c.pos++
+	*c.events = append(*c.events, "next "+c.docs[c.pos-1].ID)
+	return true
+}
+
+func (c *fakeCursor) Decode(val interface{}) error {
+	*val.(*Document) = *c.docs[c.pos-1]
+	return nil
+}
+
+func (c *fakeCursor) Err() error {
+	return nil
+}
+
+func (c *fakeCursor) Close(ctx context.Context) error {
+	if c.closed != nil {
+		c.closed()
+	}
+	return nil
+}
+
+func TestStreamCursorDeliversDocumentsOneAtATime(t *testing.T) {
+	var events []string
+	cursor := &fakeCursor{
+		docs:   []*Document{{ID: "a", Blob: []byte("1")}, {ID: "b", Blob: []byte("2")}, {ID: "c", Blob: []byte("3")}},
+		events: &events,
+	}
+
+	var seen []*Document
+	err := streamCursor(context.Background(), cursor, func(doc *Document) error {
+		events = append(events, "handle "+doc.ID)
+		seen = append(seen, doc)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("streamCursor failed: %v", err)
+	}
+
+	want := []string{"next a", "handle a", "next b", "handle b", "next c", "handle c"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, events)
+	}
+
+	// Each callback gets its own document so earlier ones can be released
+	if seen[0] == seen[1] || seen[1] == seen[2] {
+		t.Error("expected a fresh document per callback")
+	}
+}
+
+func TestStreamCursorStopsOnCallbackError(t *testing.T) {
+	var events []string
+	cursor := &fakeCursor{
+		docs:   []*Document{{ID: "a"}, {ID: "b"}},
+		events: &events,
+	}
+
+	stop := errors.New("stop")
+	err := streamCursor(context.Background(), cursor, func(doc *Document) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+	if len(events) != 1 {
+		t.Errorf("expected the stream to stop after the first document, got %v", events)
+	}
+}
+
+func TestPendingIntentOrderPrefersPriority(t *testing.T) {
+	if len(pendingIntentOrder) != 2 ||
+		pendingIntentOrder[0] != (bson.E{Key: "priority", Value: -1}) ||
+		pendingIntentOrder[1] != (bson.E{Key: "timestamp", Value: 1}) {
+		t.Errorf("expected priority desc then timestamp asc, got %v", pendingIntentOrder)
+	}
+}
+
+func TestWatchOptionsStartAtReplayTime(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	opts := watchOptions(since)
+	if opts.StartAtOperationTime == nil || opts.StartAtOperationTime.T != uint32(since.Unix()) {
+		t.Errorf("expected stream to start at %v, got %v", since, opts.StartAtOperationTime)
+	}
+
+	if live := watchOptions(time.Time{}); live.StartAtOperationTime != nil {
+		t.Errorf("expected live stream without a start time, got %v", live.StartAtOperationTime)
+	}
+}
+
+func TestWatchPipelineMatchesRequeuedIntents(t *testing.T) {
+	if opts := watchOptions(time.Time{}); opts.FullDocument == nil || *opts.FullDocument != options.UpdateLookup {
+		t.Fatalf("expected update events to look up the full document, got %v", opts.FullDocument)
+	}
+
+	pipeline := watchPipeline()
+	if len(pipeline) != 1 || pipeline[0][0].Key != "$match" {
+		t.Fatalf("expected a single $match stage, got %v", pipeline)
+	}
+	match := pipeline[0][0].Value.(bson.D).Map()
+
+	if processed, ok := match["fullDocument.processed"]; !ok || processed != false {
+		t.Errorf("expected only unprocessed intents to match, got %v", match)
+	}
+
+	var operations []string
+	for _, arm := range match["$or"].(bson.A) {
+		cond := arm.(bson.D).Map()
+		switch op := cond["operationType"].(type) {
+		case string:
+			if cond["updateDescription.updatedFields.processed"] != false {
+				t.Errorf("expected %s events to match only when processed is reset, got %v", op, cond)
+			}
+			operations = append(operations, op)
+		case bson.D:
+			for _, name := range op.Map()["$in"].(bson.A) {
+				operations = append(operations, name.(string))
+			}
+		}
+	}
+	sort.Strings(operations)
+	if got := strings.Join(operations, ","); got != "insert,replace,update" {
+		t.Errorf("expected insert, replace and update events, got %s", got)
+	}
+}
+
+func TestChunkIDsSplitsAndDropsRepeats(t *testing.T) {
+	ids := make([]string, 0, 2600)
+	for i := 0; i < 2500; i++ {
+		ids = append(ids, fmt.Sprintf("doc-%d", i))
+	}
+	// Repeated IDs are only queried once
+	ids = append(ids, ids[:100]...)
+
+	chunks := chunkIDs(ids, 1000)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+
+	var i int
+	for _, chunk := range chunks {
+		if len(chunk) > 1000 {
+			t.Errorf("expected at most 1000 IDs per chunk, got %d", len(chunk))
+		}
+		for _, id := range chunk {
+			if want := fmt.Sprintf("doc-%d", i); id != want {
+				t.Fatalf("expected %s at %d, got %s", want, i, id)
+			}
+			i++
+		}
+	}
+	if i != 2500 {
+		t.Errorf("expected 2500 IDs across chunks, got %d", i)
+	}
+}
+
+func TestStreamChunksDeliversEveryDocumentOnceInOrder(t *testing.T) {
+	ids := make([]string, 0, 2600)
+	for i := 0; i < 2500; i++ {
+		ids = append(ids, fmt.Sprintf("doc-%d", i))
+	}
+	ids = append(ids, ids[:100]...)
+
+	var open, maxOpen atomic.Int32
+	find := func(ctx context.Context, chunk []string) (documentCursor, error) {
+		n := open.Add(1)
+		for max := maxOpen.Load(); n > max && !maxOpen.CompareAndSwap(max, n); max = maxOpen.Load() {
+		}
+		cursor := &fakeCursor{events: new([]string), closed: func() { open.Add(-1) }}
+		for _, id := range chunk {
+			cursor.docs = append(cursor.docs, &Document{ID: id})
+		}
+		return cursor, nil
+	}
+
+	var seen []string
+	err := streamChunks(context.Background(), chunkIDs(ids, 100), 4, find, func(doc *Document) error {
+		seen = append(seen, doc.ID)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("streamChunks failed: %v", err)
+	}
+
+	if len(seen) != 2500 {
+		t.Fatalf("expected 2500 documents, got %d", len(seen))
+	}
+	for i, id := range seen {
+		if want := fmt.Sprintf("doc-%d", i); id != want {
+			t.Fatalf("expected %s at %d, got %s", want, i, id)
+		}
+	}
+	if n := maxOpen.Load(); n > 4 {
+		t.Errorf("expected at most 4 chunk queries at once, got %d", n)
+	}
+	if n := open.Load(); n != 0 {
+		t.Errorf("expected every cursor closed, %d left open", n)
+	}
+}
+
+func TestStreamChunksFailsNamingTheChunk(t *testing.T) {
+	ids := make([]string, 0, 50)
+	for i := 0; i < 50; i++ {
+		ids = append(ids, fmt.Sprintf("doc-%d", i))
+	}
+
+	unavailable := errors.New("shard unavailable")
+	find := func(ctx context.Context, chunk []string) (documentCursor, error) {
+		if chunk[0] == "doc-30" {
+			return nil, unavailable
+		}
+		cursor := &fakeCursor{events: new([]string)}
+		for _, id := range chunk {
+			cursor.docs = append(cursor.docs, &Document{ID: id})
+		}
+		return cursor, nil
+	}
+
+	var handled int
+	err := streamChunks(context.Background(), chunkIDs(ids, 10), 2, find, func(doc *Document) error {
+		handled++
+		return nil
+	})
+	if !errors.Is(err, unavailable) {
+		t.Fatalf("expected the chunk error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "chunk 4 of 5") {
+		t.Errorf("expected the error to name the chunk, got %q", err)
+	}
+	if handled > 30 {
+		t.Errorf("expected no documents after the failing chunk, got %d", handled)
+	}
+}
+
+func TestStreamChunksStopsOnCallbackError(t *testing.T) {
+	ids := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		ids = append(ids, fmt.Sprintf("doc-%d", i))
+	}
+
+	var open atomic.Int32
+	find := func(ctx context.Context, chunk []string) (documentCursor, error) {
+		open.Add(1)
+		cursor := &fakeCursor{events: new([]string), closed: func() { open.Add(-1) }}
+		for _, id := range chunk {
+			cursor.docs = append(cursor.docs, &Document{ID: id})
+		}
+		return cursor, nil
+	}
+
+	stop := errors.New("stop")
+	err := streamChunks(context.Background(), chunkIDs(ids, 100), 4, find, func(doc *Document) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("expected callback error, got %v", err)
+	}
+	if n := open.Load(); n != 0 {
+		t.Errorf("expected every cursor closed, %d left open", n)
+	}
+}
@@ -0,0 +1,151 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a throwaway certificate authority and a leaf it has signed
+type testCA struct {
+	caFile   string
+	certFile string
+	keyFile  string
+	leaf     *x509.Certificate
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Private CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "github-bridge"},
+		DNSNames:     []string{"mongo.internal"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}, caCert, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKeyDER, err := x509.MarshalECPrivateKey(leafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca := &testCA{
+		caFile:   filepath.Join(dir, "ca.pem"),
+		certFile: filepath.Join(dir, "client.pem"),
+		keyFile:  filepath.Join(dir, "client-key.pem"),
+		leaf:     leaf,
+	}
+	writePEM(t, ca.caFile, "CERTIFICATE", caDER)
+	writePEM(t, ca.certFile, "CERTIFICATE", leafDER)
+	writePEM(t, ca.keyFile, "EC PRIVATE KEY", leafKeyDER)
+	return ca
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadTrustsPrivateCA(t *testing.T) {
+	ca := newTestCA(t)
+
+	cfg, err := Load(ca.caFile, "", "")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if _, err := ca.leaf.Verify(x509.VerifyOptions{
+		DNSName:   "mongo.internal",
+		Roots:     cfg.RootCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}); err != nil {
+		t.Errorf("expected a certificate signed by the test CA to verify: %v", err)
+	}
+	if len(cfg.Certificates) != 0 {
+		t.Errorf("expected no client certificate, got %d", len(cfg.Certificates))
+	}
+}
+
+func TestLoadClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+
+	cfg, err := Load(ca.caFile, ca.certFile, ca.keyFile)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if len(cfg.Certificates) != 1 {
+		t.Fatalf("expected one client certificate, got %d", len(cfg.Certificates))
+	}
+	cert, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse client certificate: %v", err)
+	}
+	if cert.Subject.CommonName != "github-bridge" {
+		t.Errorf("expected the github-bridge client certificate, got %q", cert.Subject.CommonName)
+	}
+}
+
+func TestLoadRequiresCertAndKeyTogether(t *testing.T) {
+	ca := newTestCA(t)
+
+	if _, err := Load("", ca.certFile, ""); err == nil {
+		t.Error("expected an error for a certificate without a key")
+	}
+	if _, err := Load("", "", ca.keyFile); err == nil {
+		t.Error("expected an error for a key without a certificate")
+	}
+}
+
+func TestLoadWithoutFilesKeepsDefaults(t *testing.T) {
+	cfg, err := Load("", "", "")
+	if err != nil || cfg != nil {
+		t.Errorf("expected no TLS config, got %+v, %v", cfg, err)
+	}
+}
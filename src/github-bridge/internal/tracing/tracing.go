@@ -0,0 +1,57 @@
+// Package tracing exports OpenTelemetry spans from the bridge over OTLP
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// serviceName names the bridge in traces unless OTEL_SERVICE_NAME is set
+const serviceName = "github-bridge"
+
+// Setup installs a global tracer provider exporting spans over OTLP/HTTP,
+// along with the W3C trace context propagator. The exporter, sampler and
+// resource are configured by the standard OTEL_* environment variables.
+// When disabled the global no-op provider is left in place. The returned
+// function flushes pending spans and stops the provider.
+func Setup(ctx context.Context, enabled bool) (func(context.Context) error, error) {
+	if !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	// Attributes from the environment override the default service name;
+	// a partly invalid OTEL_RESOURCE_ATTRIBUTES still yields the valid ones
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestSetupDisabledKeepsNoopProvider(t *testing.T) {
+	before := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("expected a no-op shutdown, got %v", err)
+	}
+	if otel.GetTracerProvider() != before {
+		t.Error("expected the global tracer provider to be left alone")
+	}
+}
+
+func TestSetupEnabledInstallsProvider(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:4318")
+	before := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(before) })
+
+	shutdown, err := Setup(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if _, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider); !ok {
+		t.Errorf("expected an SDK tracer provider, got %T", otel.GetTracerProvider())
+	}
+
+	// Nothing was recorded, so nothing is sent to the unreachable endpoint
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+}
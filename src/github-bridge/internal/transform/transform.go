@@ -0,0 +1,143 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+)
+
+// Transformer rewrites document content before it is written to the repository
+type Transformer interface {
+	Transform(path string, content []byte) ([]byte, error)
+}
+
+// Factory builds a transformer from the bridge configuration
+type Factory func(cfg *config.Config) (Transformer, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		"substitute": newSubstituter,
+		"redact":     newRedactor,
+	}
+)
+
+// Register makes a transformer available by name. It replaces any existing
+// transformer registered under the same name.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = factory
+}
+
+// Names returns the registered transformer names
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build creates a pipeline running the named transformers in order
+func Build(names []string, cfg *config.Config) (Pipeline, error) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	pipeline := make(Pipeline, 0, len(names))
+	for _, name := range names {
+		factory, ok := registry[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transformer %q", name)
+		}
+
+		t, err := factory(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create transformer %q: %w", name, err)
+		}
+		pipeline = append(pipeline, t)
+	}
+
+	return pipeline, nil
+}
+
+// Pipeline runs transformers in order, feeding each the previous output
+type Pipeline []Transformer
+
+// Transform implements Transformer
+func (p Pipeline) Transform(path string, content []byte) ([]byte, error) {
+	for _, t := range p {
+		var err error
+		if content, err = t.Transform(path, content); err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
+
+// Func adapts a function to the Transformer interface
+type Func func(path string, content []byte) ([]byte, error)
+
+// Transform implements Transformer
+func (f Func) Transform(path string, content []byte) ([]byte, error) {
+	return f(path, content)
+}
+
+type substitution struct {
+	pattern     *regexp.Regexp
+	replacement []byte
+}
+
+// substituter applies regular expression replacements in order
+type substituter []substitution
+
+func newSubstituter(cfg *config.Config) (Transformer, error) {
+	subs := make(substituter, 0, len(cfg.ContentSubstitutions))
+	for _, s := range cfg.ContentSubstitutions {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid substitution pattern %q: %w", s.Pattern, err)
+		}
+		subs = append(subs, substitution{pattern: pattern, replacement: []byte(s.Replacement)})
+	}
+	return subs, nil
+}
+
+func (s substituter) Transform(path string, content []byte) ([]byte, error) {
+	for _, sub := range s {
+		content = sub.pattern.ReplaceAll(content, sub.replacement)
+	}
+	return content, nil
+}
+
+// redactedText replaces content matched by a redact pattern
+const redactedText = "[REDACTED]"
+
+// redactor masks every match of the configured patterns
+type redactor []*regexp.Regexp
+
+func newRedactor(cfg *config.Config) (Transformer, error) {
+	patterns := make(redactor, 0, len(cfg.RedactPatterns))
+	for _, p := range cfg.RedactPatterns {
+		pattern, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %w", p, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func (r redactor) Transform(path string, content []byte) ([]byte, error) {
+	for _, pattern := range r {
+		content = pattern.ReplaceAllLiteral(content, []byte(redactedText))
+	}
+	return content, nil
+}
@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
+)
+
+func TestPipelineRunsTransformersInOrder(t *testing.T) {
+	cfg := &config.Config{
+		ContentSubstitutions: []config.Substitution{
+			{Pattern: `\$\{ENV\}`, Replacement: "production"},
+			{Pattern: `\$\{TOKEN\}`, Replacement: "ghp_secret123"},
+		},
+		RedactPatterns: []string{`ghp_[A-Za-z0-9]+`},
+	}
+
+	// Substituting first injects a secret that redaction then masks
+	pipeline, err := Build([]string{"substitute", "redact"}, cfg)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	got, err := pipeline.Transform("config.yaml", []byte("env: ${ENV}\ntoken: ${TOKEN}\n"))
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if want := "env: production\ntoken: [REDACTED]\n"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// In the opposite order the secret is introduced after redaction ran
+	pipeline, err = Build([]string{"redact", "substitute"}, cfg)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	got, err = pipeline.Transform("config.yaml", []byte("token: ${TOKEN}\n"))
+	if err != nil {
+		t.Fatalf("Transform failed: %v", err)
+	}
+	if want := "token: ghp_secret123\n"; string(got) != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestPipelineStopsOnError(t *testing.T) {
+	errRejected := errors.New("rejected")
+	calls := 0
+
+	pipeline := Pipeline{
+		Func(func(path string, content []byte) ([]byte, error) {
+			return nil, errRejected
+		}),
+		Func(func(path string, content []byte) ([]byte, error) {
+			calls++
+			return content, nil
+		}),
+	}
+
+	if _, err := pipeline.Transform("a.txt", []byte("content")); !errors.Is(err, errRejected) {
+		t.Fatalf("expected rejection error, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("expected later transformers to be skipped, got %d calls", calls)
+	}
+}
+
+func TestBuildRejectsUnknownAndInvalid(t *testing.T) {
+	if _, err := Build([]string{"missing"}, &config.Config{}); err == nil {
+		t.Error("expected error for unknown transformer")
+	}
+
+	cfg := &config.Config{RedactPatterns: []string{"("}}
+	if _, err := Build([]string{"redact"}, cfg); err == nil {
+		t.Error("expected error for invalid redact pattern")
+	}
+}
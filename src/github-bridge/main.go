@@ -2,16 +2,22 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // COMMIT_TIMEZONE must resolve without system zoneinfo
 
 	"github.com/joho/godotenv"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/api"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/bridge"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/config"
 	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/metrics"
@@ -24,7 +30,14 @@ var (
 	date    = "unknown"
 )
 
+// metricsShutdownTimeout bounds stopping the metrics server after the bridge
+// has shut down
+const metricsShutdownTimeout = 5 * time.Second
+
 func main() {
+	once := flag.Bool("once", false, "process pending push intents once, then exit")
+	flag.Parse()
+
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
 		logrus.Debug("No .env file found")
@@ -33,7 +46,7 @@ func main() {
 	// Initialize logger
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
-	
+
 	logLevel, err := logrus.ParseLevel(os.Getenv("LOG_LEVEL"))
 	if err != nil {
 		logLevel = logrus.InfoLevel
@@ -56,21 +69,40 @@ func main() {
 	if err := cfg.Validate(); err != nil {
 		logger.Fatalf("Invalid configuration: %v", err)
 	}
+	shutdownTimeout := time.Duration(cfg.ShutdownTimeout) * time.Second
 
-	// Initialize metrics
-	metrics.Init()
+	// Initialize metrics on a registry of our own rather than the global one
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := metrics.Init(registry)
 
 	// Create bridge instance
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	bridgeService, err := bridge.New(ctx, cfg, logger)
+	bridgeService, err := bridge.New(ctx, cfg, m, logger)
 	if err != nil {
 		logger.Fatalf("Failed to create bridge: %v", err)
 	}
 
+	bridgeService.SetBuildInfo(api.BuildInfo{Version: version, Commit: commit, Date: date})
+
+	// Prove the clone, commit and push cycle works before taking intents
+	if cfg.StartupSelftest {
+		if err := bridgeService.SelfTest(ctx); err != nil {
+			logger.Fatalf("Startup self-test failed: %v", err)
+		}
+	}
+
+	if *once {
+		os.Exit(runOnce(bridgeService, cfg.OnceReport, shutdownTimeout, os.Stdout, logger))
+	}
+
 	// Start metrics server
-	go startMetricsServer(cfg.MetricsPort, logger)
+	metricsServer, err := startMetricsServer(cfg.MetricsPort, registry, bridgeService, logger)
+	if err != nil {
+		logger.Errorf("Metrics server error: %v", err)
+	}
 
 	// Handle shutdown gracefully
 	sigChan := make(chan os.Signal, 1)
@@ -89,14 +121,25 @@ func main() {
 	case sig := <-sigChan:
 		logger.Infof("Received signal %v, shutting down gracefully", sig)
 		cancel()
-		
+
 		// Give the bridge time to cleanup
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer shutdownCancel()
-		
+
 		if err := bridgeService.Shutdown(shutdownCtx); err != nil {
 			logger.Errorf("Error during shutdown: %v", err)
 		}
+
+		// Stop the metrics server last so it can be scraped while draining.
+		// It gets its own deadline since the bridge may have used up
+		// shutdownCtx.
+		if metricsServer != nil {
+			metricsCtx, metricsCancel := context.WithTimeout(context.Background(), metricsShutdownTimeout)
+			defer metricsCancel()
+			if err := metricsServer.Shutdown(metricsCtx); err != nil {
+				logger.Errorf("Error shutting down metrics server: %v", err)
+			}
+		}
 	case err := <-errChan:
 		logger.Fatalf("Bridge error: %v", err)
 	}
@@ -104,9 +147,52 @@ func main() {
 	logger.Info("GitHub Bridge stopped")
 }
 
-func startMetricsServer(port int, logger *logrus.Logger) {
+// onceRunner processes pending intents a single time
+type onceRunner interface {
+	RunOnce() (*bridge.Report, error)
+	Shutdown(ctx context.Context) error
+}
+
+// runOnce processes pending intents, shuts b down within shutdownTimeout,
+// writes the report to w when enabled and returns the exit code: non-zero
+// when the run or any intent failed
+func runOnce(b onceRunner, writeReport bool, shutdownTimeout time.Duration, w io.Writer, logger *logrus.Logger) int {
+	report, runErr := b.RunOnce()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := b.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("Error during shutdown: %v", err)
+	}
+
+	if writeReport {
+		if err := report.Write(w); err != nil {
+			logger.Errorf("Failed to write report: %v", err)
+			return 1
+		}
+	}
+
+	if runErr != nil {
+		logger.Errorf("Once-mode run failed: %v", runErr)
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// handlerRegistrar mounts additional handlers on the metrics server
+type handlerRegistrar interface {
+	RegisterHandlers(mux *http.ServeMux)
+}
+
+// startMetricsServer binds the metrics port and serves in the background.
+// The returned server's Addr is the bound address; stop it with Shutdown.
+func startMetricsServer(port int, gatherer prometheus.Gatherer, routes handlerRegistrar, logger *logrus.Logger) (*http.Server, error) {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	routes.RegisterHandlers(mux)
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
@@ -120,8 +206,18 @@ func startMetricsServer(port int, logger *logrus.Logger) {
 		IdleTimeout:  15 * time.Second,
 	}
 
-	logger.Infof("Metrics server listening on :%d", port)
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		logger.Errorf("Metrics server error: %v", err)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on :%d: %w", port, err)
 	}
-}
\ No newline at end of file
+	server.Addr = listener.Addr().String()
+
+	logger.Infof("Metrics server listening on %s", server.Addr)
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Metrics server error: %v", err)
+		}
+	}()
+
+	return server, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/tekfly/virtual-dom-gateway/github-bridge/internal/bridge"
+)
+
+type noRoutes struct{}
+
+func (noRoutes) RegisterHandlers(mux *http.ServeMux) {}
+
+func TestMetricsServerStopsListeningAfterShutdown(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	server, err := startMetricsServer(0, prometheus.NewRegistry(), noRoutes{}, logger)
+	if err != nil {
+		t.Fatalf("failed to start metrics server: %v", err)
+	}
+
+	resp, err := http.Get("http://" + server.Addr + "/health")
+	if err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from /health, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	if conn, err := net.DialTimeout("tcp", server.Addr, time.Second); err == nil {
+		conn.Close()
+		t.Error("expected the metrics port to be released after shutdown")
+	}
+}
+
+type fakeOnceRunner struct {
+	report   *bridge.Report
+	err      error
+	shutdown bool
+}
+
+func (f *fakeOnceRunner) RunOnce() (*bridge.Report, error) { return f.report, f.err }
+
+func (f *fakeOnceRunner) Shutdown(ctx context.Context) error {
+	f.shutdown = true
+	return nil
+}
+
+func TestRunOnceExitCode(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	tests := []struct {
+		name   string
+		runner *fakeOnceRunner
+		want   int
+	}{
+		{"all succeeded", &fakeOnceRunner{report: &bridge.Report{Total: 2, Succeeded: 1, Skipped: 1}}, 0},
+		{"intent failed", &fakeOnceRunner{report: &bridge.Report{Total: 1, Failed: 1}}, 1},
+		{"run failed", &fakeOnceRunner{report: &bridge.Report{}, err: errors.New("mongo down")}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if got := runOnce(tt.runner, true, time.Second, &out, logger); got != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, got)
+			}
+			if out.Len() == 0 {
+				t.Error("expected the report on stdout")
+			}
+			if !tt.runner.shutdown {
+				t.Error("expected the bridge to be shut down")
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	runOnce(&fakeOnceRunner{report: &bridge.Report{}}, false, time.Second, &out, logger)
+	if out.Len() != 0 {
+		t.Errorf("expected no report when disabled, got %q", out.String())
+	}
+}